@@ -33,13 +33,20 @@ func newCommand() *cobra.Command {
 		originalHelpFunc(command, strings)
 	})
 
+	config.AddPathFlags(cmd.PersistentFlags())
+
 	ioStreams := genericclioptions.IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr}
 
 	cmd.AddCommand(cmds.NewRunMicroshiftCommand())
 	cmd.AddCommand(cmds.NewVersionCommand(ioStreams))
 	cmd.AddCommand(cmds.NewShowConfigCommand(ioStreams))
+	cmd.AddCommand(cmds.NewConfigCommand())
 	cmd.AddCommand(cmds.NewBackupCommand())
 	cmd.AddCommand(cmds.NewRestoreCommand())
+	cmd.AddCommand(cmds.NewUpgradeCommand())
+	cmd.AddCommand(cmds.NewDataCommand())
+	cmd.AddCommand(cmds.NewExportCommand())
+	cmd.AddCommand(cmds.NewImportCommand())
 	cmd.AddCommand(cmds.NewHealthcheckCommand())
 	return cmd
 }
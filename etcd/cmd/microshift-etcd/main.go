@@ -3,6 +3,7 @@ package main
 import (
 	"os"
 
+	"github.com/openshift/microshift/pkg/config"
 	"github.com/spf13/cobra"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/component-base/cli"
@@ -21,6 +22,9 @@ func main() {
 		},
 	}
 
+	// MicroShift passes its paths, so both processes use the same data.
+	config.AddPathFlags(cmd.PersistentFlags())
+
 	cmd.AddCommand(NewRunEtcdCommand())
 	cmd.AddCommand(NewVersionCommand(genericclioptions.IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr}))
 	os.Exit(cli.Run(cmd))
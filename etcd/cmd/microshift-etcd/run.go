@@ -26,7 +26,7 @@ func NewRunEtcdCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use: "run",
 		RunE: func(cmd *cobra.Command, args []string) (err error) {
-			cfg, err := config.ActiveConfig()
+			cfg, err := config.ActiveConfig(config.PathsFromFlags(cmd.Flags()))
 			if err != nil {
 				klog.Fatalf("Error in reading and validating MicroShift config: %v", err)
 			}
@@ -56,15 +56,15 @@ func (s *EtcdService) Name() string { return "etcd" }
 
 func (s *EtcdService) configure(cfg *config.Config) {
 	s.minDefragBytes = cfg.Etcd.MinDefragBytes
-	s.maxFragmentedPercentage = cfg.Etcd.MaxFragmentedPercentage
-	s.defragCheckFreq = cfg.Etcd.DefragCheckFreq
+	s.maxFragmentedPercentage = float64(cfg.Etcd.MaxFragmentedPercentage)
+	s.defragCheckFreq = cfg.Etcd.DefragCheckFreq.Duration
 
-	certsDir := cryptomaterial.CertsDirectory(config.DataDir)
+	certsDir := cryptomaterial.CertsDirectory(cfg.Paths.DataDir)
 
 	etcdServingCertDir := cryptomaterial.EtcdServingCertDir(certsDir)
 	etcdPeerCertDir := cryptomaterial.EtcdPeerCertDir(certsDir)
 	etcdSignerCertPath := cryptomaterial.CACertPath(cryptomaterial.EtcdSignerDir(certsDir))
-	dataDir := filepath.Join(config.DataDir, s.Name())
+	dataDir := filepath.Join(cfg.Paths.DataDir, s.Name())
 
 	// based on https://github.com/openshift/cluster-etcd-operator/blob/master/bindata/bootkube/bootstrap-manifests/etcd-member-pod.yaml#L19
 	s.etcdCfg = etcd.NewConfig()
@@ -105,6 +105,12 @@ func (s *EtcdService) Run() error {
 	versionInfo := EtcdVersionInfo
 	klog.InfoS("Version", "microshift-etcd", versionInfo.String(), "etcd-base", versionInfo.EtcdVersion)
 
+	if isSnapshotOnlyDataDir(s.etcdCfg.Dir) {
+		if err := prepareSnapshotForNewCluster(s.etcdCfg); err != nil {
+			return fmt.Errorf("microshift-etcd failed to prepare restored snapshot: %v", err)
+		}
+	}
+
 	e, err := etcd.StartEtcd(s.etcdCfg)
 	if err != nil {
 		return fmt.Errorf("microshift-etcd failed to start: %v", err)
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	etcd "go.etcd.io/etcd/server/v3/embed"
+	"go.etcd.io/etcd/server/v3/datadir"
+	"go.etcd.io/etcd/server/v3/etcdserver"
+	"go.etcd.io/etcd/server/v3/etcdserver/api/membership"
+	"go.etcd.io/etcd/server/v3/etcdserver/api/snap"
+	"go.etcd.io/etcd/server/v3/etcdserver/api/v2store"
+	"go.etcd.io/etcd/server/v3/etcdserver/cindex"
+	"go.etcd.io/etcd/server/v3/mvcc/backend"
+	"go.etcd.io/etcd/server/v3/wal"
+	"go.etcd.io/etcd/server/v3/wal/walpb"
+
+	"go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/client/pkg/v3/fileutil"
+	"go.etcd.io/etcd/client/pkg/v3/types"
+	"go.etcd.io/etcd/raft/v3/raftpb"
+	"go.uber.org/zap"
+	"k8s.io/klog/v2"
+)
+
+// isSnapshotOnlyDataDir returns true if etcd's data directory contains
+// a database without the WAL. This is a layout of online backups
+// (created with `microshift backup --online`) which contain etcd snapshot
+// instead of a copy of the whole etcd's directory.
+func isSnapshotOnlyDataDir(dataDir string) bool {
+	return fileutil.Exist(datadir.ToBackendFileName(dataDir)) && !wal.Exist(datadir.ToWalDir(dataDir))
+}
+
+// prepareSnapshotForNewCluster makes the database restored from the snapshot
+// usable for a new single member cluster. It mimics `etcdutl snapshot restore`:
+// it removes old membership from the database, creates WAL and raft snapshot
+// with the new membership, and updates consistent index, so the raft log and
+// the database are in sync.
+func prepareSnapshotForNewCluster(cfg *etcd.Config) error {
+	lg, err := zap.NewProduction()
+	if err != nil {
+		return fmt.Errorf("failed to create logger: %w", err)
+	}
+
+	dbPath := datadir.ToBackendFileName(cfg.Dir)
+	walDir := datadir.ToWalDir(cfg.Dir)
+	snapDir := datadir.ToSnapDir(cfg.Dir)
+	klog.InfoS("Preparing etcd snapshot for use as a new cluster", "db", dbPath)
+
+	urlsMap, err := types.NewURLsMap(cfg.InitialCluster)
+	if err != nil {
+		return fmt.Errorf("failed to parse initial cluster %q: %w", cfg.InitialCluster, err)
+	}
+	cl, err := membership.NewClusterFromURLsMap(lg, cfg.InitialClusterToken, urlsMap)
+	if err != nil {
+		return fmt.Errorf("failed to create cluster membership: %w", err)
+	}
+
+	be := backend.NewDefaultBackend(dbPath)
+	defer be.Close()
+
+	if err := membership.TrimMembershipFromBackend(lg, be); err != nil {
+		return fmt.Errorf("failed to remove old membership from the database: %w", err)
+	}
+
+	st := v2store.New(etcdserver.StoreClusterPrefix, etcdserver.StoreKeysPrefix)
+	cl.SetStore(st)
+	cl.SetBackend(be)
+	for _, m := range cl.Members() {
+		cl.AddMember(m, membership.ApplyBoth)
+	}
+
+	m := cl.MemberByName(cfg.Name)
+	if m == nil {
+		return fmt.Errorf("member %q not found in initial cluster %q", cfg.Name, cfg.InitialCluster)
+	}
+	md := &etcdserverpb.Metadata{NodeID: uint64(m.ID), ClusterID: uint64(cl.ID())}
+	metadata, err := md.Marshal()
+	if err != nil {
+		return err
+	}
+
+	w, err := wal.Create(lg, walDir, metadata)
+	if err != nil {
+		return fmt.Errorf("failed to create WAL: %w", err)
+	}
+	defer w.Close()
+
+	ids := cl.MemberIDs()
+	ents := make([]raftpb.Entry, 0, len(ids))
+	voters := make([]uint64, 0, len(ids))
+	for i, id := range ids {
+		ctx, err := json.Marshal(cl.Member(id))
+		if err != nil {
+			return err
+		}
+		cc := raftpb.ConfChange{Type: raftpb.ConfChangeAddNode, NodeID: uint64(id), Context: ctx}
+		d, err := cc.Marshal()
+		if err != nil {
+			return err
+		}
+		ents = append(ents, raftpb.Entry{Type: raftpb.EntryConfChange, Term: 1, Index: uint64(i + 1), Data: d})
+		voters = append(voters, uint64(id))
+	}
+
+	commit, term := uint64(len(ents)), uint64(1)
+	if err := w.Save(raftpb.HardState{Term: term, Vote: voters[0], Commit: commit}, ents); err != nil {
+		return fmt.Errorf("failed to save entries to WAL: %w", err)
+	}
+
+	b, err := st.Save()
+	if err != nil {
+		return err
+	}
+	confState := raftpb.ConfState{Voters: voters}
+	raftSnap := raftpb.Snapshot{
+		Data:     b,
+		Metadata: raftpb.SnapshotMetadata{Index: commit, Term: term, ConfState: confState},
+	}
+	if err := snap.New(lg, snapDir).SaveSnap(raftSnap); err != nil {
+		return fmt.Errorf("failed to save raft snapshot: %w", err)
+	}
+	if err := w.SaveSnapshot(walpb.Snapshot{Index: commit, Term: term, ConfState: &confState}); err != nil {
+		return fmt.Errorf("failed to save snapshot to WAL: %w", err)
+	}
+
+	cindex.UpdateConsistentIndex(be.BatchTx(), commit, term)
+	be.ForceCommit()
+
+	klog.InfoS("Prepared etcd snapshot for use as a new cluster", "member", m.ID, "cluster", cl.ID())
+	return nil
+}
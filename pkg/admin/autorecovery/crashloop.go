@@ -0,0 +1,127 @@
+package autorecovery
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/openshift/microshift/pkg/admin/data"
+	"github.com/openshift/microshift/pkg/util"
+	"k8s.io/klog/v2"
+)
+
+const (
+	startAttemptsFilename = "start-attempts.json"
+)
+
+// StartAttempt is a start of MicroShift that did not reach the ready state (yet).
+type StartAttempt struct {
+	StartedAt time.Time `json:"startedAt"`
+	// FailedService is the name of the service that failed the start,
+	// empty if MicroShift did not report the failure, e.g. because it crashed.
+	FailedService string `json:"failedService,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+// StartAttempts tracks unsuccessful starts of MicroShift to detect a crash loop.
+// Each start is recorded before anything else is done and the record is
+// removed when MicroShift becomes ready or is explicitly stopped before it got
+// ready without any service failing.
+type StartAttempts struct {
+	Attempts []StartAttempt `json:"attempts"`
+
+	path string
+}
+
+// LoadStartAttempts reads the attempts persisted in the dir.
+func LoadStartAttempts(dir string) (*StartAttempts, error) {
+	s := &StartAttempts{path: filepath.Join(dir, startAttemptsFilename)}
+
+	exists, err := util.PathExists(s.path)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return s, nil
+	}
+
+	contents, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %q: %w", s.path, err)
+	}
+	if err := json.Unmarshal(contents, s); err != nil {
+		// Corrupted file shouldn't prevent MicroShift from starting.
+		klog.ErrorS(err, "Failed to unmarshal start attempts - discarding them", "path", s.path)
+		s.Attempts = nil
+	}
+	return s, nil
+}
+
+// Save persists the attempts.
+func (s *StartAttempts) Save() error {
+	contents, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("failed to marshal start attempts: %w", err)
+	}
+	if err := util.MakeDir(filepath.Dir(s.path)); err != nil {
+		return fmt.Errorf("failed to create dir %q: %w", filepath.Dir(s.path), err)
+	}
+	tmp, err := data.GenerateUniqueTempPath(s.path)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(tmp, contents, 0600); err != nil {
+		return fmt.Errorf("failed to write %q: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to rename %q to %q: %w", tmp, s.path, err)
+	}
+	return nil
+}
+
+// Clear removes all the attempts, including the persisted ones.
+func (s *StartAttempts) Clear() error {
+	s.Attempts = nil
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove %q: %w", s.path, err)
+	}
+	return nil
+}
+
+// Begin records a new attempt and forgets the ones older than the window.
+func (s *StartAttempts) Begin(now time.Time, window time.Duration) {
+	s.Attempts = s.FailedWithin(now, window)
+	s.Attempts = append(s.Attempts, StartAttempt{StartedAt: now})
+}
+
+// Fail records the service which failed the current attempt.
+func (s *StartAttempts) Fail(service string, err error) {
+	if len(s.Attempts) == 0 {
+		return
+	}
+	current := &s.Attempts[len(s.Attempts)-1]
+	current.FailedService = service
+	if err != nil {
+		current.Error = err.Error()
+	}
+}
+
+// Discard forgets the current attempt, e.g. because it was interrupted.
+func (s *StartAttempts) Discard() {
+	if len(s.Attempts) != 0 {
+		s.Attempts = s.Attempts[:len(s.Attempts)-1]
+	}
+}
+
+// FailedWithin returns the attempts started within the window before now.
+func (s *StartAttempts) FailedWithin(now time.Time, window time.Duration) []StartAttempt {
+	recent := []StartAttempt{}
+	for _, a := range s.Attempts {
+		if now.Sub(a.StartedAt) <= window {
+			recent = append(recent, a)
+		}
+	}
+	return recent
+}
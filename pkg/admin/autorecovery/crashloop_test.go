@@ -0,0 +1,67 @@
+package autorecovery
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_StartAttempts(t *testing.T) {
+	dir := t.TempDir()
+	window := 30 * time.Minute
+	now := time.Date(2024, 10, 10, 12, 0, 0, 0, time.UTC)
+
+	attempts, err := LoadStartAttempts(dir)
+	require.NoError(t, err)
+	assert.Empty(t, attempts.FailedWithin(now, window))
+
+	// First attempt crashes without reporting the failure.
+	attempts.Begin(now.Add(-40*time.Minute), window)
+	require.NoError(t, attempts.Save())
+
+	// Second attempt is stopped due to a failing service.
+	attempts, err = LoadStartAttempts(dir)
+	require.NoError(t, err)
+	attempts.Begin(now.Add(-20*time.Minute), window)
+	attempts.Fail("etcd", errors.New("etcd failed"))
+	require.NoError(t, attempts.Save())
+
+	// Third attempt is interrupted before getting ready.
+	attempts, err = LoadStartAttempts(dir)
+	require.NoError(t, err)
+	attempts.Begin(now.Add(-10*time.Minute), window)
+	attempts.Discard()
+	require.NoError(t, attempts.Save())
+
+	attempts, err = LoadStartAttempts(dir)
+	require.NoError(t, err)
+	assert.Len(t, attempts.Attempts, 2)
+	assert.Equal(t, []StartAttempt{{
+		StartedAt:     now.Add(-20 * time.Minute),
+		FailedService: "etcd",
+		Error:         "etcd failed",
+	}}, attempts.FailedWithin(now, window))
+
+	// Attempts outside of the window are forgotten when new one begins.
+	attempts.Begin(now, window)
+	assert.Len(t, attempts.Attempts, 2)
+
+	require.NoError(t, attempts.Save())
+	require.NoError(t, attempts.Clear())
+	assert.NoFileExists(t, filepath.Join(dir, startAttemptsFilename))
+	require.NoError(t, attempts.Clear())
+}
+
+func Test_LoadStartAttempts_Corrupted(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, startAttemptsFilename), []byte("{"), 0600))
+
+	attempts, err := LoadStartAttempts(dir)
+	require.NoError(t, err)
+	assert.Empty(t, attempts.Attempts)
+}
@@ -0,0 +1,212 @@
+package autorecovery
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/openshift/microshift/pkg/admin/data"
+	"github.com/openshift/microshift/pkg/util"
+)
+
+// RestorePlan explains what PerformRestore would do without modifying anything.
+type RestorePlan struct {
+	Storage data.StoragePath `json:"storage"`
+	// Version is the deployment ID (ostree/bootc systems) or the version of
+	// the MicroShift executable (RPM systems) that backups must match.
+	Version    string          `json:"version"`
+	LastBackup data.BackupName `json:"lastBackup,omitempty"`
+
+	Candidate data.BackupName `json:"candidate,omitempty"`
+	Reason    string          `json:"reason,omitempty"`
+	Encrypted bool            `json:"encrypted,omitempty"`
+	Skipped   []SkippedBackup `json:"skipped"`
+
+	DiskSpaceChecks []DiskSpaceCheck `json:"diskSpaceChecks"`
+	Operations      []Operation      `json:"operations"`
+
+	// failedPath is where the current data is saved, empty unless the manager saves failed data.
+	failedPath string
+	// previousPath is the previously restored backup to move to the `restored` substorage,
+	// empty if there is none.
+	previousPath string
+}
+
+// DiskSpaceCheck describes a check performed before the restore.
+type DiskSpaceCheck struct {
+	Purpose   string `json:"purpose"`
+	Path      string `json:"path"`
+	Required  uint64 `json:"required"`
+	Available uint64 `json:"available"`
+}
+
+func (c DiskSpaceCheck) Passed() bool {
+	return c.Available >= c.Required
+}
+
+// Operation is a file system operation performed during the restore.
+// Intermediate paths end with ".tmp.*", because their suffix is random.
+type Operation struct {
+	Action      string `json:"action"`
+	Source      string `json:"source,omitempty"`
+	Destination string `json:"destination"`
+}
+
+// PlanRestore selects the backup to restore and describes the steps
+// of the restore without performing them. PerformRestore executes the plan.
+// If the restore is not possible, returned plan explains why together with the error.
+func (m *Manager) PlanRestore() (*RestorePlan, error) {
+	if err := storageShouldExist(m.storage); err != nil {
+		return nil, err
+	}
+
+	existingState, err := GetState(m.storage)
+	if err != nil {
+		return nil, err
+	}
+
+	ver, err := getVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &RestorePlan{Storage: m.storage, Version: ver}
+	if existingState != nil {
+		plan.LastBackup = existingState.LastBackup
+	}
+
+	backups, err := GetBackups(m.storage)
+	if err != nil {
+		return nil, err
+	}
+	candidate, skipped, err := selectCandidateForRestore(backups, plan.LastBackup, ver)
+	plan.Skipped = skipped
+	if err != nil {
+		return plan, err
+	}
+	plan.Candidate = candidate.Name()
+	plan.Reason = fmt.Sprintf("it is the most recent backup matching the system's version %q", ver)
+	if plan.LastBackup != "" {
+		plan.Reason += " which is not the previously restored backup"
+	}
+
+	candidatePath := m.storage.GetBackupPath(plan.Candidate)
+	if plan.Encrypted, err = data.IsEncryptedBackupDir(candidatePath); err != nil {
+		return plan, err
+	}
+	if plan.Encrypted && m.encryptionKey == nil {
+		return plan, fmt.Errorf("%q: %w", candidatePath, data.ErrBackupEncrypted)
+	}
+
+	if err := m.planDiskSpaceChecks(plan, candidatePath); err != nil {
+		return plan, err
+	}
+	if err := m.planOperations(plan, candidatePath); err != nil {
+		return plan, err
+	}
+
+	for _, c := range plan.DiskSpaceChecks {
+		if !c.Passed() {
+			return plan, fmt.Errorf("not enough disk space in %q to %s: required=%vM available=%vM",
+				c.Path, c.Purpose, c.Required/1024/1024, c.Available/1024/1024)
+		}
+	}
+	return plan, nil
+}
+
+// planDiskSpaceChecks computes the disk space required by the restore.
+func (m *Manager) planDiskSpaceChecks(plan *RestorePlan, candidatePath string) error {
+	var required uint64
+	if plan.Encrypted {
+		manifest, err := data.ReadArchiveManifest(filepath.Join(candidatePath, data.EncryptedBackupFileName), m.encryptionKey)
+		if err != nil {
+			return err
+		}
+		required = uint64(float64(manifest.TotalSize()) * 1.1)
+	} else {
+		size, err := data.GetSizeOfDir(candidatePath)
+		if err != nil {
+			return err
+		}
+		required = size
+	}
+	dataParent := filepath.Dir(m.dataDir)
+	available, err := data.GetAvailableDiskSpace(dataParent)
+	if err != nil {
+		return err
+	}
+	plan.DiskSpaceChecks = append(plan.DiskSpaceChecks, DiskSpaceCheck{
+		Purpose:   "restore the backup",
+		Path:      dataParent,
+		Required:  required,
+		Available: available,
+	})
+
+	if m.saveFailed {
+		required, err := data.GetSizeOfMicroShiftData(m.dataDir)
+		if err != nil {
+			return err
+		}
+		available, err := data.GetAvailableDiskSpace(string(m.storage))
+		if err != nil {
+			return err
+		}
+		plan.DiskSpaceChecks = append(plan.DiskSpaceChecks, DiskSpaceCheck{
+			Purpose:   "save the failed data",
+			Path:      string(m.storage),
+			Required:  required,
+			Available: available,
+		})
+	}
+	return nil
+}
+
+// planOperations lists operations in the order they are performed by executePlan.
+func (m *Manager) planOperations(plan *RestorePlan, candidatePath string) error {
+	copyAction := "COPY"
+	if plan.Encrypted {
+		copyAction = "DECRYPT"
+	}
+	saveAction := "COPY"
+	if m.encryptionKey != nil {
+		saveAction = "ENCRYPT"
+	}
+
+	if m.saveFailed {
+		name, err := GetBackupName()
+		if err != nil {
+			return err
+		}
+		plan.failedPath = m.storage.SubStorage(failedSubstorageName).GetBackupPath(name)
+		plan.Operations = append(plan.Operations,
+			Operation{Action: saveAction, Source: m.dataDir, Destination: plan.failedPath + ".tmp.*"})
+	}
+
+	statePath := filepath.Join(string(m.storage), stateFilename)
+	plan.Operations = append(plan.Operations,
+		Operation{Action: copyAction, Source: candidatePath, Destination: m.dataDir + ".tmp.*"},
+		Operation{Action: "CREATE", Destination: statePath + ".tmp.*"},
+		Operation{Action: "RENAME", Source: m.dataDir + ".tmp.*", Destination: m.dataDir},
+		Operation{Action: "RENAME", Source: statePath + ".tmp.*", Destination: statePath},
+	)
+	if m.saveFailed {
+		plan.Operations = append(plan.Operations,
+			Operation{Action: "RENAME", Source: plan.failedPath + ".tmp.*", Destination: plan.failedPath})
+	}
+
+	if plan.LastBackup != "" {
+		previous := m.storage.GetBackupPath(plan.LastBackup)
+		exists, err := util.PathExists(previous)
+		if err != nil {
+			return err
+		}
+		if exists {
+			plan.previousPath = previous
+			plan.Operations = append(plan.Operations, Operation{
+				Action:      "RENAME",
+				Source:      previous,
+				Destination: m.storage.SubStorage(restoredSubstorageName).GetBackupPath(plan.LastBackup),
+			})
+		}
+	}
+	return nil
+}
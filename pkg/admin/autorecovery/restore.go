@@ -3,9 +3,10 @@ package autorecovery
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
 
 	"github.com/openshift/microshift/pkg/admin/data"
-	"github.com/openshift/microshift/pkg/config"
 	"github.com/openshift/microshift/pkg/util"
 	"k8s.io/klog/v2"
 )
@@ -15,75 +16,96 @@ const (
 	restoredSubstorageName = "restored"
 )
 
+// IsStorageEntry returns true if the name is one of the files or directories
+// that auto-recovery keeps in its storage next to the backups.
+func IsStorageEntry(name string) bool {
+	return slices.Contains([]string{stateFilename, startAttemptsFilename, failedSubstorageName, restoredSubstorageName}, name)
+}
+
 type Manager struct {
 	storage    data.StoragePath
+	dataDir    string
 	saveFailed bool
+
+	// encryptionKey is used to decrypt encrypted backups and to encrypt failed data.
+	// Nil means that encrypted backups cannot be restored.
+	encryptionKey []byte
+
+	// dataManager runs the restore hooks, if configured with data.WithHooks.
+	dataManager data.Manager
 }
 
-func NewManager(storage data.StoragePath, saveFailed bool) (*Manager, error) {
+// NewManager creates the auto-recovery manager. The options configure the data.Manager
+// of the storage, which runs the restore hooks around the restore.
+func NewManager(storage data.StoragePath, dataDir string, saveFailed bool, encryptionKey []byte, opts ...data.ManagerOption) (*Manager, error) {
 	if storage == "" {
 		return nil, fmt.Errorf("`storage` argument is empty")
 	}
-
-	return &Manager{storage: storage, saveFailed: saveFailed}, nil
-}
-
-func (m *Manager) PerformRestore() error {
-	if err := storageShouldExist(m.storage); err != nil {
-		return err
+	if dataDir == "" {
+		return nil, fmt.Errorf("`dataDir` argument is empty")
 	}
 
-	existingState, err := GetState(m.storage)
+	dataManager, err := data.NewManager(storage, append([]data.ManagerOption{data.WithDataDir(dataDir)}, opts...)...)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	restoreCandidate, err := getCandidateForRestore(m.storage, existingState)
+	return &Manager{storage: storage, dataDir: dataDir, saveFailed: saveFailed, encryptionKey: encryptionKey, dataManager: dataManager}, nil
+}
+
+// PerformRestore restores the most recent backup matching the system's version
+// which is not the previously restored backup, following the plan of PlanRestore.
+func (m *Manager) PerformRestore() error {
+	plan, err := m.PlanRestore()
 	if err != nil {
 		return err
 	}
+	klog.InfoS("Candidate backup for restore", "candidate", plan.Candidate, "skipped", plan.Skipped)
+	return m.dataManager.RunWithRestoreHooks(plan.Candidate, func() error {
+		return m.executePlan(plan)
+	})
+}
 
+// executePlan performs the operations of the plan. Disk space checks are already done by PlanRestore.
+func (m *Manager) executePlan(plan *RestorePlan) error {
 	/*
 		Preparations - initial file system operations:
-		  COPY    $STORAGE/$CANDIDATE -> /var/lib/microshift.tmp
-		  COPY    /var/lib/microshift -> $STORAGE/failed/$DATE_DEPLOY-ID.tmp
+		  COPY    $STORAGE/$CANDIDATE -> $DATA_DIR.tmp
+		  COPY    $DATA_DIR -> $STORAGE/failed/$DATE_DEPLOY-ID.tmp
 		  CREATE  $STORAGE/state.json.new
 		  -       $STORAGE/$PREVIOUSLY_RESTORED									Should already exist, nothing to do.
 
 		"Closing the transaction"
-		  RENAME    /var/lib/microshift.tmp -> /var/lib/microshift
+		  RENAME    $DATA_DIR.tmp -> $DATA_DIR
 						This operation is first, because it's the most important one - whole point of the restore is to get to running state.
 		  RENAME    $STORAGE/failed/DATE_DEPLOY-ID.tmp -> $STORAGE/failed/DATE_DEPLOY-ID
 		  RENAME    $STORAGE/state.json.new -> $STORAGE/state.json
 		  RENAME    $STORAGE/$PREVIOUSLY_RESTORED -> $STORAGE/restored/$PREVIOUSLY_RESTORED
 	*/
 
-	if err := data.CheckIfEnoughSpaceToRestore(m.storage.GetBackupPath(restoreCandidate.Name())); err != nil {
-		return err
+	candidatePath := m.storage.GetBackupPath(plan.Candidate)
+	var decryptionKey []byte
+	if plan.Encrypted {
+		decryptionKey = m.encryptionKey
 	}
 
 	// Copies/creations into intermediate destinations
 	var oldData *data.AtomicDirCopy
-	if m.saveFailed {
-		if err := data.CheckIfEnoughSpaceToBackUp(string(m.storage)); err != nil {
-			return err
-		}
-
-		oldDataBackupName, err := GetBackupName()
-		if err != nil {
-			return err
-		}
-		failedStorage := m.storage.SubStorage(failedSubstorageName)
-		if err := os.MkdirAll(string(failedStorage), 0600); err != nil {
+	if plan.failedPath != "" {
+		if err := os.MkdirAll(filepath.Dir(plan.failedPath), 0600); err != nil {
 			return fmt.Errorf("failed to create %q subdirectory: %w", failedSubstorageName, err)
 		}
-		oldData = &data.AtomicDirCopy{Source: config.DataDir, Destination: failedStorage.GetBackupPath(oldDataBackupName)}
+		oldData = &data.AtomicDirCopy{
+			Source:        m.dataDir,
+			Destination:   plan.failedPath,
+			EncryptionKey: m.encryptionKey,
+		}
 		if err := oldData.CopyToIntermediate(); err != nil {
 			return fmt.Errorf("old microshift data: %w", err)
 		}
 	}
 
-	newData := data.AtomicDirCopy{Source: m.storage.GetBackupPath(restoreCandidate.Name()), Destination: config.DataDir}
+	newData := data.AtomicDirCopy{Source: candidatePath, Destination: m.dataDir, DecryptionKey: decryptionKey}
 	if err := newData.CopyToIntermediate(); err != nil {
 		if rollbackErr := oldData.RollbackIntermediate(); rollbackErr != nil {
 			klog.ErrorS(rollbackErr, "Failed to rollback intermediate state for old data")
@@ -91,7 +113,7 @@ func (m *Manager) PerformRestore() error {
 		return fmt.Errorf("new microshift data: %w", err)
 	}
 
-	newState := NewState(m.storage, restoreCandidate.Name())
+	newState := NewState(m.storage, plan.Candidate)
 	if err := newState.SaveToIntermediate(); err != nil {
 		if rollbackErr := oldData.RollbackIntermediate(); rollbackErr != nil {
 			klog.ErrorS(rollbackErr, "Failed to rollback intermediate state for old data")
@@ -106,7 +128,10 @@ func (m *Manager) PerformRestore() error {
 	if err := newData.RenameToFinal(); err != nil {
 		return fmt.Errorf("new microshift data: %w", err)
 	}
-	// Update the state file right after /var/lib/microshift is restored from a backup
+	if err := data.RemoveHooksRecord(m.dataDir); err != nil {
+		klog.ErrorS(err, "Failed to remove record of the hooks from the data directory")
+	}
+	// Update the state file right after the data dir is restored from a backup
 	if err := newState.MoveToFinal(); err != nil {
 		return fmt.Errorf("new state file: %w", err)
 	}
@@ -114,27 +139,18 @@ func (m *Manager) PerformRestore() error {
 		return fmt.Errorf("old microshift data: %w", err)
 	}
 
-	//nolint:nestif
-	if existingState != nil {
-		previous := m.storage.GetBackupPath(existingState.LastBackup)
-		exists, err := util.PathExists(previous)
-		if err != nil {
-			klog.ErrorS(err, "Failed to check if previously restored backup exists")
-			return err
+	if plan.previousPath != "" {
+		restoredStorage := m.storage.SubStorage(restoredSubstorageName)
+		if err := os.MkdirAll(string(restoredStorage), 0600); err != nil {
+			return fmt.Errorf("failed to create `restored` subdirectory: %w", err)
+		}
+
+		previouslyRestored := data.AtomicDirCopy{
+			Source:      plan.previousPath,
+			Destination: restoredStorage.GetBackupPath(plan.LastBackup),
 		}
-		if exists {
-			restoredStorage := m.storage.SubStorage(restoredSubstorageName)
-			if err := os.MkdirAll(string(restoredStorage), 0600); err != nil {
-				return fmt.Errorf("failed to create `restored` subdirectory: %w", err)
-			}
-
-			previouslyRestored := data.AtomicDirCopy{
-				Source:      m.storage.GetBackupPath(existingState.LastBackup),
-				Destination: restoredStorage.GetBackupPath(existingState.LastBackup),
-			}
-			if err := previouslyRestored.RenameToFinal(); err != nil {
-				return fmt.Errorf("previously restored backup: %w", err)
-			}
+		if err := previouslyRestored.RenameToFinal(); err != nil {
+			return fmt.Errorf("previously restored backup: %w", err)
 		}
 	}
 
@@ -142,37 +158,44 @@ func (m *Manager) PerformRestore() error {
 	return nil
 }
 
-func getCandidateForRestore(storagePath data.StoragePath, existingState *state) (Backup, error) {
-	backups, err := GetBackups(storagePath)
-	if err != nil {
-		return Backup{}, err
-	}
-
-	if existingState != nil {
-		backups = backups.RemoveBackup(existingState.LastBackup)
-	}
-
-	if len(backups) == 0 {
-		klog.InfoS("There are no candidate backups for restoring!")
-		return Backup{}, fmt.Errorf("no backups for restoring")
-	}
+// SkippedBackup is a backup that was not selected for restoring.
+type SkippedBackup struct {
+	Name   data.BackupName `json:"name"`
+	Reason string          `json:"reason"`
+}
 
-	ver, err := getVersion()
-	if err != nil {
-		return Backup{}, err
+// selectCandidateForRestore selects the most recent backup matching the version
+// that is not the previously restored backup. It also returns all the other
+// backups with reasons why they were not selected.
+func selectCandidateForRestore(backups Backups, lastBackup data.BackupName, ver string) (Backup, []SkippedBackup, error) {
+	sorted := slices.Clone(backups)
+	slices.SortFunc(sorted, func(a, b Backup) int {
+		return b.CreationTime.Compare(a.CreationTime)
+	})
+
+	var candidate *Backup
+	skipped := []SkippedBackup{}
+	for _, b := range sorted {
+		reason := ""
+		switch {
+		case lastBackup != "" && b.Name() == lastBackup:
+			reason = "it is the previously restored backup (LastBackup in the state file)"
+		case b.Version != ver:
+			reason = fmt.Sprintf("its version %q doesn't match the system's version %q", b.Version, ver)
+		case candidate != nil:
+			reason = fmt.Sprintf("it is older than the candidate %q", candidate.Name())
+		default:
+			candidate = &b
+			continue
+		}
+		skipped = append(skipped, SkippedBackup{Name: b.Name(), Reason: reason})
 	}
 
-	backups = backups.FilterByVersion(ver)
-	if len(backups) == 0 {
-		klog.InfoS("There are no candidate backups for restoring after filtering by version!")
-		return Backup{}, fmt.Errorf("no backups for restoring")
+	if candidate == nil {
+		klog.InfoS("There are no candidate backups for restoring!", "skipped", skipped)
+		return Backup{}, skipped, fmt.Errorf("no backups for restoring")
 	}
-	klog.InfoS("Potential backups", "bz", backups)
-
-	restoreCandidate := backups.GetMostRecent()
-	klog.InfoS("Candidate backup for restore", "b", restoreCandidate)
-
-	return restoreCandidate, nil
+	return *candidate, skipped, nil
 }
 
 func storageShouldExist(storage data.StoragePath) error {
@@ -0,0 +1,197 @@
+package autorecovery
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/openshift/microshift/pkg/admin/data"
+	"github.com/openshift/microshift/pkg/util"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+)
+
+// RetentionPolicy describes which backups are kept in each of the auto-recovery
+// storages: the main one, "failed", and "restored".
+// Zero value of a field means that the limit is not enforced.
+type RetentionPolicy struct {
+	// KeepLast is a number of the most recent backups to keep.
+	KeepLast int
+
+	// MaxAge is a maximum age of a backup.
+	MaxAge time.Duration
+
+	// MaxTotalSize is a maximum size, in bytes, of all backups in a storage.
+	// The most recent backups are kept first.
+	MaxTotalSize uint64
+}
+
+func (p RetentionPolicy) IsEmpty() bool {
+	return p.KeepLast == 0 && p.MaxAge == 0 && p.MaxTotalSize == 0
+}
+
+func (p RetentionPolicy) Validate() error {
+	if p.KeepLast < 0 {
+		return fmt.Errorf("number of backups to keep cannot be negative: %d", p.KeepLast)
+	}
+	if p.MaxAge < 0 {
+		return fmt.Errorf("maximum age of backups cannot be negative: %v", p.MaxAge)
+	}
+	return nil
+}
+
+// Prune removes backups that exceed the retention policy from the auto-recovery
+// storage and its "failed" and "restored" substorages. The policy is applied
+// to each of them separately.
+// The backup referenced by the state file's LastBackup and the protected
+// backups are never removed, but they count towards the limits.
+// The dataDir is MicroShift's data directory the backups were created from.
+// It returns paths of removed backups.
+func Prune(storage data.StoragePath, dataDir string, policy RetentionPolicy, protected ...data.BackupName) ([]string, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	if policy.IsEmpty() {
+		return nil, nil
+	}
+
+	keep := sets.New(protected...)
+	existingState, err := GetState(storage)
+	if err != nil {
+		return nil, err
+	}
+	if existingState != nil && existingState.LastBackup != "" {
+		keep.Insert(existingState.LastBackup)
+	}
+
+	removed := []string{}
+	for _, s := range []data.StoragePath{
+		storage,
+		storage.SubStorage(failedSubstorageName),
+		storage.SubStorage(restoredSubstorageName),
+	} {
+		r, err := pruneStorage(s, dataDir, policy, keep)
+		removed = append(removed, r...)
+		if err != nil {
+			return removed, err
+		}
+	}
+
+	if len(removed) != 0 {
+		// Deduplicated backups from all the substorages share the main storage's object pool.
+		if err := data.RemoveUnusedObjects(storage); err != nil {
+			return removed, err
+		}
+	}
+
+	return removed, nil
+}
+
+func pruneStorage(storage data.StoragePath, dataDir string, policy RetentionPolicy, keep sets.Set[data.BackupName]) ([]string, error) {
+	if exists, err := util.PathExists(string(storage)); err != nil {
+		return nil, err
+	} else if !exists {
+		return nil, nil
+	}
+
+	backups, err := GetBackups(storage)
+	if err != nil {
+		return nil, err
+	}
+
+	// Files shared by deduplicated backups occupy the disk only once.
+	usage := data.NewDiskUsage()
+	sizeOf := func(b Backup) (uint64, func(), error) {
+		return usage.Uncounted(storage.GetBackupPath(b.Name()))
+	}
+	toRemove, err := selectBackupsToRemove(backups, policy, keep, time.Now(), sizeOf)
+	if err != nil {
+		return nil, err
+	}
+	if len(toRemove) == 0 {
+		return nil, nil
+	}
+
+	dm, err := data.NewManager(storage, data.WithDataDir(dataDir))
+	if err != nil {
+		return nil, err
+	}
+
+	removed := make([]string, 0, len(toRemove))
+	for _, b := range toRemove {
+		if err := dm.RemoveBackup(b.Name()); err != nil {
+			return removed, err
+		}
+		removed = append(removed, dm.GetBackupPath(b.Name()))
+	}
+	klog.InfoS("Pruned auto-recovery backups", "storage", storage, "removed", removed)
+
+	return removed, nil
+}
+
+// selectBackupsToRemove returns backups that exceed the policy's limits.
+// Backups are considered from the most recent one. sizeOf returns the size
+// a backup adds to the backups counted so far, and a function counting it.
+func selectBackupsToRemove(backups Backups, policy RetentionPolicy, keep sets.Set[data.BackupName],
+	now time.Time, sizeOf func(Backup) (uint64, func(), error)) (Backups, error) {
+	sorted := slices.Clone(backups)
+	slices.SortFunc(sorted, func(a, b Backup) int {
+		return b.CreationTime.Compare(a.CreationTime)
+	})
+
+	// Backups that must be kept count towards the limits before any other backup.
+	kept := 0
+	var totalSize uint64
+	for _, b := range sorted {
+		if !keep.Has(b.Name()) {
+			continue
+		}
+		kept++
+		if policy.MaxTotalSize != 0 {
+			size, count, err := sizeOf(b)
+			if err != nil {
+				return nil, err
+			}
+			count()
+			totalSize += size
+		}
+	}
+
+	toRemove := Backups{}
+	for _, b := range sorted {
+		if keep.Has(b.Name()) {
+			continue
+		}
+
+		remove := (policy.KeepLast != 0 && kept >= policy.KeepLast) ||
+			(policy.MaxAge != 0 && now.Sub(b.LocalCreationTime()) > policy.MaxAge)
+		if !remove && policy.MaxTotalSize != 0 {
+			// Only the files not shared with the kept backups are counted.
+			size, count, err := sizeOf(b)
+			if err != nil {
+				return nil, err
+			}
+			if totalSize+size > policy.MaxTotalSize {
+				remove = true
+			} else {
+				count()
+				totalSize += size
+			}
+		}
+		if remove {
+			toRemove = append(toRemove, b)
+			continue
+		}
+		kept++
+	}
+
+	return toRemove, nil
+}
+
+// LocalCreationTime returns the creation time in the local time zone.
+// Backup names contain local time without time zone, so the parsed time
+// is the wall clock time in the UTC.
+func (b Backup) LocalCreationTime() time.Time {
+	t := b.CreationTime
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.Local)
+}
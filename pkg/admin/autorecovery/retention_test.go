@@ -0,0 +1,109 @@
+package autorecovery
+
+import (
+	"testing"
+	"time"
+
+	"github.com/openshift/microshift/pkg/admin/data"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+func Test_selectBackupsToRemove(t *testing.T) {
+	now := time.Date(2024, 10, 10, 0, 0, 0, 0, time.Local)
+	backupAt := func(daysAgo int) Backup {
+		ct := now.AddDate(0, 0, -daysAgo)
+		return Backup{
+			CreationTime: time.Date(ct.Year(), ct.Month(), ct.Day(), ct.Hour(), ct.Minute(), ct.Second(), 0, time.UTC),
+			Version:      "4.18.0",
+		}
+	}
+	b1, b2, b3, b4 := backupAt(1), backupAt(2), backupAt(3), backupAt(4)
+	backups := Backups{b3, b1, b4, b2}
+	sizeOf := func(Backup) (uint64, func(), error) { return 100, func() {}, nil }
+
+	testData := []struct {
+		name     string
+		policy   RetentionPolicy
+		keep     sets.Set[data.BackupName]
+		expected Backups
+	}{
+		{
+			name:     "Empty policy",
+			policy:   RetentionPolicy{},
+			keep:     sets.New[data.BackupName](),
+			expected: Backups{},
+		},
+		{
+			name:     "Keep last 2",
+			policy:   RetentionPolicy{KeepLast: 2},
+			keep:     sets.New[data.BackupName](),
+			expected: Backups{b3, b4},
+		},
+		{
+			name:     "Keep last 2, oldest is protected",
+			policy:   RetentionPolicy{KeepLast: 2},
+			keep:     sets.New(b4.Name()),
+			expected: Backups{b2, b3},
+		},
+		{
+			name:     "Max age",
+			policy:   RetentionPolicy{MaxAge: 60 * time.Hour},
+			keep:     sets.New[data.BackupName](),
+			expected: Backups{b3, b4},
+		},
+		{
+			name:     "Max total size",
+			policy:   RetentionPolicy{MaxTotalSize: 350},
+			keep:     sets.New[data.BackupName](),
+			expected: Backups{b4},
+		},
+		{
+			name:     "Protected backups count towards the size",
+			policy:   RetentionPolicy{MaxTotalSize: 250},
+			keep:     sets.New(b3.Name()),
+			expected: Backups{b2, b4},
+		},
+		{
+			name:     "Combined limits",
+			policy:   RetentionPolicy{KeepLast: 3, MaxAge: 36 * time.Hour},
+			keep:     sets.New[data.BackupName](),
+			expected: Backups{b2, b3, b4},
+		},
+	}
+
+	for _, td := range testData {
+		t.Run(td.name, func(t *testing.T) {
+			toRemove, err := selectBackupsToRemove(backups, td.policy, td.keep, now, sizeOf)
+			assert.NoError(t, err)
+			assert.Equal(t, td.expected, toRemove)
+		})
+	}
+}
+
+func Test_selectBackupsToRemove_SharedFiles(t *testing.T) {
+	now := time.Date(2024, 10, 10, 0, 0, 0, 0, time.Local)
+	backupAt := func(daysAgo int) Backup {
+		ct := now.AddDate(0, 0, -daysAgo)
+		return Backup{
+			CreationTime: time.Date(ct.Year(), ct.Month(), ct.Day(), ct.Hour(), ct.Minute(), ct.Second(), 0, time.UTC),
+			Version:      "4.18.0",
+		}
+	}
+	b1, b2, b3 := backupAt(1), backupAt(2), backupAt(3)
+
+	// b1 and b2 share all their files, b3 has its own.
+	counted := sets.New[string]()
+	files := map[data.BackupName]string{b1.Name(): "shared", b2.Name(): "shared", b3.Name(): "own"}
+	sizeOf := func(b Backup) (uint64, func(), error) {
+		f := files[b.Name()]
+		if counted.Has(f) {
+			return 0, func() {}, nil
+		}
+		return 100, func() { counted.Insert(f) }, nil
+	}
+
+	toRemove, err := selectBackupsToRemove(Backups{b1, b2, b3}, RetentionPolicy{MaxTotalSize: 150}, nil, now, sizeOf)
+	assert.NoError(t, err)
+	assert.Equal(t, Backups{b3}, toRemove)
+}
@@ -83,21 +83,36 @@ func getBackups(fsys fs.FS) (Backups, error) {
 func dirListToBackups(files []string) Backups {
 	backups := make([]Backup, 0, len(files))
 	for _, file := range files {
-		splitName := strings.Split(file, "_")
-		if len(splitName) != 2 {
+		// Skip intermediate directories of backups that are being created or moved.
+		if strings.Contains(file, ".tmp.") {
 			continue
 		}
-		dt := splitName[0]
-		ver := splitName[1]
-		creationTime, err := time.Parse(backupCreationTimeFormat, dt)
+		// Skip other directories, like "failed" and "restored".
+		if len(strings.Split(file, "_")) != 2 {
+			continue
+		}
+		b, err := ParseBackupName(file)
 		if err != nil {
-			klog.ErrorS(err, "Failed to parse datetime part of the backup name", "name", file)
+			klog.ErrorS(err, "Failed to parse the backup name", "name", file)
 			continue
 		}
-		backups = append(backups, Backup{
-			CreationTime: creationTime,
-			Version:      ver,
-		})
+		backups = append(backups, b)
 	}
 	return backups
 }
+
+// ParseBackupName parses the name of the backup created with "backup --auto-recovery".
+func ParseBackupName(name string) (Backup, error) {
+	splitName := strings.Split(name, "_")
+	if len(splitName) != 2 {
+		return Backup{}, fmt.Errorf("name %q doesn't match the schema $dateTime_$version", name)
+	}
+	creationTime, err := time.Parse(backupCreationTimeFormat, splitName[0])
+	if err != nil {
+		return Backup{}, fmt.Errorf("failed to parse datetime part of the backup name: %w", err)
+	}
+	return Backup{
+		CreationTime: creationTime,
+		Version:      splitName[1],
+	}, nil
+}
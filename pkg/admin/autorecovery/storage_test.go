@@ -6,6 +6,7 @@ import (
 	"testing/fstest"
 	"time"
 
+	"github.com/openshift/microshift/pkg/admin/data"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -48,8 +49,9 @@ func Test_dirListToBackups(t *testing.T) {
 		"20241001111100_4.18.0",
 		"20241001112200_4.18.0",
 		"20241001113300_4.18.1",
-		"202410011139004.18.1", // Missing delimiter `_`` - should be ignored
-		"1239832asd_4.18.1",    // Invalid datetime - should be ignored
+		"202410011139004.18.1",           // Missing delimiter `_`` - should be ignored
+		"1239832asd_4.18.1",              // Invalid datetime - should be ignored
+		"20241001114000_4.18.1.tmp.1234", // Intermediate directory - should be ignored
 		"20241001114400_default-35d7b5c80f0f1378d6846f6dc1304bbf1dcdc5847198fcd4e6099364eaf99048.0",
 		"20241001115500_rhel-35d7b5c80f0f1378d6846f6dc1304bbf1dcdc5847198fcd4e6099364eaf99048.0",
 	}
@@ -80,3 +82,23 @@ func Test_getBackups(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, expectedBackups, bs)
 }
+
+func Test_selectCandidateForRestore(t *testing.T) {
+	lastBackup := Backup{CreationTime: time.Date(2024, 10, 1, 3, 0, 0, 0, time.UTC), Version: "4.18.1"}
+	candidate := Backup{CreationTime: time.Date(2024, 10, 1, 2, 0, 0, 0, time.UTC), Version: "4.18.1"}
+	older := Backup{CreationTime: time.Date(2024, 10, 1, 1, 0, 0, 0, time.UTC), Version: "4.18.1"}
+	otherVersion := Backup{CreationTime: time.Date(2024, 10, 1, 4, 0, 0, 0, time.UTC), Version: "4.18.0"}
+
+	selected, skipped, err := selectCandidateForRestore(Backups{older, lastBackup, otherVersion, candidate}, lastBackup.Name(), "4.18.1")
+	assert.NoError(t, err)
+	assert.Equal(t, candidate, selected)
+	assert.Equal(t, []data.BackupName{otherVersion.Name(), lastBackup.Name(), older.Name()},
+		[]data.BackupName{skipped[0].Name, skipped[1].Name, skipped[2].Name})
+	assert.Contains(t, skipped[0].Reason, "version")
+	assert.Contains(t, skipped[1].Reason, "previously restored")
+	assert.Contains(t, skipped[2].Reason, "older")
+
+	_, skipped, err = selectCandidateForRestore(Backups{lastBackup, otherVersion}, lastBackup.Name(), "4.18.1")
+	assert.Error(t, err)
+	assert.Len(t, skipped, 2)
+}
@@ -0,0 +1,117 @@
+package bundle
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	APIVersion = "microshift.openshift.io/v1"
+	Kind       = "ExportBundle"
+)
+
+// Source describes the node the bundle was exported from.
+// Node specific values are rewritten when the bundle is imported on another node.
+type Source struct {
+	NodeName   string `json:"nodeName"`
+	BaseDomain string `json:"baseDomain"`
+	Version    string `json:"version"`
+}
+
+// Bundle is a portable set of API objects from user namespaces,
+// ordered so they can be applied one after another.
+type Bundle struct {
+	APIVersion string                       `json:"apiVersion"`
+	Kind       string                       `json:"kind"`
+	Source     Source                       `json:"source"`
+	Items      []*unstructured.Unstructured `json:"items"`
+}
+
+// kindOrder lists kinds which must exist before the objects referencing them.
+// Other kinds are applied after these.
+var kindOrder = []string{
+	"Namespace",
+	"ResourceQuota",
+	"LimitRange",
+	"ServiceAccount",
+	"Secret",
+	"ConfigMap",
+	"Role",
+	"RoleBinding",
+	"NetworkPolicy",
+	"PersistentVolumeClaim",
+	"Service",
+	"Pod",
+	"Deployment",
+	"StatefulSet",
+	"DaemonSet",
+	"Job",
+	"CronJob",
+	"Ingress",
+	"Route",
+}
+
+func kindPriority(kind string) int {
+	for i, k := range kindOrder {
+		if k == kind {
+			return i
+		}
+	}
+	return len(kindOrder)
+}
+
+// Sort orders the items by kind priority, kind, namespace and name.
+func (b *Bundle) Sort() {
+	sort.SliceStable(b.Items, func(i, j int) bool {
+		a, o := b.Items[i], b.Items[j]
+		if pa, po := kindPriority(a.GetKind()), kindPriority(o.GetKind()); pa != po {
+			return pa < po
+		}
+		if a.GetKind() != o.GetKind() {
+			return a.GetKind() < o.GetKind()
+		}
+		if a.GetNamespace() != o.GetNamespace() {
+			return a.GetNamespace() < o.GetNamespace()
+		}
+		return a.GetName() < o.GetName()
+	})
+}
+
+// Write serializes the bundle as YAML.
+func (b *Bundle) Write(out io.Writer) error {
+	buf, err := yaml.Marshal(b)
+	if err != nil {
+		return fmt.Errorf("failed to marshal bundle: %w", err)
+	}
+	if _, err := out.Write(buf); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	return nil
+}
+
+// ReadFile reads and validates the bundle from the file.
+func ReadFile(path string) (*Bundle, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle: %w", err)
+	}
+	b := &Bundle{}
+	if err := yaml.UnmarshalStrict(buf, b); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal bundle %q: %w", path, err)
+	}
+	if b.APIVersion != APIVersion || b.Kind != Kind {
+		return nil, fmt.Errorf("%q is not a MicroShift export bundle: expected %s/%s, got %s/%s",
+			path, APIVersion, Kind, b.APIVersion, b.Kind)
+	}
+	for i, item := range b.Items {
+		if item == nil || item.GetKind() == "" || item.GetAPIVersion() == "" || item.GetName() == "" {
+			return nil, fmt.Errorf("item #%d of bundle %q is missing apiVersion, kind or name", i, path)
+		}
+	}
+	return b, nil
+}
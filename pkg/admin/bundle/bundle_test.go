@@ -0,0 +1,296 @@
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"sigs.k8s.io/yaml"
+)
+
+func object(t *testing.T, manifest string) *unstructured.Unstructured {
+	t.Helper()
+	obj := &unstructured.Unstructured{}
+	require.NoError(t, yaml.Unmarshal([]byte(manifest), &obj.Object))
+	return obj
+}
+
+func Test_Sort(t *testing.T) {
+	b := &Bundle{Items: []*unstructured.Unstructured{
+		object(t, "{apiVersion: route.openshift.io/v1, kind: Route, metadata: {name: r, namespace: a}}"),
+		object(t, "{apiVersion: example.com/v1, kind: Widget, metadata: {name: w, namespace: a}}"),
+		object(t, "{apiVersion: apps/v1, kind: Deployment, metadata: {name: d, namespace: b}}"),
+		object(t, "{apiVersion: apps/v1, kind: Deployment, metadata: {name: d, namespace: a}}"),
+		object(t, "{apiVersion: v1, kind: ConfigMap, metadata: {name: c, namespace: a}}"),
+		object(t, "{apiVersion: v1, kind: Namespace, metadata: {name: a}}"),
+	}}
+
+	b.Sort()
+
+	refs := []string{}
+	for _, obj := range b.Items {
+		refs = append(refs, objectRef(obj))
+	}
+	assert.Equal(t, []string{
+		"Namespace/a",
+		"ConfigMap/a/c",
+		"Deployment/a/d",
+		"Deployment/b/d",
+		"Route/a/r",
+		"Widget/a/w",
+	}, refs)
+}
+
+func Test_shouldExport(t *testing.T) {
+	testData := []struct {
+		manifest string
+		expected bool
+	}{
+		{"{apiVersion: v1, kind: ConfigMap, metadata: {name: app-config, namespace: a}}", true},
+		{"{apiVersion: v1, kind: ConfigMap, metadata: {name: kube-root-ca.crt, namespace: a}}", false},
+		{"{apiVersion: v1, kind: ServiceAccount, metadata: {name: default, namespace: a}}", false},
+		{"{apiVersion: v1, kind: Secret, type: kubernetes.io/service-account-token, metadata: {name: t, namespace: a}}", false},
+		{"{apiVersion: v1, kind: Secret, type: Opaque, metadata: {name: s, namespace: a}}", true},
+		{"{apiVersion: rbac.authorization.k8s.io/v1, kind: RoleBinding, metadata: {name: 'system:image-pullers', namespace: a}}", false},
+		{"{apiVersion: v1, kind: Service, metadata: {name: kubernetes, namespace: default}}", false},
+		{"{apiVersion: v1, kind: Service, metadata: {name: kubernetes, namespace: a}}", true},
+		{"{apiVersion: apps/v1, kind: ReplicaSet, metadata: {name: rs, namespace: a, ownerReferences: [{apiVersion: apps/v1, kind: Deployment, name: d, uid: x}]}}", false},
+	}
+
+	for _, td := range testData {
+		assert.Equal(t, td.expected, shouldExport(object(t, td.manifest)), td.manifest)
+	}
+}
+
+func Test_sanitize(t *testing.T) {
+	svc := sanitize(object(t, `
+apiVersion: v1
+kind: Service
+metadata:
+  name: svc
+  namespace: a
+  uid: 1234
+  resourceVersion: "5"
+  creationTimestamp: "2024-01-01T00:00:00Z"
+  managedFields: [{manager: kubectl}]
+spec:
+  clusterIP: 10.43.0.10
+  clusterIPs: [10.43.0.10]
+  ports: [{port: 80}]
+status:
+  loadBalancer: {}
+`))
+	assert.Equal(t, object(t, `
+apiVersion: v1
+kind: Service
+metadata:
+  name: svc
+  namespace: a
+spec:
+  ports: [{port: 80}]
+`), svc)
+
+	pvc := sanitize(object(t, `
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: data
+  namespace: a
+  annotations:
+    pv.kubernetes.io/bind-completed: "yes"
+    volume.kubernetes.io/selected-node: node1
+    app: db
+spec:
+  volumeName: pvc-1234
+  resources: {requests: {storage: 1Gi}}
+`))
+	assert.Equal(t, object(t, `
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: data
+  namespace: a
+  annotations:
+    app: db
+spec:
+  resources: {requests: {storage: 1Gi}}
+`), pvc)
+
+	route := sanitize(object(t, `
+apiVersion: route.openshift.io/v1
+kind: Route
+metadata:
+  name: r
+  namespace: a
+  annotations:
+    openshift.io/host.generated: "true"
+spec:
+  host: r-a.apps.example.com
+  to: {kind: Service, name: svc}
+`))
+	assert.Equal(t, object(t, `
+apiVersion: route.openshift.io/v1
+kind: Route
+metadata:
+  name: r
+  namespace: a
+spec:
+  to: {kind: Service, name: svc}
+`), route)
+}
+
+func Test_Rewrite(t *testing.T) {
+	b := &Bundle{
+		Source: Source{NodeName: "old-node", BaseDomain: "old.example.com"},
+		Items: []*unstructured.Unstructured{
+			object(t, `
+apiVersion: apps/v1
+kind: Deployment
+metadata: {name: d, namespace: a}
+spec:
+  template:
+    spec:
+      nodeSelector:
+        kubernetes.io/hostname: old-node
+      affinity:
+        nodeAffinity:
+          requiredDuringSchedulingIgnoredDuringExecution:
+            nodeSelectorTerms:
+            - matchExpressions:
+              - {key: kubernetes.io/hostname, operator: In, values: [old-node, other]}
+              - {key: zone, operator: In, values: [old-node]}
+`),
+			object(t, `{apiVersion: v1, kind: Pod, metadata: {name: p, namespace: a}, spec: {nodeName: old-node}}`),
+			object(t, `{apiVersion: route.openshift.io/v1, kind: Route, metadata: {name: r, namespace: a}, spec: {host: app.apps.old.example.com}}`),
+			object(t, `
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata: {name: i, namespace: a}
+spec:
+  rules: [{host: app.old.example.com}, {host: app.other.com}]
+  tls: [{hosts: [app.old.example.com]}]
+`),
+		},
+	}
+
+	Rewrite(b, Target{NodeName: "new-node", BaseDomain: "new.example.org"})
+
+	assert.Equal(t, object(t, `
+apiVersion: apps/v1
+kind: Deployment
+metadata: {name: d, namespace: a}
+spec:
+  template:
+    spec:
+      nodeSelector:
+        kubernetes.io/hostname: new-node
+      affinity:
+        nodeAffinity:
+          requiredDuringSchedulingIgnoredDuringExecution:
+            nodeSelectorTerms:
+            - matchExpressions:
+              - {key: kubernetes.io/hostname, operator: In, values: [new-node, other]}
+              - {key: zone, operator: In, values: [old-node]}
+`), b.Items[0])
+	assert.Equal(t, object(t, `{apiVersion: v1, kind: Pod, metadata: {name: p, namespace: a}, spec: {nodeName: new-node}}`), b.Items[1])
+	assert.Equal(t, object(t, `{apiVersion: route.openshift.io/v1, kind: Route, metadata: {name: r, namespace: a}, spec: {host: app.apps.new.example.org}}`), b.Items[2])
+	assert.Equal(t, object(t, `
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata: {name: i, namespace: a}
+spec:
+  rules: [{host: app.new.example.org}, {host: app.other.com}]
+  tls: [{hosts: [app.new.example.org]}]
+`), b.Items[3])
+}
+
+func Test_WriteAndReadFile(t *testing.T) {
+	b := &Bundle{
+		APIVersion: APIVersion,
+		Kind:       Kind,
+		Source:     Source{NodeName: "node", BaseDomain: "example.com", Version: "4.19.0"},
+		Items: []*unstructured.Unstructured{
+			object(t, "{apiVersion: v1, kind: Namespace, metadata: {name: a}}"),
+		},
+	}
+	buf := &bytes.Buffer{}
+	require.NoError(t, b.Write(buf))
+
+	path := filepath.Join(t.TempDir(), "bundle.yaml")
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0600))
+	read, err := ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, b, read)
+
+	require.NoError(t, os.WriteFile(path, []byte("{apiVersion: v1, kind: List, items: []}"), 0600))
+	_, err = ReadFile(path)
+	assert.ErrorContains(t, err, "is not a MicroShift export bundle")
+}
+
+func newFakeClients(existingNamespaces ...string) *Clients {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}, meta.RESTScopeRoot)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, meta.RESTScopeNamespace)
+
+	dc := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	dc.PrependReactor("patch", "*", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		patch := action.(clienttesting.PatchAction)
+		if ns := patch.GetNamespace(); ns != "" && !slices.Contains(existingNamespaces, ns) {
+			return true, nil, apierrors.NewNotFound(schema.GroupResource{Resource: "namespaces"}, ns)
+		}
+		return true, &unstructured.Unstructured{Object: map[string]interface{}{}}, nil
+	})
+	return &Clients{Dynamic: dc, Mapper: mapper}
+}
+
+func Test_Import(t *testing.T) {
+	newBundle := func() *Bundle {
+		return &Bundle{Items: []*unstructured.Unstructured{
+			object(t, `{apiVersion: v1, kind: ConfigMap, metadata: {name: cm, namespace: new}}`),
+			object(t, `{apiVersion: v1, kind: ConfigMap, metadata: {name: cm, namespace: existing}}`),
+			object(t, `{apiVersion: v1, kind: Namespace, metadata: {name: new}}`),
+		}}
+	}
+
+	t.Run("dry run tolerates objects in namespaces of the bundle", func(t *testing.T) {
+		results, err := Import(context.Background(), newFakeClients("existing"), newBundle(), Target{}, ImportOptions{DryRun: true})
+		require.NoError(t, err)
+		require.Len(t, results, 3)
+		assert.Equal(t, ImportResult{Object: "Namespace/new"}, results[0])
+		assert.Equal(t, ImportResult{Object: "ConfigMap/existing/cm"}, results[1])
+		assert.Equal(t, "ConfigMap/new/cm", results[2].Object)
+		assert.Empty(t, results[2].Error)
+		assert.Contains(t, results[2].Warning, `namespace "new" is only created by the actual import`)
+	})
+
+	t.Run("objects in missing namespaces fail", func(t *testing.T) {
+		b := newBundle()
+		b.Items = b.Items[:2]
+		results, err := Import(context.Background(), newFakeClients("existing"), b, Target{}, ImportOptions{DryRun: true})
+		assert.ErrorContains(t, err, "failed to import ConfigMap/new/cm")
+		require.Len(t, results, 2)
+		assert.NotEmpty(t, results[1].Error)
+	})
+
+	t.Run("managed namespaces are refused", func(t *testing.T) {
+		b := newBundle()
+		b.Items = append(b.Items,
+			object(t, `{apiVersion: v1, kind: ConfigMap, metadata: {name: cm, namespace: kube-system}}`),
+			object(t, `{apiVersion: v1, kind: Namespace, metadata: {name: openshift-foo}}`))
+		results, err := Import(context.Background(), newFakeClients("existing"), b, Target{}, ImportOptions{})
+		assert.EqualError(t, err, "bundle contains objects in namespaces managed by MicroShift which cannot be imported: kube-system, openshift-foo")
+		assert.Empty(t, results)
+	})
+}
@@ -0,0 +1,299 @@
+package bundle
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog/v2"
+)
+
+var (
+	// managedNamespaces are created and reconciled by MicroShift.
+	managedNamespaces = sets.New("kube-system", "kube-public", "kube-node-lease", "openshift")
+	// managedNamespacePrefixes are prefixes of namespaces reserved for MicroShift's components.
+	managedNamespacePrefixes = []string{"kube-", "openshift-"}
+
+	// skippedResources are never exported: they are either recreated by the cluster,
+	// or are only meaningful in the cluster they exist in.
+	skippedResources = sets.New(
+		schema.GroupResource{Resource: "events"},
+		schema.GroupResource{Resource: "endpoints"},
+		schema.GroupResource{Resource: "bindings"},
+		schema.GroupResource{Resource: "podtemplates"},
+		schema.GroupResource{Group: "events.k8s.io", Resource: "events"},
+		schema.GroupResource{Group: "discovery.k8s.io", Resource: "endpointslices"},
+		schema.GroupResource{Group: "coordination.k8s.io", Resource: "leases"},
+		schema.GroupResource{Group: "apps", Resource: "controllerrevisions"},
+		schema.GroupResource{Group: "storage.k8s.io", Resource: "csistoragecapacities"},
+		schema.GroupResource{Group: "metrics.k8s.io", Resource: "pods"},
+		schema.GroupResource{Group: "authorization.k8s.io", Resource: "localsubjectaccessreviews"},
+	)
+
+	// defaultObjects are created in every namespace by the cluster.
+	defaultObjects = sets.New(
+		"ConfigMap/kube-root-ca.crt",
+		"ConfigMap/openshift-service-ca.crt",
+		"ServiceAccount/default",
+	)
+)
+
+// IsManagedNamespace returns true if the namespace belongs to MicroShift.
+func IsManagedNamespace(name string) bool {
+	if managedNamespaces.Has(name) {
+		return true
+	}
+	for _, p := range managedNamespacePrefixes {
+		if strings.HasPrefix(name, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Clients are used to read and write API objects of any kind.
+type Clients struct {
+	Dynamic   dynamic.Interface
+	Discovery discovery.DiscoveryInterface
+	Mapper    meta.RESTMapper
+}
+
+// NewClients creates clients for the cluster accessible with the kubeconfig.
+func NewClients(kubeconfig string) (*Clients, error) {
+	restConfig, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create rest config: %w", err)
+	}
+	restConfig = rest.AddUserAgent(restConfig, "microshift-export")
+
+	dc, err := dynamic.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dynamic client: %w", err)
+	}
+	disco, err := discovery.NewDiscoveryClientForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create discovery client: %w", err)
+	}
+	return &Clients{
+		Dynamic:   dc,
+		Discovery: disco,
+		Mapper:    restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(disco)),
+	}, nil
+}
+
+// ExportOptions limit the exported objects.
+type ExportOptions struct {
+	// Namespaces to export. All user namespaces are exported if empty.
+	Namespaces []string
+}
+
+// Export collects API objects from user namespaces, excluding objects managed
+// by MicroShift or by controllers, and strips them of cluster specific fields.
+func Export(ctx context.Context, c *Clients, source Source, opts ExportOptions) (*Bundle, error) {
+	b := &Bundle{APIVersion: APIVersion, Kind: Kind, Source: source, Items: []*unstructured.Unstructured{}}
+
+	namespaces, err := getNamespaces(ctx, c, opts.Namespaces)
+	if err != nil {
+		return nil, err
+	}
+
+	resources, err := getExportableResources(c.Discovery)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ns := range namespaces {
+		if ns.GetName() != metav1.NamespaceDefault {
+			b.Items = append(b.Items, sanitize(ns))
+		}
+		for _, gvr := range resources {
+			list, err := c.Dynamic.Resource(gvr).Namespace(ns.GetName()).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return nil, fmt.Errorf("failed to list %s in namespace %q: %w", gvr.String(), ns.GetName(), err)
+			}
+			for i := range list.Items {
+				obj := &list.Items[i]
+				if !shouldExport(obj) {
+					continue
+				}
+				b.Items = append(b.Items, sanitize(obj))
+			}
+		}
+	}
+
+	b.Sort()
+	klog.InfoS("Exported objects", "namespaces", len(namespaces), "objects", len(b.Items))
+	return b, nil
+}
+
+func getNamespaces(ctx context.Context, c *Clients, selected []string) ([]*unstructured.Unstructured, error) {
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}
+	list, err := c.Dynamic.Resource(gvr).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+
+	namespaces := []*unstructured.Unstructured{}
+	found := sets.New[string]()
+	for i := range list.Items {
+		ns := &list.Items[i]
+		name := ns.GetName()
+		if len(selected) != 0 {
+			if !slices.Contains(selected, name) {
+				continue
+			}
+		} else if IsManagedNamespace(name) {
+			continue
+		}
+		found.Insert(name)
+		namespaces = append(namespaces, ns)
+	}
+
+	for _, name := range selected {
+		if !found.Has(name) {
+			return nil, fmt.Errorf("namespace %q does not exist", name)
+		}
+		if IsManagedNamespace(name) {
+			return nil, fmt.Errorf("namespace %q is managed by MicroShift and cannot be exported", name)
+		}
+	}
+	return namespaces, nil
+}
+
+// getExportableResources returns namespaced resources which can be listed and created.
+func getExportableResources(disco discovery.DiscoveryInterface) ([]schema.GroupVersionResource, error) {
+	lists, err := disco.ServerPreferredNamespacedResources()
+	if err != nil {
+		if !discovery.IsGroupDiscoveryFailedError(err) {
+			return nil, fmt.Errorf("failed to discover API resources: %w", err)
+		}
+		// Objects of the unavailable APIs cannot be exported, but the rest can.
+		klog.ErrorS(err, "Some API groups are not available - their objects will not be exported")
+	}
+
+	resources := []schema.GroupVersionResource{}
+	for _, list := range lists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse group version %q: %w", list.GroupVersion, err)
+		}
+		for _, r := range list.APIResources {
+			if strings.Contains(r.Name, "/") {
+				// Subresource
+				continue
+			}
+			if !slices.Contains(r.Verbs, "list") || !slices.Contains(r.Verbs, "create") {
+				continue
+			}
+			if skippedResources.Has(schema.GroupResource{Group: gv.Group, Resource: r.Name}) {
+				continue
+			}
+			resources = append(resources, gv.WithResource(r.Name))
+		}
+	}
+	return resources, nil
+}
+
+// shouldExport returns false for objects that are created by the cluster
+// or by controllers from other objects.
+func shouldExport(obj *unstructured.Unstructured) bool {
+	if len(obj.GetOwnerReferences()) != 0 {
+		return false
+	}
+	if defaultObjects.Has(obj.GetKind() + "/" + obj.GetName()) {
+		return false
+	}
+
+	switch obj.GetKind() {
+	case "Secret":
+		secretType, _, _ := unstructured.NestedString(obj.Object, "type")
+		if secretType == "kubernetes.io/service-account-token" {
+			return false
+		}
+		// Pull secrets generated for service accounts.
+		if _, ok := obj.GetAnnotations()["kubernetes.io/service-account.name"]; ok && secretType == "kubernetes.io/dockercfg" {
+			return false
+		}
+	case "RoleBinding":
+		if strings.HasPrefix(obj.GetName(), "system:") {
+			return false
+		}
+	case "Service":
+		if obj.GetNamespace() == metav1.NamespaceDefault && (obj.GetName() == "kubernetes" || obj.GetName() == "openshift") {
+			return false
+		}
+	}
+	return true
+}
+
+var (
+	removedMetadataFields = []string{
+		"uid", "resourceVersion", "generation", "creationTimestamp", "deletionTimestamp",
+		"deletionGracePeriodSeconds", "managedFields", "selfLink",
+	}
+	// removedAnnotationPrefixes are annotations set by the cluster that would
+	// be wrong in the new cluster.
+	removedAnnotationPrefixes = []string{
+		"pv.kubernetes.io/",
+		"volume.kubernetes.io/",
+		"volume.beta.kubernetes.io/",
+		"openshift.io/sa.scc.",
+		"deployment.kubernetes.io/revision",
+	}
+)
+
+// sanitize returns a copy of the object without fields assigned by the cluster.
+func sanitize(in *unstructured.Unstructured) *unstructured.Unstructured {
+	obj := in.DeepCopy()
+	for _, f := range removedMetadataFields {
+		unstructured.RemoveNestedField(obj.Object, "metadata", f)
+	}
+	unstructured.RemoveNestedField(obj.Object, "status")
+
+	switch obj.GetKind() {
+	case "Service":
+		// Cluster IPs are allocated from the service network of the cluster.
+		if ip, _, _ := unstructured.NestedString(obj.Object, "spec", "clusterIP"); ip != "None" {
+			unstructured.RemoveNestedField(obj.Object, "spec", "clusterIP")
+			unstructured.RemoveNestedField(obj.Object, "spec", "clusterIPs")
+		}
+		unstructured.RemoveNestedField(obj.Object, "spec", "healthCheckNodePort")
+	case "PersistentVolumeClaim":
+		// Persistent volumes are not portable, new ones are provisioned.
+		unstructured.RemoveNestedField(obj.Object, "spec", "volumeName")
+	case "Route":
+		// Generated hosts are generated again by the new cluster.
+		if annotations := obj.GetAnnotations(); annotations["openshift.io/host.generated"] == "true" {
+			unstructured.RemoveNestedField(obj.Object, "spec", "host")
+			delete(annotations, "openshift.io/host.generated")
+			obj.SetAnnotations(annotations)
+		}
+	}
+
+	if annotations := obj.GetAnnotations(); annotations != nil {
+		for k := range annotations {
+			for _, p := range removedAnnotationPrefixes {
+				if strings.HasPrefix(k, p) {
+					delete(annotations, k)
+				}
+			}
+		}
+		obj.SetAnnotations(annotations)
+		if len(annotations) == 0 {
+			unstructured.RemoveNestedField(obj.Object, "metadata", "annotations")
+		}
+	}
+	return obj
+}
@@ -0,0 +1,221 @@
+package bundle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+)
+
+const (
+	fieldManager = "microshift-import"
+	hostnameKey  = "kubernetes.io/hostname"
+)
+
+// Target describes the node the bundle is imported to.
+type Target struct {
+	NodeName   string
+	BaseDomain string
+}
+
+// ImportOptions control how the bundle is imported.
+type ImportOptions struct {
+	// DryRun only validates the objects with the API server without persisting them.
+	DryRun bool
+}
+
+// ImportResult describes the outcome of importing a single object.
+type ImportResult struct {
+	Object string `json:"object"`
+	Error  string `json:"error,omitempty"`
+	// Warning explains why the object was not fully validated in the dry run.
+	Warning string `json:"warning,omitempty"`
+}
+
+// Import rewrites node specific fields of the bundle's objects for the target
+// node and applies them in order using server side apply.
+// Bundles with objects in namespaces managed by MicroShift are refused as a whole.
+// Failure to apply one object doesn't stop the import of the following ones,
+// all the errors are returned together.
+func Import(ctx context.Context, c *Clients, b *Bundle, target Target, opts ImportOptions) ([]ImportResult, error) {
+	if err := checkManagedNamespaces(b); err != nil {
+		return nil, err
+	}
+	Rewrite(b, target)
+	// Namespaces must be applied before their contents, even if the bundle was edited.
+	b.Sort()
+
+	applyOpts := metav1.ApplyOptions{FieldManager: fieldManager, Force: true}
+	if opts.DryRun {
+		applyOpts.DryRun = []string{metav1.DryRunAll}
+	}
+
+	// Namespaces of the bundle are not created in the dry run,
+	// so the API server can't find them when validating their contents.
+	dryRunNamespaces := sets.New[string]()
+	results := []ImportResult{}
+	var errs []error
+	for _, obj := range b.Items {
+		res := ImportResult{Object: objectRef(obj)}
+		err := apply(ctx, c, obj, applyOpts)
+		switch {
+		case err == nil:
+			klog.InfoS("Imported", "object", res.Object, "dryRun", opts.DryRun)
+			if opts.DryRun && isNamespace(obj) {
+				dryRunNamespaces.Insert(obj.GetName())
+			}
+		case apierrors.IsNotFound(err) && dryRunNamespaces.Has(obj.GetNamespace()):
+			res.Warning = fmt.Sprintf("namespace %q is only created by the actual import, the object was not validated", obj.GetNamespace())
+			klog.InfoS("Skipped validation of object in a namespace that does not exist yet", "object", res.Object)
+		default:
+			res.Error = err.Error()
+			errs = append(errs, fmt.Errorf("failed to import %s: %w", res.Object, err))
+		}
+		results = append(results, res)
+	}
+	return results, errors.Join(errs...)
+}
+
+// checkManagedNamespaces refuses the bundle if it contains namespaces managed
+// by MicroShift or objects in them, which are never exported.
+func checkManagedNamespaces(b *Bundle) error {
+	managed := sets.New[string]()
+	for _, obj := range b.Items {
+		if isNamespace(obj) && IsManagedNamespace(obj.GetName()) {
+			managed.Insert(obj.GetName())
+		} else if IsManagedNamespace(obj.GetNamespace()) {
+			managed.Insert(obj.GetNamespace())
+		}
+	}
+	if managed.Len() != 0 {
+		return fmt.Errorf("bundle contains objects in namespaces managed by MicroShift which cannot be imported: %s",
+			strings.Join(sets.List(managed), ", "))
+	}
+	return nil
+}
+
+func isNamespace(obj *unstructured.Unstructured) bool {
+	return obj.GetKind() == "Namespace" && obj.GroupVersionKind().Group == ""
+}
+
+func apply(ctx context.Context, c *Clients, obj *unstructured.Unstructured, opts metav1.ApplyOptions) error {
+	gvk := obj.GroupVersionKind()
+	mapping, err := c.Mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return fmt.Errorf("failed to find API resource: %w", err)
+	}
+
+	client := c.Dynamic.Resource(mapping.Resource)
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		_, err = client.Namespace(obj.GetNamespace()).Apply(ctx, obj.GetName(), obj, opts)
+	} else {
+		_, err = client.Apply(ctx, obj.GetName(), obj, opts)
+	}
+	return err
+}
+
+func objectRef(obj *unstructured.Unstructured) string {
+	if obj.GetNamespace() == "" {
+		return fmt.Sprintf("%s/%s", obj.GetKind(), obj.GetName())
+	}
+	return fmt.Sprintf("%s/%s/%s", obj.GetKind(), obj.GetNamespace(), obj.GetName())
+}
+
+// Rewrite replaces the node name and the base domain of the source node
+// with the target's ones in all the bundle's objects.
+func Rewrite(b *Bundle, target Target) {
+	for _, obj := range b.Items {
+		if b.Source.NodeName != "" && target.NodeName != "" && b.Source.NodeName != target.NodeName {
+			obj.Object = rewriteNodeName(obj.Object, b.Source.NodeName, target.NodeName).(map[string]interface{})
+		}
+		if b.Source.BaseDomain != "" && target.BaseDomain != "" && b.Source.BaseDomain != target.BaseDomain {
+			rewriteHosts(obj, b.Source.BaseDomain, target.BaseDomain)
+		}
+	}
+}
+
+// rewriteNodeName walks the object and replaces references to the node:
+// `nodeName` fields, `kubernetes.io/hostname` node selectors and
+// node affinity expressions using the `kubernetes.io/hostname` key.
+func rewriteNodeName(v interface{}, from, to string) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, child := range val {
+			if s, ok := child.(string); ok && s == from && (k == "nodeName" || k == hostnameKey) {
+				val[k] = to
+				continue
+			}
+			val[k] = rewriteNodeName(child, from, to)
+		}
+		if val["key"] == hostnameKey {
+			if values, ok := val["values"].([]interface{}); ok {
+				for i, value := range values {
+					if value == from {
+						values[i] = to
+					}
+				}
+			}
+		}
+		return val
+	case []interface{}:
+		for i := range val {
+			val[i] = rewriteNodeName(val[i], from, to)
+		}
+		return val
+	}
+	return v
+}
+
+// rewriteHosts replaces the base domain in hosts of Routes and Ingresses.
+func rewriteHosts(obj *unstructured.Unstructured, from, to string) {
+	rewrite := func(host string) string {
+		if host == from {
+			return to
+		}
+		if strings.HasSuffix(host, "."+from) {
+			return strings.TrimSuffix(host, from) + to
+		}
+		return host
+	}
+
+	switch obj.GetKind() {
+	case "Route":
+		if host, ok, _ := unstructured.NestedString(obj.Object, "spec", "host"); ok {
+			_ = unstructured.SetNestedField(obj.Object, rewrite(host), "spec", "host")
+		}
+	case "Ingress":
+		rules, _, _ := unstructured.NestedSlice(obj.Object, "spec", "rules")
+		for _, r := range rules {
+			if rule, ok := r.(map[string]interface{}); ok {
+				if host, ok := rule["host"].(string); ok {
+					rule["host"] = rewrite(host)
+				}
+			}
+		}
+		if rules != nil {
+			_ = unstructured.SetNestedSlice(obj.Object, rules, "spec", "rules")
+		}
+		tls, _, _ := unstructured.NestedSlice(obj.Object, "spec", "tls")
+		for _, t := range tls {
+			if entry, ok := t.(map[string]interface{}); ok {
+				if hosts, ok := entry["hosts"].([]interface{}); ok {
+					for i, h := range hosts {
+						if host, ok := h.(string); ok {
+							hosts[i] = rewrite(host)
+						}
+					}
+				}
+			}
+		}
+		if tls != nil {
+			_ = unstructured.SetNestedSlice(obj.Object, tls, "spec", "tls")
+		}
+	}
+}
@@ -0,0 +1,36 @@
+// Package check holds the results of checks performed by the commands
+// examining MicroShift data without changing it, like 'backup verify'
+// and 'upgrade preflight'.
+package check
+
+import "strings"
+
+// Result is an outcome of a single check.
+type Result struct {
+	Name    string `json:"name"`
+	Passed  bool   `json:"passed"`
+	Message string `json:"message"`
+}
+
+// Results lists outcomes of checks in the order they were performed.
+type Results []Result
+
+// Passed returns true if all the checks passed.
+func (r Results) Passed() bool {
+	for _, c := range r {
+		if !c.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// Add records the outcome of a check. The check failed if err is not nil, and
+// the error replaces the message, which describes the outcome of a passed check.
+func (r *Results) Add(name string, err error, msg string) {
+	res := Result{Name: name, Passed: err == nil, Message: msg}
+	if err != nil {
+		res.Message = strings.ReplaceAll(err.Error(), "\n", "; ")
+	}
+	*r = append(*r, res)
+}
@@ -0,0 +1,616 @@
+package data
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+type BackupFormat string
+
+const (
+	// BackupFormatDir is a plain copy of the data directory.
+	BackupFormatDir BackupFormat = "dir"
+	// BackupFormatTarGz is a single, gzip compressed tar file with a manifest.
+	BackupFormatTarGz BackupFormat = "tar.gz"
+
+	// ArchiveManifestName is the name of the first entry of the archive.
+	ArchiveManifestName = "manifest.json"
+)
+
+var (
+	supportedBackupFormats = []BackupFormat{BackupFormatDir, BackupFormatTarGz}
+)
+
+func ParseBackupFormat(format string) (BackupFormat, error) {
+	f := BackupFormat(format)
+	if !slices.Contains(supportedBackupFormats, f) {
+		return "", fmt.Errorf("unsupported backup format %q, supported formats: %v", format, supportedBackupFormats)
+	}
+	return f, nil
+}
+
+// Extension returns a file extension for a backup of given format.
+func (f BackupFormat) Extension() string {
+	if f == BackupFormatDir {
+		return ""
+	}
+	return "." + string(f)
+}
+
+// Manifest describes the contents of an archive backup.
+type Manifest struct {
+	CreationTime time.Time `json:"creationTime"`
+
+	// Version of the MicroShift which created the data (from the version file).
+	Version string `json:"version"`
+	// DeploymentID is only present on ostree/bootc systems.
+	DeploymentID string `json:"deploymentID,omitempty"`
+	BootID       string `json:"bootID,omitempty"`
+
+	// Hooks are the results of the pre-backup hooks run before the archive was created.
+	Hooks []HookResult `json:"hooks,omitempty"`
+
+	Files []ManifestFile `json:"files"`
+}
+
+type ManifestFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// TotalSize returns the sum of sizes of all files in the manifest.
+func (m *Manifest) TotalSize() uint64 {
+	var size int64
+	for _, f := range m.Files {
+		size += f.Size
+	}
+	return uint64(size)
+}
+
+// IsArchive checks if given path is a regular file which is expected
+// for the backups that are not a directory.
+func IsArchive(path string) (bool, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return false, fmt.Errorf("failed to stat %q: %w", path, err)
+	}
+	return fi.Mode().IsRegular(), nil
+}
+
+// BackupArchive creates a compressed archive backup out of the src directory.
+// The src is usually MicroShift's data directory, but it can be another backup.
+// If the manager has an encryption key, the archive is encrypted.
+func (dm *manager) BackupArchive(src string, name BackupName) (string, error) {
+	return dm.withBackupHooks(name, func(pre []HookResult) (string, error) {
+		return dm.backupArchive(src, name, pre)
+	})
+}
+
+func (dm *manager) backupArchive(src string, name BackupName, hooks []HookResult) (string, error) {
+	klog.InfoS("Creating archive backup",
+		"storage", dm.storage,
+		"name", name,
+		"src", src,
+	)
+
+	if err := dm.prepareBackupDestination(name); err != nil {
+		return "", err
+	}
+
+	manifest, err := createManifest(src)
+	if err != nil {
+		return "", err
+	}
+	// If the src is a backup directory, results of the hooks run
+	// when it was created are carried over to the manifest.
+	srcHooks, err := ReadHooksRecord(src)
+	if err != nil {
+		return "", err
+	}
+	manifest.Hooks = append(srcHooks, hooks...)
+
+	dest := dm.GetBackupPath(name)
+	intermediate, err := GenerateUniqueTempPath(dest)
+	if err != nil {
+		return "", err
+	}
+
+	if err := writeArchive(src, intermediate, manifest, dm.encryptionKey); err != nil {
+		if rmErr := os.RemoveAll(intermediate); rmErr != nil {
+			return "", errors.Join(err, fmt.Errorf("failed to remove %q: %w", intermediate, rmErr))
+		}
+		return "", err
+	}
+
+	if err := os.Rename(intermediate, dest); err != nil {
+		return "", fmt.Errorf("failed to rename %q to %q: %w", intermediate, dest, err)
+	}
+
+	klog.InfoS("Created archive backup", "backup", dest, "files", len(manifest.Files))
+	return dest, nil
+}
+
+func createManifest(src string) (*Manifest, error) {
+	meta, err := ReadVersionFile(filepath.Join(src, "version"))
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Manifest{
+		CreationTime: time.Now().UTC(),
+		Version:      meta.Version,
+		DeploymentID: meta.DeploymentID,
+		BootID:       meta.BootID,
+	}
+
+	err = filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() || isHooksRecord(src, path) {
+			return nil
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		sum, size, err := sha256File(path)
+		if err != nil {
+			return err
+		}
+		m.Files = append(m.Files, ManifestFile{Path: rel, Size: size, SHA256: sum})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create manifest of %q: %w", src, err)
+	}
+
+	return m, nil
+}
+
+// isHooksRecord returns true if the path is the record of the hooks
+// of the backup directory, which is not a part of the data.
+func isHooksRecord(src, path string) bool {
+	return path == filepath.Join(src, HooksRecordName)
+}
+
+func sha256File(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to compute checksum of %q: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
+
+// writeArchive writes the archive of the src into dest file.
+// If the key is not nil, the archive is encrypted.
+func writeArchive(src, dest string, manifest *Manifest, key []byte) error {
+	f, err := os.OpenFile(dest, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create %q: %w", dest, err)
+	}
+	defer f.Close()
+
+	bw := bufio.NewWriter(f)
+	var w io.WriteCloser = nopWriteCloser{bw}
+	if key != nil {
+		if w, err = newEncryptingWriter(bw, key); err != nil {
+			return err
+		}
+	}
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+
+	manifestData, err := json.Marshal(manifest)
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    ArchiveManifestName,
+		Mode:    0600,
+		Size:    int64(len(manifestData)),
+		ModTime: manifest.CreationTime,
+	}); err != nil {
+		return err
+	}
+	if _, err := tw.Write(manifestData); err != nil {
+		return err
+	}
+
+	err = filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == src || isHooksRecord(src, path) {
+			return nil
+		}
+		return addToArchive(tw, src, path)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to archive %q: %w", src, err)
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gw.Close(); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+func addToArchive(tw *tar.Writer, root, path string) error {
+	fi, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+
+	link := ""
+	if fi.Mode()&fs.ModeSymlink != 0 {
+		if link, err = os.Readlink(path); err != nil {
+			return err
+		}
+		// Fail early, such archives can't be restored.
+		if filepath.IsAbs(link) || hasParentReference(link) {
+			return fmt.Errorf("symlink %q to %q cannot be archived: only links within the directory without '..' are supported", path, link)
+		}
+	} else if !fi.Mode().IsRegular() && !fi.IsDir() {
+		klog.InfoS("Skipping special file", "path", path, "mode", fi.Mode())
+		return nil
+	}
+
+	hdr, err := tar.FileInfoHeader(fi, link)
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return err
+	}
+	hdr.Name = filepath.ToSlash(rel)
+	if fi.IsDir() {
+		hdr.Name += "/"
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+
+	if !fi.Mode().IsRegular() {
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := io.Copy(tw, f); err != nil {
+		return fmt.Errorf("failed to archive %q: %w", path, err)
+	}
+	return nil
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+type archiveReader struct {
+	*tar.Reader
+	f  *os.File
+	gr *gzip.Reader
+}
+
+// openArchive opens the archive for reading. If the archive is encrypted,
+// it's decrypted using the key.
+func openArchive(path string, key []byte) (*archiveReader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %q: %w", path, err)
+	}
+
+	br := bufio.NewReader(f)
+	var r io.Reader = br
+	if magic, err := br.Peek(len(encryptionMagic)); err == nil && string(magic) == encryptionMagic {
+		if key == nil {
+			f.Close()
+			return nil, fmt.Errorf("%q: %w", path, ErrBackupEncrypted)
+		}
+		if r, err = newDecryptingReader(br, key); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("%q: %w", path, err)
+		}
+	}
+
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%q is not a gzip compressed archive: %w", path, err)
+	}
+
+	return &archiveReader{Reader: tar.NewReader(gr), f: f, gr: gr}, nil
+}
+
+// readToEnd reads the rest of the compressed stream after the end of the archive,
+// so the gzip checksum and, for encrypted archives, the final segment are verified.
+func (a *archiveReader) readToEnd() error {
+	_, err := io.Copy(io.Discard, a.gr)
+	return err
+}
+
+func (a *archiveReader) Close() {
+	_ = a.gr.Close()
+	_ = a.f.Close()
+}
+
+// ReadArchiveManifest reads manifest of the archive backup without extracting it.
+// The key is only required if the archive is encrypted.
+func ReadArchiveManifest(path string, key []byte) (*Manifest, error) {
+	ar, err := openArchive(path, key)
+	if err != nil {
+		return nil, err
+	}
+	defer ar.Close()
+
+	return readManifest(ar.Reader)
+}
+
+func readManifest(tr *tar.Reader) (*Manifest, error) {
+	hdr, err := tr.Next()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read first entry of the archive: %w", err)
+	}
+	if hdr.Name != ArchiveManifestName {
+		return nil, fmt.Errorf("first entry of the archive is %q, expected %q", hdr.Name, ArchiveManifestName)
+	}
+
+	manifest := &Manifest{}
+	if err := json.NewDecoder(tr).Decode(manifest); err != nil {
+		return nil, fmt.Errorf("failed to decode manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+// ExtractArchive extracts the archive into the dest directory verifying
+// checksums of all the files against the manifest.
+// It returns an error if any file doesn't match the manifest,
+// is missing from the archive, or is not listed in the manifest.
+// The key is only required if the archive is encrypted.
+func ExtractArchive(path, dest string, key []byte) error {
+	ar, err := openArchive(path, key)
+	if err != nil {
+		return err
+	}
+	defer ar.Close()
+	tr := ar.Reader
+
+	manifest, err := readManifest(tr)
+	if err != nil {
+		return err
+	}
+
+	expected := make(map[string]ManifestFile, len(manifest.Files))
+	for _, mf := range manifest.Files {
+		expected[filepath.Clean(mf.Path)] = mf
+	}
+
+	if err := os.MkdirAll(dest, 0700); err != nil {
+		return fmt.Errorf("failed to create %q: %w", dest, err)
+	}
+
+	dirs := []*tar.Header{}
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read archive %q: %w", path, err)
+		}
+
+		name := filepath.Clean(filepath.FromSlash(hdr.Name))
+		if escapesRoot(name) {
+			return fmt.Errorf("archive entry %q points outside of the destination", hdr.Name)
+		}
+		if err := ensureNoSymlinkInPath(dest, name); err != nil {
+			return fmt.Errorf("archive entry %q: %w", hdr.Name, err)
+		}
+		target := filepath.Join(dest, name)
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0700); err != nil {
+				return err
+			}
+			dirs = append(dirs, hdr)
+		case tar.TypeSymlink:
+			link := filepath.FromSlash(hdr.Linkname)
+			if filepath.IsAbs(link) || hasParentReference(link) {
+				return fmt.Errorf("archive entry %q links to %q outside of the destination", hdr.Name, hdr.Linkname)
+			}
+			if err := os.Symlink(hdr.Linkname, target); err != nil {
+				return err
+			}
+			if err := os.Lchown(target, hdr.Uid, hdr.Gid); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			mf, ok := expected[name]
+			if !ok {
+				return fmt.Errorf("file %q is not listed in the manifest", name)
+			}
+			delete(expected, name)
+			if err := extractFile(tr, hdr, target, mf); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unsupported type of archive entry %q: %v", hdr.Name, hdr.Typeflag)
+		}
+	}
+
+	if err := ar.readToEnd(); err != nil {
+		return fmt.Errorf("failed to read archive %q: %w", path, err)
+	}
+
+	if len(expected) != 0 {
+		missing := make([]string, 0, len(expected))
+		for p := range expected {
+			missing = append(missing, p)
+		}
+		slices.Sort(missing)
+		return fmt.Errorf("files listed in the manifest are missing from the archive: %s", strings.Join(missing, ", "))
+	}
+
+	// Set directories' metadata after all files were extracted,
+	// otherwise creating files would change directories' mtime.
+	for _, hdr := range dirs {
+		target := filepath.Join(dest, filepath.Clean(filepath.FromSlash(hdr.Name)))
+		if err := setMetadata(target, hdr); err != nil {
+			return err
+		}
+	}
+
+	klog.InfoS("Extracted and verified archive", "archive", path, "dest", dest, "files", len(manifest.Files))
+	return nil
+}
+
+func extractFile(r io.Reader, hdr *tar.Header, target string, mf ManifestFile) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(f, h), r)
+	if err != nil {
+		return fmt.Errorf("failed to extract %q: %w", target, err)
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); sum != mf.SHA256 || size != mf.Size {
+		return fmt.Errorf("checksum mismatch for %q: expected sha256 %s (%d bytes), got %s (%d bytes)",
+			mf.Path, mf.SHA256, mf.Size, sum, size)
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	return setMetadata(target, hdr)
+}
+
+func setMetadata(target string, hdr *tar.Header) error {
+	if err := os.Lchown(target, hdr.Uid, hdr.Gid); err != nil {
+		return err
+	}
+	if err := os.Chmod(target, hdr.FileInfo().Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Chtimes(target, hdr.AccessTime, hdr.ModTime)
+}
+
+// restoreArchive extracts and verifies the archive next to the data directory
+// and only then replaces the data directory with it.
+func (dm *manager) restoreArchive(path string) error {
+	manifest, err := ReadArchiveManifest(path, dm.encryptionKey)
+	if err != nil {
+		return fmt.Errorf("%q is not a valid MicroShift backup: %w", path, err)
+	}
+
+	if err := checkIfEnoughSpaceToRestoreManifest(manifest, dm.dataDir); err != nil {
+		return err
+	}
+
+	intermediate, err := GenerateUniqueTempPath(dm.dataDir)
+	if err != nil {
+		return err
+	}
+	removeIntermediate := func() {
+		if err := os.RemoveAll(intermediate); err != nil {
+			klog.ErrorS(err, "Failed to remove intermediate directory", "path", intermediate)
+		}
+	}
+
+	if err := ExtractArchive(path, intermediate, dm.encryptionKey); err != nil {
+		removeIntermediate()
+		return fmt.Errorf("failed to extract %q: %w", path, err)
+	}
+
+	if err := IsMicroShiftBackup(intermediate); err != nil {
+		removeIntermediate()
+		return fmt.Errorf("%q is not a valid MicroShift backup: %w", path, err)
+	}
+
+	if err := replaceDataDir(intermediate, dm.dataDir); err != nil {
+		removeIntermediate()
+		return err
+	}
+	return nil
+}
+
+// escapesRoot tells if the cleaned relative path points outside of the directory it's relative to.
+func escapesRoot(name string) bool {
+	name = filepath.Clean(name)
+	return filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator))
+}
+
+// hasParentReference tells if the symlink's target contains "..". Such targets are
+// rejected, because they can't be checked lexically: a ".." following a link extracted
+// earlier or later, e.g. "s/.." with s linking to ".", resolves outside of the destination.
+func hasParentReference(link string) bool {
+	return slices.Contains(strings.Split(link, string(filepath.Separator)), "..")
+}
+
+// ensureNoSymlinkInPath fails if any of the parent directories of the name
+// within the root is a symlink, so entries can't be written through symlinks
+// extracted earlier.
+func ensureNoSymlinkInPath(root, name string) error {
+	dir := root
+	parts := strings.Split(filepath.Dir(name), string(filepath.Separator))
+	for _, part := range parts {
+		if part == "." {
+			continue
+		}
+		dir = filepath.Join(dir, part)
+		fi, err := os.Lstat(dir)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if fi.Mode()&fs.ModeSymlink != 0 {
+			return fmt.Errorf("path goes through symlink %q", dir)
+		}
+	}
+	return nil
+}
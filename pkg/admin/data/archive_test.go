@@ -0,0 +1,233 @@
+package data
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createTestDataDir(t *testing.T) string {
+	t.Helper()
+	src := t.TempDir()
+	files := map[string]string{
+		"version":                `{"version":"4.18.0","deployment_id":"rhel-123.0","boot_id":"abc"}`,
+		"certs/ca.crt":           "certificate",
+		"etcd/member/snap/db":    "database",
+		"resources/kube/config":  "kubeconfig",
+		"kubelet-plugins/.empty": "",
+	}
+	for p, c := range files {
+		require.NoError(t, os.MkdirAll(filepath.Join(src, filepath.Dir(p)), 0700))
+		require.NoError(t, os.WriteFile(filepath.Join(src, p), []byte(c), 0600))
+	}
+	return src
+}
+
+func Test_ArchiveRoundTrip(t *testing.T) {
+	src := createTestDataDir(t)
+	archive := filepath.Join(t.TempDir(), "backup.tar.gz")
+
+	manifest, err := createManifest(src)
+	require.NoError(t, err)
+	assert.Equal(t, "4.18.0", manifest.Version)
+	assert.Equal(t, "rhel-123.0", manifest.DeploymentID)
+	assert.Len(t, manifest.Files, 5)
+
+	require.NoError(t, writeArchive(src, archive, manifest, nil))
+
+	readManifest, err := ReadArchiveManifest(archive, nil)
+	require.NoError(t, err)
+	assert.Equal(t, manifest.Files, readManifest.Files)
+
+	dest := filepath.Join(t.TempDir(), "restored")
+	require.NoError(t, ExtractArchive(archive, dest, nil))
+
+	for _, f := range manifest.Files {
+		expected, err := os.ReadFile(filepath.Join(src, f.Path))
+		require.NoError(t, err)
+		actual, err := os.ReadFile(filepath.Join(dest, f.Path))
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual)
+	}
+}
+
+func Test_extractArchive_ChecksumMismatch(t *testing.T) {
+	src := createTestDataDir(t)
+	archive := filepath.Join(t.TempDir(), "backup.tar.gz")
+
+	manifest, err := createManifest(src)
+	require.NoError(t, err)
+	manifest.Files[0].SHA256 = "0000000000000000000000000000000000000000000000000000000000000000"
+	require.NoError(t, writeArchive(src, archive, manifest, nil))
+
+	err = ExtractArchive(archive, filepath.Join(t.TempDir(), "restored"), nil)
+	assert.ErrorContains(t, err, "checksum mismatch")
+}
+
+func Test_extractArchive_FileMissingFromManifest(t *testing.T) {
+	src := createTestDataDir(t)
+	archive := filepath.Join(t.TempDir(), "backup.tar.gz")
+
+	manifest, err := createManifest(src)
+	require.NoError(t, err)
+	manifest.Files = manifest.Files[1:]
+	require.NoError(t, writeArchive(src, archive, manifest, nil))
+
+	err = ExtractArchive(archive, filepath.Join(t.TempDir(), "restored"), nil)
+	assert.ErrorContains(t, err, "not listed in the manifest")
+}
+
+// writeRawArchive writes an unencrypted archive with the entries as they are,
+// e.g. to create archives which MicroShift would never produce.
+func writeRawArchive(t *testing.T, entries []*tar.Header, contents map[string]string) string {
+	t.Helper()
+	manifest := &Manifest{}
+	for name, c := range contents {
+		sum := sha256.Sum256([]byte(c))
+		manifest.Files = append(manifest.Files, ManifestFile{Path: name, SHA256: hex.EncodeToString(sum[:]), Size: int64(len(c))})
+	}
+	manifestData, err := json.Marshal(manifest)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "backup.tar.gz")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: ArchiveManifestName, Mode: 0600, Size: int64(len(manifestData))}))
+	_, err = tw.Write(manifestData)
+	require.NoError(t, err)
+	for _, hdr := range entries {
+		if hdr.Typeflag == tar.TypeReg {
+			hdr.Size = int64(len(contents[hdr.Name]))
+		}
+		require.NoError(t, tw.WriteHeader(hdr))
+		if hdr.Typeflag == tar.TypeReg {
+			_, err = tw.Write([]byte(contents[hdr.Name]))
+			require.NoError(t, err)
+		}
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gw.Close())
+	return path
+}
+
+func Test_extractArchive_Symlinks(t *testing.T) {
+	uid, gid := os.Getuid(), os.Getgid()
+	tests := []struct {
+		name     string
+		entries  []*tar.Header
+		contents map[string]string
+		err      string
+	}{
+		{
+			name: "relative link within destination",
+			entries: []*tar.Header{
+				{Name: "certs/", Typeflag: tar.TypeDir, Mode: 0700},
+				{Name: "certs/ca.crt", Typeflag: tar.TypeReg, Mode: 0600},
+				{Name: "ca.crt", Typeflag: tar.TypeSymlink, Linkname: "certs/ca.crt", Uid: uid, Gid: gid},
+			},
+			contents: map[string]string{"certs/ca.crt": "certificate"},
+		},
+		{
+			name: "absolute link",
+			entries: []*tar.Header{
+				{Name: "x", Typeflag: tar.TypeSymlink, Linkname: "/etc", Uid: uid, Gid: gid},
+			},
+			err: "outside of the destination",
+		},
+		{
+			name: "link escaping destination",
+			entries: []*tar.Header{
+				{Name: "certs/", Typeflag: tar.TypeDir, Mode: 0700},
+				{Name: "certs/x", Typeflag: tar.TypeSymlink, Linkname: "../../etc", Uid: uid, Gid: gid},
+			},
+			err: "outside of the destination",
+		},
+		{
+			name: "link through another link",
+			entries: []*tar.Header{
+				{Name: "s", Typeflag: tar.TypeSymlink, Linkname: ".", Uid: uid, Gid: gid},
+				{Name: "l", Typeflag: tar.TypeSymlink, Linkname: "s/..", Uid: uid, Gid: gid},
+			},
+			err: "outside of the destination",
+		},
+		{
+			name: "link with parent reference within destination",
+			entries: []*tar.Header{
+				{Name: "certs/", Typeflag: tar.TypeDir, Mode: 0700},
+				{Name: "certs/x", Typeflag: tar.TypeSymlink, Linkname: "../version", Uid: uid, Gid: gid},
+			},
+			err: "outside of the destination",
+		},
+		{
+			name: "file written through extracted link",
+			entries: []*tar.Header{
+				{Name: "certs/", Typeflag: tar.TypeDir, Mode: 0700},
+				{Name: "x", Typeflag: tar.TypeSymlink, Linkname: "certs", Uid: uid, Gid: gid},
+				{Name: "x/passwd", Typeflag: tar.TypeReg, Mode: 0600},
+			},
+			contents: map[string]string{"x/passwd": "root"},
+			err:      "goes through symlink",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive := writeRawArchive(t, tt.entries, tt.contents)
+			err := ExtractArchive(archive, filepath.Join(t.TempDir(), "restored"), nil)
+			if tt.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestCheckIfEnoughSpaceToExtract(t *testing.T) {
+	dataDir := filepath.Join(t.TempDir(), "microshift")
+
+	small := &Manifest{Files: []ManifestFile{{Path: "version", Size: 16}}}
+	assert.NoError(t, CheckIfEnoughSpaceToExtract(small, dataDir))
+
+	huge := &Manifest{Files: []ManifestFile{{Path: "etcd/member/snap/db", Size: 1 << 60}}}
+	assert.ErrorContains(t, CheckIfEnoughSpaceToExtract(huge, dataDir), "not enough disk space")
+}
+
+func TestDiskUsage(t *testing.T) {
+	storage := t.TempDir()
+	for _, dir := range []string{"b1", "b2", "b3"} {
+		require.NoError(t, os.Mkdir(filepath.Join(storage, dir), 0700))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(storage, "b1", "db"), make([]byte, 100), 0600))
+	require.NoError(t, os.Link(filepath.Join(storage, "b1", "db"), filepath.Join(storage, "b1", "db-copy")))
+	require.NoError(t, os.Link(filepath.Join(storage, "b1", "db"), filepath.Join(storage, "b2", "db")))
+	require.NoError(t, os.WriteFile(filepath.Join(storage, "b3", "db"), make([]byte, 10), 0600))
+
+	usage := NewDiskUsage()
+	size, count, err := usage.Uncounted(filepath.Join(storage, "b1"))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(100), size)
+
+	// Not counted yet, so b2 occupies the same file.
+	size, _, err = usage.Uncounted(filepath.Join(storage, "b2"))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(100), size)
+
+	count()
+	size, _, err = usage.Uncounted(filepath.Join(storage, "b2"))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), size)
+	size, _, err = usage.Uncounted(filepath.Join(storage, "b3"))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(10), size)
+}
@@ -7,6 +7,7 @@ import (
 	"math/rand/v2"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 
 	"github.com/openshift/microshift/pkg/util"
@@ -30,10 +31,18 @@ var (
 // On Unix systems, the rename operation is atomic. This ensures that the file
 // is either fully renamed or not at all, which helps prevent issues like partial
 // file copies in cases of power failure or unexpected program termination.
+//
+// If EncryptionKey is set, the Source is not copied, but stored as an encrypted
+// backup: a directory containing an encrypted archive.
+// If DecryptionKey is set, the Source must be an encrypted backup which is
+// decrypted and extracted.
 type AtomicDirCopy struct {
 	Source      string
 	Destination string
 
+	EncryptionKey []byte
+	DecryptionKey []byte
+
 	intermediatePath string
 }
 
@@ -46,6 +55,17 @@ func (c *AtomicDirCopy) CopyToIntermediate() error {
 	if err != nil {
 		return err
 	}
+
+	if c.EncryptionKey != nil || c.DecryptionKey != nil {
+		if err := c.transformToIntermediate(); err != nil {
+			if rollbackErr := c.RollbackIntermediate(); rollbackErr != nil {
+				return errors.Join(err, rollbackErr)
+			}
+			return err
+		}
+		return nil
+	}
+
 	cmd := exec.Command("cp", append(cpArgs, c.Source, c.intermediatePath)...) //nolint:gosec
 
 	var outb, errb bytes.Buffer
@@ -68,6 +88,31 @@ func (c *AtomicDirCopy) CopyToIntermediate() error {
 	return nil
 }
 
+func (c *AtomicDirCopy) transformToIntermediate() error {
+	if c.DecryptionKey != nil {
+		src := filepath.Join(c.Source, EncryptedBackupFileName)
+		if err := ExtractArchive(src, c.intermediatePath, c.DecryptionKey); err != nil {
+			return fmt.Errorf("failed to decrypt %q to %q: %w", c.Source, c.intermediatePath, err)
+		}
+		klog.InfoS("Made an intermediate decrypted copy", "src", c.Source, "intermediate", c.intermediatePath)
+		return nil
+	}
+
+	manifest, err := createManifest(c.Source)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(c.intermediatePath, 0700); err != nil {
+		return fmt.Errorf("failed to create %q: %w", c.intermediatePath, err)
+	}
+	dest := filepath.Join(c.intermediatePath, EncryptedBackupFileName)
+	if err := writeArchive(c.Source, dest, manifest, c.EncryptionKey); err != nil {
+		return fmt.Errorf("failed to encrypt %q to %q: %w", c.Source, dest, err)
+	}
+	klog.InfoS("Made an intermediate encrypted copy", "src", c.Source, "intermediate", c.intermediatePath)
+	return nil
+}
+
 func (c *AtomicDirCopy) RollbackIntermediate() error {
 	if c == nil {
 		return nil
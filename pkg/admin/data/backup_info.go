@@ -0,0 +1,225 @@
+package data
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+type BackupType string
+
+const (
+	// BackupTypeDir is a plain or deduplicated copy of the data directory.
+	BackupTypeDir BackupType = "dir"
+	// BackupTypeArchive is a compressed archive created with "backup --format=tar.gz".
+	BackupTypeArchive BackupType = "tar.gz"
+	// BackupTypeEncrypted is an encrypted archive, either a file or a directory containing it.
+	BackupTypeEncrypted BackupType = "encrypted"
+)
+
+// BackupInfo describes a backup. The metadata comes from the backed up version file
+// (or the manifest of the archive), so it's not available for encrypted backups
+// unless the key is provided.
+type BackupInfo struct {
+	Name string     `json:"name"`
+	Path string     `json:"path"`
+	Type BackupType `json:"type"`
+
+	// CreationTime of the archives comes from the manifest.
+	// For directories, the time of the last change of the directory is used.
+	CreationTime time.Time `json:"creationTime"`
+	// Size is the size of the backup's files on the disk.
+	Size uint64 `json:"size"`
+	// DataSize is the size of the data after restoring.
+	DataSize uint64 `json:"dataSize,omitempty"`
+	Files    int    `json:"files,omitempty"`
+
+	Version      string `json:"version,omitempty"`
+	DeploymentID string `json:"deploymentID,omitempty"`
+	BootID       string `json:"bootID,omitempty"`
+
+	// Hooks are the results of the hooks run when the backup was created
+	// (see hooks.go).
+	Hooks []HookResult `json:"hooks,omitempty"`
+
+	// Deduplicated is true if the backup shares files with other backups (see dedup.go).
+	Deduplicated bool `json:"deduplicated,omitempty"`
+	// LastBackup is true if the backup is the auto-recovery's most recently restored backup.
+	// It's not set by this package.
+	LastBackup bool `json:"lastBackup,omitempty"`
+
+	// Error explains why the metadata is incomplete.
+	Error string `json:"error,omitempty"`
+}
+
+// SkippedEntry is an entry of the storage which was not listed as a backup.
+type SkippedEntry struct {
+	Name string
+	Err  error
+}
+
+// ListBackups returns information about all the backups in the storage, in the
+// order of their names. Entries that are not MicroShift backups, or that can't be read,
+// are returned as skipped. Hidden entries, like the deduplication's object pool, are ignored.
+// The key is only needed to read the metadata of encrypted backups.
+func ListBackups(storage StoragePath, key []byte) ([]*BackupInfo, []SkippedEntry, error) {
+	entries, err := os.ReadDir(string(storage))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read storage %q: %w", storage, err)
+	}
+
+	backups := []*BackupInfo{}
+	skipped := []SkippedEntry{}
+	for _, e := range entries {
+		if e.Name()[0] == '.' {
+			continue
+		}
+		info, err := GetBackupInfo(storage.GetBackupPath(BackupName(e.Name())), key)
+		if err != nil {
+			skipped = append(skipped, SkippedEntry{Name: e.Name(), Err: err})
+			continue
+		}
+		backups = append(backups, info)
+	}
+	return backups, skipped, nil
+}
+
+// GetBackupInfo reads metadata of the backup: a directory, an archive, or an encrypted backup.
+// It returns an error if the path is not a MicroShift backup.
+// If the backup is encrypted and the key is not provided (or it's wrong),
+// the returned info only contains the information about the backup's files
+// and the Error field explains why the rest is missing.
+func GetBackupInfo(path string, key []byte) (*BackupInfo, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %q: %w", path, err)
+	}
+
+	info := &BackupInfo{
+		Name:         filepath.Base(path),
+		Path:         path,
+		CreationTime: changeTime(fi),
+	}
+
+	archive := ""
+	if fi.Mode().IsRegular() {
+		if encrypted, err := IsEncrypted(path); err != nil {
+			return nil, err
+		} else if encrypted {
+			info.Type = BackupTypeEncrypted
+		} else if isGzip(path) {
+			info.Type = BackupTypeArchive
+		} else {
+			return nil, fmt.Errorf("%q is not a MicroShift backup", path)
+		}
+		archive = path
+		info.Size = uint64(fi.Size())
+	} else {
+		if encrypted, err := IsEncryptedBackupDir(path); err != nil {
+			return nil, err
+		} else if encrypted {
+			info.Type = BackupTypeEncrypted
+			archive = filepath.Join(path, EncryptedBackupFileName)
+		} else if err := IsMicroShiftBackup(path); err != nil {
+			return nil, fmt.Errorf("%q is not a MicroShift backup: %w", path, err)
+		} else {
+			info.Type = BackupTypeDir
+		}
+		if err := info.readDirStats(path); err != nil {
+			return nil, err
+		}
+	}
+
+	if archive == "" {
+		hooks, err := ReadHooksRecord(path)
+		if err != nil {
+			info.Error = err.Error()
+			return info, nil
+		}
+		info.Hooks = hooks
+
+		vf, err := ReadVersionFile(filepath.Join(path, "version"))
+		if err != nil {
+			info.Error = err.Error()
+			return info, nil
+		}
+		info.Version, info.DeploymentID, info.BootID = vf.Version, vf.DeploymentID, vf.BootID
+		return info, nil
+	}
+
+	manifest, err := ReadArchiveManifest(archive, key)
+	if err != nil {
+		info.Error = err.Error()
+		return info, nil
+	}
+	info.CreationTime = manifest.CreationTime
+	info.Version, info.DeploymentID, info.BootID = manifest.Version, manifest.DeploymentID, manifest.BootID
+	info.DataSize = manifest.TotalSize()
+	info.Files = len(manifest.Files)
+	info.Hooks = manifest.Hooks
+	return info, nil
+}
+
+// readDirStats computes the size of the backup directory and checks if it's deduplicated.
+// For directories that are not encrypted, the size on disk is the same as the size of data.
+func (info *BackupInfo) readDirStats(path string) error {
+	var size uint64
+	files := 0
+	err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() || isHooksRecord(path, p) {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += uint64(fi.Size())
+		files++
+		if st, ok := fi.Sys().(*syscall.Stat_t); ok && st.Nlink > 1 {
+			info.Deduplicated = true
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get size of %q: %w", path, err)
+	}
+
+	info.Size = size
+	if info.Type == BackupTypeDir {
+		info.DataSize = size
+		info.Files = files
+	}
+	return nil
+}
+
+func changeTime(fi fs.FileInfo) time.Time {
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		return time.Unix(st.Ctim.Unix())
+	}
+	return fi.ModTime()
+}
+
+func isGzip(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		return false
+	}
+	defer gr.Close()
+	_, err = gr.Read(make([]byte, 1))
+	return err == nil || errors.Is(err, io.EOF)
+}
@@ -0,0 +1,67 @@
+package data
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ListBackups(t *testing.T) {
+	key := newTestKey(t)
+	storage := StoragePath(t.TempDir())
+
+	src := createTestDataDir(t)
+	require.NoError(t, os.Rename(src, storage.GetBackupPath("dir")))
+	src = createTestDataDir(t)
+	manifest, err := createManifest(src)
+	require.NoError(t, err)
+	require.NoError(t, writeArchive(src, storage.GetBackupPath("archive.tar.gz"), manifest, nil))
+	require.NoError(t, writeArchive(src, storage.GetBackupPath("encrypted.tar.gz.enc"), manifest, key))
+	// Not backups: skipped, except for the hidden object pool.
+	require.NoError(t, os.MkdirAll(storage.GetBackupPath("failed"), 0700))
+	require.NoError(t, os.MkdirAll(filepath.Join(string(storage), ObjectPoolDirName), 0700))
+	require.NoError(t, os.WriteFile(storage.GetBackupPath("state.json"), []byte("{}"), 0600))
+
+	backups, skipped, err := ListBackups(storage, nil)
+	require.NoError(t, err)
+	require.Len(t, backups, 3)
+	require.Len(t, skipped, 2)
+	assert.Equal(t, "failed", skipped[0].Name)
+	assert.ErrorContains(t, skipped[0].Err, "is not a MicroShift backup")
+	assert.Equal(t, "state.json", skipped[1].Name)
+	assert.ErrorContains(t, skipped[1].Err, "is not a MicroShift backup")
+
+	infos := map[string]*BackupInfo{}
+	for _, b := range backups {
+		infos[b.Name] = b
+	}
+
+	dir := infos["dir"]
+	require.NotNil(t, dir)
+	assert.Equal(t, BackupTypeDir, dir.Type)
+	assert.Equal(t, "4.18.0", dir.Version)
+	assert.Equal(t, "rhel-123.0", dir.DeploymentID)
+	assert.Equal(t, 5, dir.Files)
+	assert.Empty(t, dir.Error)
+
+	archive := infos["archive.tar.gz"]
+	require.NotNil(t, archive)
+	assert.Equal(t, BackupTypeArchive, archive.Type)
+	assert.Equal(t, "abc", archive.BootID)
+	assert.Equal(t, manifest.TotalSize(), archive.DataSize)
+	assert.Equal(t, manifest.CreationTime.Unix(), archive.CreationTime.Unix())
+
+	encrypted := infos["encrypted.tar.gz.enc"]
+	require.NotNil(t, encrypted)
+	assert.Equal(t, BackupTypeEncrypted, encrypted.Type)
+	assert.Empty(t, encrypted.Version)
+	assert.NotEmpty(t, encrypted.Error)
+
+	encrypted, err = GetBackupInfo(encrypted.Path, key)
+	require.NoError(t, err)
+	assert.Equal(t, "4.18.0", encrypted.Version)
+	assert.Empty(t, encrypted.Error)
+}
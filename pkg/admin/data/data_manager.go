@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/openshift/microshift/pkg/config"
 	"github.com/openshift/microshift/pkg/util"
@@ -23,17 +24,47 @@ var (
 	)
 )
 
-func NewManager(storage StoragePath) (*manager, error) {
+// ManagerOption configures optional behavior of the Manager.
+type ManagerOption func(*manager)
+
+// WithEncryptionKey makes the Manager encrypt created backups with given key
+// and decrypt encrypted backups when restoring. Nil key disables encryption.
+func WithEncryptionKey(key []byte) ManagerOption {
+	return func(dm *manager) {
+		dm.encryptionKey = key
+	}
+}
+
+// WithDataDir sets MicroShift's data directory which the Manager backs up
+// and restores. Defaults to config.DefaultDataDir.
+func WithDataDir(dir string) ManagerOption {
+	return func(dm *manager) {
+		dm.dataDir = dir
+	}
+}
+
+func NewManager(storage StoragePath, opts ...ManagerOption) (*manager, error) {
 	if storage == "" {
 		return nil, &EmptyArgErr{argName: "storage"}
 	}
-	return &manager{storage: storage}, nil
+	dm := &manager{storage: storage, dataDir: config.DefaultDataDir}
+	for _, opt := range opts {
+		opt(dm)
+	}
+	return dm, nil
 }
 
 var _ Manager = (*manager)(nil)
 
 type manager struct {
-	storage StoragePath
+	storage       StoragePath
+	dataDir       string
+	encryptionKey []byte
+	deduplicate   bool
+
+	// hooksDir contains the hooks to run around backups and restores (see hooks.go).
+	hooksDir    string
+	hookTimeout time.Duration
 }
 
 func (dm *manager) GetBackupPath(name BackupName) string {
@@ -51,6 +82,11 @@ func (dm *manager) RemoveBackup(name BackupName) error {
 	if err := os.RemoveAll(dm.GetBackupPath(name)); err != nil {
 		return fmt.Errorf("failed to delete backup %q: %w", name, err)
 	}
+	if dm.deduplicate {
+		if err := RemoveUnusedObjects(dm.storage); err != nil {
+			return err
+		}
+	}
 	klog.InfoS("Removed backup",
 		"name", name,
 	)
@@ -58,20 +94,21 @@ func (dm *manager) RemoveBackup(name BackupName) error {
 }
 
 func (dm *manager) GetBackupList() ([]BackupName, error) {
-	if exists, err := util.PathExists(config.BackupsDir); err != nil {
+	if exists, err := util.PathExists(string(dm.storage)); err != nil {
 		return nil, err
 	} else if !exists {
 		return []BackupName{}, nil
 	}
 
-	files, err := os.ReadDir(config.BackupsDir)
+	files, err := os.ReadDir(string(dm.storage))
 	if err != nil {
 		return nil, err
 	}
 
 	backups := make([]BackupName, 0, len(files))
 	for _, file := range files {
-		if file.IsDir() {
+		// Hidden directories, like the object pool, are not backups.
+		if file.IsDir() && !strings.HasPrefix(file.Name(), ".") {
 			backups = append(backups, BackupName(file.Name()))
 		}
 	}
@@ -80,53 +117,88 @@ func (dm *manager) GetBackupList() ([]BackupName, error) {
 }
 
 func (dm *manager) Backup(name BackupName) (string, error) {
+	return dm.withBackupHooks(name, func([]HookResult) (string, error) {
+		return dm.backup(name)
+	})
+}
+
+func (dm *manager) backup(name BackupName) (string, error) {
+	if dm.deduplicate && dm.encryptionKey == nil {
+		return dm.backupDeduplicated(name)
+	}
+
 	klog.InfoS("Copying data to backup directory",
 		"storage", dm.storage,
 		"name", name,
-		"data", config.DataDir,
+		"data", dm.dataDir,
 	)
 
+	if err := dm.prepareBackupDestination(name); err != nil {
+		return "", err
+	}
+
+	dest := dm.GetBackupPath(name)
+	copier := AtomicDirCopy{Source: dm.dataDir, Destination: dest, EncryptionKey: dm.encryptionKey}
+	if err := copier.CopyToIntermediate(); err != nil {
+		return "", err
+	}
+	if err := copier.RenameToFinal(); err != nil {
+		return "", err
+	}
+
+	klog.InfoS("Copied data to backup directory",
+		"backup", dest, "data", dm.dataDir)
+	return dest, nil
+}
+
+// prepareBackupDestination verifies that the backup doesn't exist yet,
+// creates the storage if needed, and checks if there is enough disk space.
+func (dm *manager) prepareBackupDestination(name BackupName) error {
+	if err := dm.createBackupDestination(name); err != nil {
+		return err
+	}
+	return CheckIfEnoughSpaceToBackUp(dm.dataDir, string(dm.storage))
+}
+
+// createBackupDestination verifies that the backup doesn't exist yet
+// and creates the storage if needed.
+func (dm *manager) createBackupDestination(name BackupName) error {
 	if name == "" {
-		return "", &EmptyArgErr{"name"}
+		return &EmptyArgErr{"name"}
 	}
 
 	if exists, err := dm.BackupExists(name); err != nil {
-		return "", fmt.Errorf("failed to determine if backup %q exists: %w", name, err)
+		return fmt.Errorf("failed to determine if backup %q exists: %w", name, err)
 	} else if exists {
-		return "", fmt.Errorf("failed to create backup destination %q because it already exists",
+		return fmt.Errorf("failed to create backup destination %q because it already exists",
 			name)
 	}
 
 	if found, err := pathExists(string(dm.storage)); err != nil {
-		return "", fmt.Errorf("failed to determine if storage location %q for backup exists: %w",
+		return fmt.Errorf("failed to determine if storage location %q for backup exists: %w",
 			dm.storage, err)
 	} else if !found {
 		if makeDirErr := util.MakeDir(string(dm.storage)); makeDirErr != nil {
-			return "", fmt.Errorf("failed to create backup storage directory %q: %w",
+			return fmt.Errorf("failed to create backup storage directory %q: %w",
 				dm.storage, makeDirErr)
 		}
 		klog.InfoS("Created backup storage directory", "path", dm.storage)
 	}
 
-	if err := CheckIfEnoughSpaceToBackUp(string(dm.storage)); err != nil {
-		return "", err
-	}
-
-	dest := dm.GetBackupPath(name)
-	if err := copyPath(config.DataDir, dest); err != nil {
-		return "", err
-	}
-
-	klog.InfoS("Copied data to backup directory",
-		"backup", dest, "data", config.DataDir)
-	return dest, nil
+	return nil
 }
 
 func (dm *manager) Restore(name BackupName) error {
+	return dm.RunWithRestoreHooks(name, func() error {
+		return dm.restore(name)
+	})
+}
+
+func (dm *manager) restore(name BackupName) error {
 	klog.InfoS("Copying backup to data directory",
 		"storage", dm.storage,
 		"name", name,
-		"data", config.DataDir,
+		"data", dm.dataDir,
 	)
 
 	if name == "" {
@@ -141,35 +213,57 @@ func (dm *manager) Restore(name BackupName) error {
 		return fmt.Errorf("failed to restore backup, %q does not exist", path)
 	}
 
-	if err := dm.isMicroShiftBackup(path); err != nil {
+	if isArchive, err := IsArchive(path); err != nil {
+		return err
+	} else if isArchive {
+		if err := dm.restoreArchive(path); err != nil {
+			return err
+		}
+		klog.InfoS("Restored archive backup to data directory",
+			"name", name,
+			"data", dm.dataDir,
+		)
+		return nil
+	}
+
+	if isEncrypted, err := IsEncryptedBackupDir(path); err != nil {
+		return err
+	} else if isEncrypted {
+		if err := dm.restoreArchive(filepath.Join(path, EncryptedBackupFileName)); err != nil {
+			return err
+		}
+		klog.InfoS("Restored encrypted backup to data directory",
+			"name", name,
+			"data", dm.dataDir,
+		)
+		return nil
+	}
+
+	if err := IsMicroShiftBackup(path); err != nil {
 		return fmt.Errorf("%q is not a valid MicroShift backup: %w", path, err)
 	}
 
-	if err := CheckIfEnoughSpaceToRestore(path); err != nil {
+	if err := CheckIfEnoughSpaceToRestore(path, dm.dataDir); err != nil {
 		return err
 	}
 
-	tmp := fmt.Sprintf("%s.saved", config.DataDir)
-	klog.InfoS("Renaming existing data dir", "data", config.DataDir, "renamedTo", tmp)
-	if err := os.Rename(config.DataDir, tmp); err != nil {
+	tmp := fmt.Sprintf("%s.saved", dm.dataDir)
+	klog.InfoS("Renaming existing data dir", "data", dm.dataDir, "renamedTo", tmp)
+	if err := os.Rename(dm.dataDir, tmp); err != nil {
 		return fmt.Errorf("failed to rename existing data directory %q to %q: %w",
-			config.DataDir, tmp, err)
+			dm.dataDir, tmp, err)
 	}
 
-	if err := copyPath(path, config.DataDir); err != nil {
+	if err := copyPath(path, dm.dataDir); err != nil {
 		klog.ErrorS(err, "Failed to copy backup, restoring current data dir")
-
-		if err := os.RemoveAll(config.DataDir); err != nil {
-			return fmt.Errorf("failed to remove data directory %q: %w", config.DataDir, err)
-		}
-
-		if err := os.Rename(tmp, config.DataDir); err != nil {
-			return fmt.Errorf("failed to rename temporary directory %q to %q: %w",
-				tmp, config.DataDir, err)
+		if err := restoreSavedDataDir(tmp, dm.dataDir); err != nil {
+			return err
 		}
-
 		return fmt.Errorf("failed to copy backup to data dir: %w", err)
 	}
+	if err := RemoveHooksRecord(dm.dataDir); err != nil {
+		klog.ErrorS(err, "Failed to remove record of the hooks from the data directory")
+	}
 
 	klog.InfoS("Removing temporary data directory", "path", tmp)
 	if err := os.RemoveAll(tmp); err != nil {
@@ -178,15 +272,65 @@ func (dm *manager) Restore(name BackupName) error {
 
 	klog.InfoS("Copied backup to data directory",
 		"name", name,
-		"data", config.DataDir,
+		"data", dm.dataDir,
 	)
 	return nil
 }
 
+// replaceDataDir replaces the data directory with the src directory
+// which must be on the same filesystem. Existing data directory is kept
+// aside until the src is renamed into its place.
+func replaceDataDir(src, dataDir string) error {
+	tmp := fmt.Sprintf("%s.saved", dataDir)
+	dataExists, err := pathExists(dataDir)
+	if err != nil {
+		return err
+	}
+	if dataExists {
+		klog.InfoS("Renaming existing data dir", "data", dataDir, "renamedTo", tmp)
+		if err := os.Rename(dataDir, tmp); err != nil {
+			return fmt.Errorf("failed to rename existing data directory %q to %q: %w",
+				dataDir, tmp, err)
+		}
+	}
+
+	klog.InfoS("Renaming to data directory", "src", src, "data", dataDir)
+	if err := os.Rename(src, dataDir); err != nil {
+		klog.ErrorS(err, "Failed to rename to data directory, restoring current data dir")
+		if dataExists {
+			if err := restoreSavedDataDir(tmp, dataDir); err != nil {
+				return err
+			}
+		}
+		return fmt.Errorf("failed to rename %q to %q: %w", src, dataDir, err)
+	}
+
+	if dataExists {
+		klog.InfoS("Removing temporary data directory", "path", tmp)
+		if err := os.RemoveAll(tmp); err != nil {
+			klog.ErrorS(err, "Failed to remove temporary data directory, leaving in place", "path", tmp)
+		}
+	}
+	return nil
+}
+
+// restoreSavedDataDir puts the data directory saved aside back into its place.
+func restoreSavedDataDir(saved, dataDir string) error {
+	if err := os.RemoveAll(dataDir); err != nil {
+		return fmt.Errorf("failed to remove data directory %q: %w", dataDir, err)
+	}
+
+	if err := os.Rename(saved, dataDir); err != nil {
+		return fmt.Errorf("failed to rename temporary directory %q to %q: %w",
+			saved, dataDir, err)
+	}
+	return nil
+}
+
 func (dm *manager) RemoveData() error {
 	klog.InfoS("Starting MicroShift data removal")
 
-	err := os.RemoveAll(config.DataDir)
+	err := os.RemoveAll(dm.dataDir)
 	if err != nil {
 		return fmt.Errorf("failed to remove MicroShift data: %w", err)
 	}
@@ -195,9 +339,9 @@ func (dm *manager) RemoveData() error {
 	return nil
 }
 
-// isMicroShiftBackup verifies if given path is a valid MicroShift backup
+// IsMicroShiftBackup verifies if given path is a valid MicroShift backup
 // by checking if all expected subdirs exists
-func (dm *manager) isMicroShiftBackup(path string) error {
+func IsMicroShiftBackup(path string) error {
 	files, err := os.ReadDir(path)
 	if err != nil {
 		return fmt.Errorf("failed to ReadDir %q: %w", path, err)
@@ -0,0 +1,358 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+	"k8s.io/klog/v2"
+)
+
+// Deduplicated backups are regular backup directories, but their files are
+// hard links to objects in a content-addressed pool shared by all backups
+// in the storage. Files that didn't change between backups are stored only once,
+// so a new backup costs only the files that changed and the directory entries.
+// Because every backup is a complete directory tree, restoring, moving, and
+// removing backups works the same way as for other backups. This is why backups
+// don't record only a manifest of the objects: the hard links are the manifest,
+// and the link count of an object tells if any backup still uses it.
+//
+// Objects are named after the SHA-256 checksum of their contents, permissions,
+// and ownership, because all hard links of a file share these attributes.
+// Objects that are no longer linked from any backup are removed.
+
+const (
+	// ObjectPoolDirName is the name of the directory within the storage holding the shared objects.
+	// It starts with a dot, so it's not considered a backup.
+	ObjectPoolDirName = ".objects"
+)
+
+// WithDeduplication makes the Manager create backups with files
+// deduplicated using the storage's object pool.
+// It has no effect on encrypted backups.
+func WithDeduplication() ManagerOption {
+	return func(dm *manager) {
+		dm.deduplicate = true
+	}
+}
+
+type dedupEntry struct {
+	rel  string
+	info fs.FileInfo
+	// link is a target of the symlink.
+	link string
+	// object is a path of the regular file's object in the pool.
+	object string
+	sha256 string
+}
+
+// dedupPlan describes how to create a deduplicated copy of the src.
+type dedupPlan struct {
+	src     string
+	entries []dedupEntry
+	// newObjectsSize is a size of the files that are not in the pool yet.
+	newObjectsSize uint64
+}
+
+func (dm *manager) objectPool() string {
+	return filepath.Join(string(dm.storage), ObjectPoolDirName)
+}
+
+// backupDeduplicated creates a backup of the data directory with files
+// that already exist in the pool linked instead of copied.
+func (dm *manager) backupDeduplicated(name BackupName) (string, error) {
+	klog.InfoS("Creating deduplicated backup",
+		"storage", dm.storage,
+		"name", name,
+		"data", dm.dataDir,
+	)
+
+	if err := dm.createBackupDestination(name); err != nil {
+		return "", err
+	}
+
+	plan, err := planDeduplicatedCopy(dm.dataDir, dm.objectPool())
+	if err != nil {
+		return "", err
+	}
+	if err := CheckIfEnoughSpaceToBackUpSize(string(dm.storage), plan.newObjectsSize); err != nil {
+		return "", err
+	}
+
+	dest := dm.GetBackupPath(name)
+	intermediate, err := GenerateUniqueTempPath(dest)
+	if err != nil {
+		return "", err
+	}
+	if err := plan.execute(intermediate); err != nil {
+		if rmErr := os.RemoveAll(intermediate); rmErr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to remove %q: %w", intermediate, rmErr))
+		}
+		if gcErr := RemoveUnusedObjects(dm.storage); gcErr != nil {
+			err = errors.Join(err, gcErr)
+		}
+		return "", err
+	}
+
+	final := AtomicDirCopy{Source: intermediate, Destination: dest}
+	if err := final.RenameToFinal(); err != nil {
+		return "", err
+	}
+
+	if err := RemoveUnusedObjects(dm.storage); err != nil {
+		klog.ErrorS(err, "Failed to remove unused objects - ignoring")
+	}
+
+	klog.InfoS("Created deduplicated backup", "backup", dest, "data", dm.dataDir,
+		"files", len(plan.entries), "newObjectsSize", plan.newObjectsSize)
+	return dest, nil
+}
+
+// planDeduplicatedCopy walks the src and computes checksums of all files
+// to find out which of them need to be added to the pool.
+func planDeduplicatedCopy(src, pool string) (*dedupPlan, error) {
+	plan := &dedupPlan{src: src}
+	newObjects := map[string]bool{}
+
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		entry := dedupEntry{rel: rel, info: fi}
+		switch {
+		case fi.IsDir():
+		case fi.Mode()&fs.ModeSymlink != 0:
+			if entry.link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		case fi.Mode().IsRegular():
+			sum, _, err := sha256File(path)
+			if err != nil {
+				return err
+			}
+			entry.sha256 = sum
+			entry.object = filepath.Join(pool, objectName(sum, fi))
+			if exists, err := pathExists(entry.object); err != nil {
+				return err
+			} else if !exists && !newObjects[entry.object] {
+				newObjects[entry.object] = true
+				plan.newObjectsSize += uint64(fi.Size())
+			}
+		default:
+			klog.InfoS("Skipping special file", "path", path, "mode", fi.Mode())
+			return nil
+		}
+		plan.entries = append(plan.entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk %q: %w", src, err)
+	}
+
+	klog.InfoS("Planned deduplicated copy", "src", src, "entries", len(plan.entries),
+		"newObjects", len(newObjects), "newObjectsSize", plan.newObjectsSize)
+	return plan, nil
+}
+
+// objectName returns a name of the object relative to the pool.
+// The first two characters of the checksum are used as a subdirectory
+// to keep the number of entries in a single directory low.
+func objectName(sum string, fi fs.FileInfo) string {
+	uid, gid := 0, 0
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		uid, gid = int(st.Uid), int(st.Gid)
+	}
+	return filepath.Join(sum[:2], fmt.Sprintf("%s-%o-%d-%d", sum, fi.Mode().Perm(), uid, gid))
+}
+
+// execute creates the deduplicated copy in the dest directory.
+func (p *dedupPlan) execute(dest string) error {
+	dirs := []dedupEntry{}
+	for _, e := range p.entries {
+		target := filepath.Join(dest, e.rel)
+		switch {
+		case e.info.IsDir():
+			if err := os.MkdirAll(target, 0700); err != nil {
+				return err
+			}
+			dirs = append(dirs, e)
+		case e.link != "":
+			if err := os.Symlink(e.link, target); err != nil {
+				return err
+			}
+			if err := lchownLike(target, e.info); err != nil {
+				return err
+			}
+		default:
+			if err := p.linkObject(e, target); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Set directories' metadata after all the entries were created,
+	// otherwise creating entries would change directories' mtime.
+	slices.Reverse(dirs)
+	for _, e := range dirs {
+		target := filepath.Join(dest, e.rel)
+		if err := lchownLike(target, e.info); err != nil {
+			return err
+		}
+		if err := os.Chmod(target, e.info.Mode().Perm()); err != nil {
+			return err
+		}
+		if err := os.Chtimes(target, e.info.ModTime(), e.info.ModTime()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *dedupPlan) linkObject(e dedupEntry, target string) error {
+	exists, err := pathExists(e.object)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		if err := createObject(filepath.Join(p.src, e.rel), e); err != nil {
+			return err
+		}
+	}
+
+	err = os.Link(e.object, target)
+	if errors.Is(err, syscall.EMLINK) {
+		// Object reached the maximum number of links: fall back to a copy.
+		klog.InfoS("Object has too many links - copying", "object", e.object, "target", target)
+		return copyFile(e.object, target, e.info)
+	}
+	return err
+}
+
+// createObject copies the file into the pool. The checksum of the copy
+// must match the checksum computed when planning.
+func createObject(src string, e dedupEntry) error {
+	if err := os.MkdirAll(filepath.Dir(e.object), 0700); err != nil {
+		return err
+	}
+
+	tmp, err := GenerateUniqueTempPath(e.object)
+	if err != nil {
+		return err
+	}
+	if err := copyFile(src, tmp, e.info); err != nil {
+		return errors.Join(err, os.RemoveAll(tmp))
+	}
+
+	sum, _, err := sha256File(tmp)
+	if err != nil {
+		return errors.Join(err, os.RemoveAll(tmp))
+	}
+	if sum != e.sha256 {
+		return errors.Join(fmt.Errorf("%q changed while creating the backup", src), os.RemoveAll(tmp))
+	}
+
+	if err := os.Rename(tmp, e.object); err != nil {
+		return errors.Join(err, os.RemoveAll(tmp))
+	}
+	return nil
+}
+
+// copyFile copies the file preserving the metadata.
+// It attempts to create a reflink (Copy-on-Write clone) first.
+func copyFile(src, dest string, fi fs.FileInfo) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if err := unix.IoctlFileClone(int(out.Fd()), int(in.Fd())); err != nil {
+		if _, err := io.Copy(out, in); err != nil {
+			return fmt.Errorf("failed to copy %q to %q: %w", src, dest, err)
+		}
+	}
+	if err := out.Sync(); err != nil {
+		return err
+	}
+
+	if err := lchownLike(dest, fi); err != nil {
+		return err
+	}
+	if err := os.Chmod(dest, fi.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Chtimes(dest, fi.ModTime(), fi.ModTime())
+}
+
+func lchownLike(path string, fi fs.FileInfo) error {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	return os.Lchown(path, int(st.Uid), int(st.Gid))
+}
+
+// RemoveUnusedObjects removes objects of the storage's pool which are not linked
+// from any backup, and leftovers of interrupted backups.
+func RemoveUnusedObjects(storage StoragePath) error {
+	pool := filepath.Join(string(storage), ObjectPoolDirName)
+	if exists, err := pathExists(pool); err != nil || !exists {
+		return err
+	}
+
+	removed := 0
+	var freed int64
+	err := filepath.WalkDir(pool, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		st, ok := fi.Sys().(*syscall.Stat_t)
+		if !ok {
+			return nil
+		}
+		if st.Nlink > 1 && !strings.Contains(d.Name(), ".tmp.") {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		removed++
+		freed += fi.Size()
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove unused objects from %q: %w", pool, err)
+	}
+
+	klog.InfoS("Removed unused objects", "pool", pool, "removed", removed, "freedM", freed/1024/1024)
+	return nil
+}
@@ -0,0 +1,64 @@
+package data
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func inode(t *testing.T, path string) uint64 {
+	t.Helper()
+	fi, err := os.Stat(path)
+	require.NoError(t, err)
+	return fi.Sys().(*syscall.Stat_t).Ino
+}
+
+func Test_DeduplicatedCopy(t *testing.T) {
+	src := createTestDataDir(t)
+	storage := StoragePath(t.TempDir())
+	pool := filepath.Join(string(storage), ObjectPoolDirName)
+
+	plan, err := planDeduplicatedCopy(src, pool)
+	require.NoError(t, err)
+	// "kubelet-plugins/.empty" has no content
+	assert.Equal(t, uint64(len(`{"version":"4.18.0","deployment_id":"rhel-123.0","boot_id":"abc"}`)+
+		len("certificate")+len("database")+len("kubeconfig")), plan.newObjectsSize)
+	first := storage.GetBackupPath("first")
+	require.NoError(t, plan.execute(first))
+
+	// Change one file: only that file should be added to the pool.
+	require.NoError(t, os.WriteFile(filepath.Join(src, "etcd/member/snap/db"), []byte("new database"), 0600))
+	plan, err = planDeduplicatedCopy(src, pool)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(len("new database")), plan.newObjectsSize)
+	second := storage.GetBackupPath("second")
+	require.NoError(t, plan.execute(second))
+
+	assert.Equal(t, inode(t, filepath.Join(first, "certs/ca.crt")), inode(t, filepath.Join(second, "certs/ca.crt")))
+	assert.NotEqual(t, inode(t, filepath.Join(first, "etcd/member/snap/db")), inode(t, filepath.Join(second, "etcd/member/snap/db")))
+
+	contents, err := os.ReadFile(filepath.Join(second, "etcd/member/snap/db"))
+	require.NoError(t, err)
+	assert.Equal(t, "new database", string(contents))
+
+	// Removing the first backup makes the old database object unused.
+	require.NoError(t, os.RemoveAll(first))
+	require.NoError(t, RemoveUnusedObjects(storage))
+
+	objects := 0
+	require.NoError(t, filepath.WalkDir(pool, func(path string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			objects++
+		}
+		return err
+	}))
+	assert.Equal(t, 5, objects)
+
+	contents, err = os.ReadFile(filepath.Join(second, "certs/ca.crt"))
+	require.NoError(t, err)
+	assert.Equal(t, "certificate", string(contents))
+}
@@ -6,15 +6,17 @@ import (
 	"path/filepath"
 	"syscall"
 
-	"github.com/openshift/microshift/pkg/config"
-
 	"k8s.io/klog/v2"
 )
 
-func GetSizeOfMicroShiftData() (uint64, error) {
-	return GetSizeOfDir(config.DataDir)
+func GetSizeOfMicroShiftData(dataDir string) (uint64, error) {
+	return GetSizeOfDir(dataDir)
 }
 
+// GetSizeOfDir returns the size of the files in the directory increased by 10%.
+// Every hard link is counted, because copying the directory, e.g. to restore
+// a deduplicated backup, creates a separate file for each of them.
+// Use DiskUsage for the space the files occupy on the disk.
 func GetSizeOfDir(path string) (uint64, error) {
 	var size int64
 	err := filepath.Walk(path, func(path string, info fs.FileInfo, err error) error {
@@ -35,6 +37,61 @@ func GetSizeOfDir(path string) (uint64, error) {
 	return roundedUp, nil
 }
 
+// fileID identifies a file regardless of the number of its hard links.
+type fileID struct {
+	dev uint64
+	ino uint64
+}
+
+// DiskUsage sums the space occupied by files on the disk, counting each file
+// once even if it is hard linked from several directories, like the objects
+// shared by deduplicated backups.
+type DiskUsage struct {
+	counted map[fileID]struct{}
+}
+
+func NewDiskUsage() *DiskUsage {
+	return &DiskUsage{counted: map[fileID]struct{}{}}
+}
+
+// Uncounted returns the size of the files under the path which were not counted
+// yet, and a function counting them, so they don't add to the size of other paths.
+func (u *DiskUsage) Uncounted(path string) (uint64, func(), error) {
+	var size uint64
+	files := map[fileID]struct{}{}
+	err := filepath.Walk(path, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		st, ok := info.Sys().(*syscall.Stat_t)
+		if !ok {
+			return fmt.Errorf("failed to get inode of %q", path)
+		}
+		id := fileID{dev: uint64(st.Dev), ino: st.Ino} //nolint:unconvert // Dev is uint32 on some architectures.
+		if _, ok := u.counted[id]; ok {
+			return nil
+		}
+		if _, ok := files[id]; ok {
+			return nil
+		}
+		files[id] = struct{}{}
+		size += uint64(info.Size())
+		return nil
+	})
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to get disk usage of %q: %w", path, err)
+	}
+	count := func() {
+		for id := range files {
+			u.counted[id] = struct{}{}
+		}
+	}
+	return size, count, nil
+}
+
 func GetAvailableDiskSpace(path string) (uint64, error) {
 	var stat syscall.Statfs_t
 	if err := syscall.Statfs(path, &stat); err != nil {
@@ -54,25 +111,59 @@ func GetAvailableDiskSpace(path string) (uint64, error) {
 }
 
 // CheckIfEnoughSpaceToRestore performs a naive check if there is enough
-// disk space on /var/lib filesystem to restore the backup.
+// disk space on the filesystem holding the data directory to restore the backup.
 // It does not accommodate for potential Copy-on-Write disk savings.
-func CheckIfEnoughSpaceToRestore(backupPath string) error {
+func CheckIfEnoughSpaceToRestore(backupPath, dataDir string) error {
 	backupSize, err := GetSizeOfDir(backupPath)
 	if err != nil {
 		return err
 	}
+	return checkIfEnoughSpaceToRestoreSize(backupSize, dataDir)
+}
+
+// CheckIfEnoughSpaceToRestoreEncrypted checks if there is enough disk space
+// on the filesystem holding the data directory to restore the encrypted backup directory.
+// The size of the data is obtained from the manifest, because the size
+// of the backup directory is the size of the compressed archive.
+func CheckIfEnoughSpaceToRestoreEncrypted(backupPath string, key []byte, dataDir string) error {
+	manifest, err := ReadArchiveManifest(filepath.Join(backupPath, EncryptedBackupFileName), key)
+	if err != nil {
+		return err
+	}
+	return checkIfEnoughSpaceToRestoreManifest(manifest, dataDir)
+}
 
-	// Restore process: renames MicroShift data dir, copies backup into place, deletes renamed copy hence the /var/lib
-	// needs extra space before old MicroShift data is removed.
-	availableSpace, err := GetAvailableDiskSpace("/var/lib")
+func checkIfEnoughSpaceToRestoreManifest(manifest *Manifest, dataDir string) error {
+	// Add 10% for the directories and filesystem overhead which is not part of the manifest.
+	return checkIfEnoughSpaceToRestoreSize(uint64(float64(manifest.TotalSize())*1.1), dataDir)
+}
+
+func checkIfEnoughSpaceToRestoreSize(backupSize uint64, dataDir string) error {
+	// Restore process: renames MicroShift data dir, copies backup into place, deletes renamed copy hence
+	// the data dir's parent (/var/lib by default) needs extra space before old MicroShift data is removed.
+	return checkIfEnoughSpaceNextToDataDir(backupSize, dataDir, "restore the backup")
+}
+
+// CheckIfEnoughSpaceToExtract checks if there is enough disk space next to
+// the data directory to extract the archive described by the manifest.
+func CheckIfEnoughSpaceToExtract(manifest *Manifest, dataDir string) error {
+	// Add 10% for the directories and filesystem overhead which is not part of the manifest.
+	return checkIfEnoughSpaceNextToDataDir(uint64(float64(manifest.TotalSize())*1.1), dataDir, "extract the backup")
+}
+
+func checkIfEnoughSpaceNextToDataDir(size uint64, dataDir, operation string) error {
+	parent := filepath.Dir(dataDir)
+	availableSpace, err := GetAvailableDiskSpace(parent)
 	if err != nil {
 		return err
 	}
 
-	if availableSpace < backupSize {
+	if availableSpace < size {
 		return fmt.Errorf(
-			"not enough disk space in /var/lib to restore the backup: required=%vM available=%vM",
-			backupSize/1024/1024,
+			"not enough disk space in %s to %s: required=%vM available=%vM",
+			parent,
+			operation,
+			size/1024/1024,
 			availableSpace/1024/1024,
 		)
 	}
@@ -83,12 +174,18 @@ func CheckIfEnoughSpaceToRestore(backupPath string) error {
 // CheckIfEnoughSpaceToBackUp performs a naive check if there is enough
 // disk space on a filesystem holding the backups for another backup of MicroShift data.
 // It does not accommodate for potential Copy-on-Write disk savings.
-func CheckIfEnoughSpaceToBackUp(storage string) error {
-	dataSize, err := GetSizeOfMicroShiftData()
+// Deduplicated backups use CheckIfEnoughSpaceToBackUpSize with the size of new objects instead.
+func CheckIfEnoughSpaceToBackUp(dataDir, storage string) error {
+	dataSize, err := GetSizeOfMicroShiftData(dataDir)
 	if err != nil {
 		return err
 	}
+	return CheckIfEnoughSpaceToBackUpSize(storage, dataSize)
+}
 
+// CheckIfEnoughSpaceToBackUpSize checks if there is enough disk space
+// on a filesystem holding the backups for a backup of given size.
+func CheckIfEnoughSpaceToBackUpSize(storage string, dataSize uint64) error {
 	availableSpace, err := GetAvailableDiskSpace(storage)
 	if err != nil {
 		return err
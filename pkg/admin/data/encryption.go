@@ -0,0 +1,325 @@
+package data
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// Encrypted backups are regular archive backups (see archive.go) encrypted
+// with AES-256-GCM using a key derived from the user provided key and
+// a random salt stored in the header.
+// The archive is split into segments which are sealed separately, so the backup
+// can be streamed without holding it in memory. Each segment's nonce contains
+// its sequence number and a flag marking the final segment, so reordering,
+// removing, or truncating segments is detected.
+//
+// Layout: magic | salt | key check | segment... | final segment
+
+const (
+	// EncryptedBackupFileName is the name of the file inside of encrypted backup directory.
+	EncryptedBackupFileName = "backup.tar.gz.enc"
+
+	// EncryptionKeySize is the size of the key expected in the key file.
+	EncryptionKeySize = 32
+
+	encryptionMagic       = "MSHBENC1"
+	encryptionSaltSize    = 32
+	encryptionKeyCheckLen = 32
+	encryptionHeaderSize  = len(encryptionMagic) + encryptionSaltSize + encryptionKeyCheckLen
+	encryptionSegmentSize = 64 * 1024
+	encryptionHKDFInfo    = "microshift backup encryption v1"
+)
+
+var (
+	// ErrBackupEncrypted is returned when the backup is encrypted, but the key was not provided.
+	ErrBackupEncrypted = errors.New("backup is encrypted and requires an encryption key")
+)
+
+// LoadEncryptionKey reads the key from a file. The file must contain 32 bytes,
+// either raw or base64 encoded (e.g. created with `openssl rand -base64 32`).
+// The file must not be accessible by group or others, and it must not be
+// stored within MicroShift's data directory, because it would be part of the backup.
+func LoadEncryptionKey(path, dataDir string) ([]byte, error) {
+	if path == "" {
+		return nil, &EmptyArgErr{argName: "path"}
+	}
+
+	realPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve key file path %q: %w", path, err)
+	}
+	realPath, err = filepath.Abs(realPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path of key file %q: %w", path, err)
+	}
+	if isWithinDir(realPath, dataDir) {
+		return nil, fmt.Errorf("key file %q must be stored outside of MicroShift data directory %q", path, dataDir)
+	}
+
+	fi, err := os.Stat(realPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat key file %q: %w", path, err)
+	}
+	if !fi.Mode().IsRegular() {
+		return nil, fmt.Errorf("key file %q is not a regular file", path)
+	}
+	if fi.Mode().Perm()&0077 != 0 {
+		return nil, fmt.Errorf("key file %q must not be accessible by group or others (permissions: %v)", path, fi.Mode().Perm())
+	}
+
+	contents, err := os.ReadFile(realPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file %q: %w", path, err)
+	}
+	return parseEncryptionKey(contents)
+}
+
+func parseEncryptionKey(contents []byte) ([]byte, error) {
+	if len(contents) == EncryptionKeySize {
+		return contents, nil
+	}
+
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(contents)))
+	if err == nil && len(key) == EncryptionKeySize {
+		return key, nil
+	}
+	return nil, fmt.Errorf("key file must contain %d bytes, either raw or base64 encoded", EncryptionKeySize)
+}
+
+func isWithinDir(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
+// IsEncrypted checks if the file starts with the header of an encrypted backup.
+func IsEncrypted(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, fmt.Errorf("failed to open %q: %w", path, err)
+	}
+	defer f.Close()
+
+	magic := make([]byte, len(encryptionMagic))
+	if _, err := io.ReadFull(f, magic); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to read %q: %w", path, err)
+	}
+	return string(magic) == encryptionMagic, nil
+}
+
+// IsEncryptedBackupDir checks if the path is a directory holding an encrypted backup.
+func IsEncryptedBackupDir(path string) (bool, error) {
+	p := filepath.Join(path, EncryptedBackupFileName)
+	exists, err := pathExists(p)
+	if err != nil || !exists {
+		return false, err
+	}
+	return IsEncrypted(p)
+}
+
+// deriveKeys derives the key for the cipher and a value which is stored
+// in the header to tell apart wrong key from tampered data.
+func deriveKeys(key, salt []byte) ([]byte, []byte, error) {
+	if len(key) != EncryptionKeySize {
+		return nil, nil, fmt.Errorf("invalid encryption key size: %d, expected %d", len(key), EncryptionKeySize)
+	}
+	derived := make([]byte, EncryptionKeySize+encryptionKeyCheckLen)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, key, salt, []byte(encryptionHKDFInfo)), derived); err != nil {
+		return nil, nil, fmt.Errorf("failed to derive encryption key: %w", err)
+	}
+	return derived[:EncryptionKeySize], derived[EncryptionKeySize:], nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func segmentNonce(aead cipher.AEAD, counter uint64, final bool) []byte {
+	nonce := make([]byte, aead.NonceSize())
+	binary.BigEndian.PutUint64(nonce, counter)
+	if final {
+		nonce[len(nonce)-1] = 1
+	}
+	return nonce
+}
+
+type encryptingWriter struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	header  []byte
+	buf     []byte
+	counter uint64
+	closed  bool
+}
+
+// newEncryptingWriter returns a writer which encrypts everything written to it.
+// Close must be called to write the final segment; it does not close w.
+func newEncryptingWriter(w io.Writer, key []byte) (io.WriteCloser, error) {
+	salt := make([]byte, encryptionSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	encKey, keyCheck, err := deriveKeys(key, salt)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(encKey)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 0, encryptionHeaderSize)
+	header = append(header, encryptionMagic...)
+	header = append(header, salt...)
+	header = append(header, keyCheck...)
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+
+	return &encryptingWriter{
+		w:      w,
+		aead:   aead,
+		header: header,
+		buf:    make([]byte, 0, encryptionSegmentSize),
+	}, nil
+}
+
+func (e *encryptingWriter) Write(p []byte) (int, error) {
+	if e.closed {
+		return 0, fmt.Errorf("write to closed encrypting writer")
+	}
+	n := len(p)
+	for len(p) > 0 {
+		// Segment is sealed only when more data arrives,
+		// because the final segment must be marked as such.
+		if len(e.buf) == encryptionSegmentSize {
+			if err := e.seal(false); err != nil {
+				return n - len(p), err
+			}
+		}
+		c := min(len(p), encryptionSegmentSize-len(e.buf))
+		e.buf = append(e.buf, p[:c]...)
+		p = p[c:]
+	}
+	return n, nil
+}
+
+func (e *encryptingWriter) Close() error {
+	if e.closed {
+		return nil
+	}
+	e.closed = true
+	return e.seal(true)
+}
+
+func (e *encryptingWriter) seal(final bool) error {
+	out := e.aead.Seal(nil, segmentNonce(e.aead, e.counter, final), e.buf, e.header)
+	e.counter++
+	e.buf = e.buf[:0]
+	_, err := e.w.Write(out)
+	return err
+}
+
+type decryptingReader struct {
+	r       *bufio.Reader
+	aead    cipher.AEAD
+	header  []byte
+	in      []byte
+	out     []byte
+	counter uint64
+	done    bool
+}
+
+// newDecryptingReader returns a reader which decrypts and authenticates the data.
+// Read returns an error if the data was modified or truncated, or if the key is wrong.
+func newDecryptingReader(r io.Reader, key []byte) (io.Reader, error) {
+	header := make([]byte, encryptionHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("failed to read encryption header: %w", err)
+	}
+	if string(header[:len(encryptionMagic)]) != encryptionMagic {
+		return nil, fmt.Errorf("data is not an encrypted backup")
+	}
+
+	salt := header[len(encryptionMagic) : len(encryptionMagic)+encryptionSaltSize]
+	encKey, keyCheck, err := deriveKeys(key, salt)
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare(keyCheck, header[len(encryptionMagic)+encryptionSaltSize:]) != 1 {
+		return nil, fmt.Errorf("backup was encrypted with a different key")
+	}
+	aead, err := newAEAD(encKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &decryptingReader{
+		r:      bufio.NewReaderSize(r, encryptionSegmentSize+aead.Overhead()+1),
+		aead:   aead,
+		header: header,
+		in:     make([]byte, encryptionSegmentSize+aead.Overhead()),
+	}, nil
+}
+
+func (d *decryptingReader) Read(p []byte) (int, error) {
+	for len(d.out) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+		if err := d.open(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, d.out)
+	d.out = d.out[n:]
+	return n, nil
+}
+
+func (d *decryptingReader) open() error {
+	n, err := io.ReadFull(d.r, d.in)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	final := n < len(d.in)
+	if !final {
+		if _, err := d.r.Peek(1); errors.Is(err, io.EOF) {
+			final = true
+		} else if err != nil {
+			return err
+		}
+	}
+
+	out, err := d.aead.Open(nil, segmentNonce(d.aead, d.counter, final), d.in[:n], d.header)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt segment %d: data was modified or truncated", d.counter)
+	}
+	d.counter++
+	d.out = out
+	d.done = final
+	return nil
+}
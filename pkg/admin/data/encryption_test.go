@@ -0,0 +1,159 @@
+package data
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestKey(t *testing.T) []byte {
+	t.Helper()
+	key := make([]byte, EncryptionKeySize)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+	return key
+}
+
+func encrypt(t *testing.T, key, plaintext []byte) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	w, err := newEncryptingWriter(buf, key)
+	require.NoError(t, err)
+	_, err = w.Write(plaintext)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func decrypt(key, ciphertext []byte) ([]byte, error) {
+	r, err := newDecryptingReader(bytes.NewReader(ciphertext), key)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func Test_EncryptionRoundTrip(t *testing.T) {
+	key := newTestKey(t)
+
+	for _, size := range []int{0, 1, encryptionSegmentSize, 2*encryptionSegmentSize + 100} {
+		plaintext := make([]byte, size)
+		_, err := rand.Read(plaintext)
+		require.NoError(t, err)
+
+		decrypted, err := decrypt(key, encrypt(t, key, plaintext))
+		assert.NoError(t, err, "size %d", size)
+		assert.Equal(t, len(plaintext), len(decrypted), "size %d", size)
+		assert.True(t, bytes.Equal(plaintext, decrypted), "size %d", size)
+	}
+}
+
+func Test_EncryptionTampering(t *testing.T) {
+	key := newTestKey(t)
+	plaintext := bytes.Repeat([]byte("microshift"), encryptionSegmentSize/4)
+	ciphertext := encrypt(t, key, plaintext)
+	segment := encryptionSegmentSize + 16
+
+	testData := []struct {
+		name       string
+		ciphertext []byte
+		key        []byte
+	}{
+		{
+			name: "Modified byte",
+			ciphertext: func() []byte {
+				c := bytes.Clone(ciphertext)
+				c[encryptionHeaderSize+100] ^= 0x01
+				return c
+			}(),
+			key: key,
+		},
+		{
+			name: "Modified salt",
+			ciphertext: func() []byte {
+				c := bytes.Clone(ciphertext)
+				c[len(encryptionMagic)] ^= 0x01
+				return c
+			}(),
+			key: key,
+		},
+		{
+			name:       "Truncated to full segments",
+			ciphertext: ciphertext[:encryptionHeaderSize+2*segment],
+			key:        key,
+		},
+		{
+			name:       "Truncated in the middle of a segment",
+			ciphertext: ciphertext[:len(ciphertext)-10],
+			key:        key,
+		},
+		{
+			name: "Swapped segments",
+			ciphertext: func() []byte {
+				c := bytes.Clone(ciphertext)
+				first := bytes.Clone(c[encryptionHeaderSize : encryptionHeaderSize+segment])
+				copy(c[encryptionHeaderSize:], c[encryptionHeaderSize+segment:encryptionHeaderSize+2*segment])
+				copy(c[encryptionHeaderSize+segment:], first)
+				return c
+			}(),
+			key: key,
+		},
+		{
+			name:       "Wrong key",
+			ciphertext: ciphertext,
+			key:        newTestKey(t),
+		},
+	}
+
+	for _, td := range testData {
+		t.Run(td.name, func(t *testing.T) {
+			_, err := decrypt(td.key, td.ciphertext)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func Test_EncryptedArchiveRoundTrip(t *testing.T) {
+	key := newTestKey(t)
+	src := createTestDataDir(t)
+	archive := filepath.Join(t.TempDir(), "backup.tar.gz.enc")
+
+	manifest, err := createManifest(src)
+	require.NoError(t, err)
+	require.NoError(t, writeArchive(src, archive, manifest, key))
+
+	encrypted, err := IsEncrypted(archive)
+	require.NoError(t, err)
+	assert.True(t, encrypted)
+
+	_, err = ReadArchiveManifest(archive, nil)
+	assert.ErrorIs(t, err, ErrBackupEncrypted)
+
+	dest := filepath.Join(t.TempDir(), "restored")
+	require.NoError(t, ExtractArchive(archive, dest, key))
+	contents, err := os.ReadFile(filepath.Join(dest, "certs", "ca.crt"))
+	require.NoError(t, err)
+	assert.Equal(t, "certificate", string(contents))
+}
+
+func Test_parseEncryptionKey(t *testing.T) {
+	key := bytes.Repeat([]byte{0xAB}, EncryptionKeySize)
+
+	parsed, err := parseEncryptionKey(key)
+	assert.NoError(t, err)
+	assert.Equal(t, key, parsed)
+
+	parsed, err = parseEncryptionKey([]byte(base64.StdEncoding.EncodeToString(key) + "\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, key, parsed)
+
+	_, err = parseEncryptionKey([]byte("too short"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,294 @@
+package data
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// Hooks are executables provided by the administrator that the Manager runs
+// before and after creating or restoring a backup, for example to make
+// applications flush their data before the backup, or to repair them after
+// the restore. Hooks of a stage live in a subdirectory of the hooks directory
+// named after the stage and they run one by one in the lexical order of their names.
+//
+// Failure of a pre-backup or pre-restore hook aborts the operation and
+// the remaining hooks of the stage are not run. Failures of post-backup and
+// post-restore hooks are only logged, because the operation is already done.
+
+const (
+	// DefaultHookTimeout is the time each hook is given to finish before it's killed.
+	DefaultHookTimeout = 5 * time.Minute
+
+	// HooksRecordName is the name of the file inside backup directories
+	// recording the results of the hooks run when the backup was created.
+	// Archives record the results in their manifest instead.
+	HooksRecordName = "hooks.json"
+
+	// Environment variables passed to the hooks.
+	HookEnvBackupPath = "MICROSHIFT_BACKUP_PATH"
+	HookEnvBackupName = "MICROSHIFT_BACKUP_NAME"
+	HookEnvStage      = "MICROSHIFT_HOOK_STAGE"
+
+	// maxHookOutputSize limits how much of the hook's output is recorded.
+	maxHookOutputSize = 64 * 1024
+	// hookWaitDelay is the time given to the hook's output to be closed
+	// after the hook exits or is killed, e.g. by a process it left behind.
+	hookWaitDelay = 10 * time.Second
+)
+
+type HookStage string
+
+const (
+	HookStagePreBackup   HookStage = "pre-backup"
+	HookStagePostBackup  HookStage = "post-backup"
+	HookStagePreRestore  HookStage = "pre-restore"
+	HookStagePostRestore HookStage = "post-restore"
+)
+
+// HookResult describes a single run of a hook.
+type HookResult struct {
+	Stage     HookStage `json:"stage"`
+	Hook      string    `json:"hook"`
+	StartTime time.Time `json:"startTime"`
+	Duration  string    `json:"duration"`
+	// ExitCode is -1 if the hook couldn't be started or was killed.
+	ExitCode int `json:"exitCode"`
+	// Output is the combined stdout and stderr of the hook,
+	// truncated to its last 64 KiB.
+	Output string `json:"output,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// HooksRecord is the contents of the HooksRecordName file.
+type HooksRecord struct {
+	Hooks []HookResult `json:"hooks"`
+}
+
+// WithHooks makes the Manager run the hooks from the dir, each with given timeout,
+// around creating and restoring backups. Empty dir disables the hooks.
+func WithHooks(dir string, timeout time.Duration) ManagerOption {
+	return func(dm *manager) {
+		dm.hooksDir = dir
+		dm.hookTimeout = timeout
+	}
+}
+
+// withBackupHooks runs the pre-backup hooks, then the create func which is given
+// their results, and then the post-backup hooks. Results of all the hooks
+// are recorded inside the backup if it's a directory.
+func (dm *manager) withBackupHooks(name BackupName, create func(pre []HookResult) (string, error)) (string, error) {
+	if dm.hooksDir == "" {
+		return create(nil)
+	}
+
+	pre, err := dm.runHooks(HookStagePreBackup, name)
+	if err != nil {
+		return "", fmt.Errorf("backup aborted: %w", err)
+	}
+
+	dest, err := create(pre)
+	if err != nil {
+		return "", err
+	}
+
+	post, err := dm.runHooks(HookStagePostBackup, name)
+	if err != nil {
+		klog.ErrorS(err, "Post-backup hooks failed", "backup", dest)
+	}
+
+	if isArchive, err := IsArchive(dest); err != nil {
+		klog.ErrorS(err, "Failed to record results of the hooks", "backup", dest)
+	} else if !isArchive && len(pre)+len(post) != 0 {
+		if err := writeHooksRecord(dest, append(pre, post...)); err != nil {
+			klog.ErrorS(err, "Failed to record results of the hooks", "backup", dest)
+		}
+	}
+	return dest, nil
+}
+
+// RunWithRestoreHooks runs the pre-restore hooks, then the restore func,
+// and then the post-restore hooks. It's used by restores not performed by
+// the Manager, like the auto-recovery's.
+func (dm *manager) RunWithRestoreHooks(name BackupName, restore func() error) error {
+	if dm.hooksDir == "" {
+		return restore()
+	}
+
+	if _, err := dm.runHooks(HookStagePreRestore, name); err != nil {
+		return fmt.Errorf("restore aborted: %w", err)
+	}
+
+	if err := restore(); err != nil {
+		return err
+	}
+
+	if _, err := dm.runHooks(HookStagePostRestore, name); err != nil {
+		klog.ErrorS(err, "Post-restore hooks failed", "name", name)
+	}
+	return nil
+}
+
+// runHooks runs all the hooks of the stage. Pre-stage hooks stop on the first failure.
+func (dm *manager) runHooks(stage HookStage, name BackupName) ([]HookResult, error) {
+	hooks, err := listHooks(filepath.Join(dm.hooksDir, string(stage)))
+	if err != nil {
+		return nil, err
+	}
+	if len(hooks) == 0 {
+		return nil, nil
+	}
+
+	klog.InfoS("Running hooks", "stage", stage, "hooks", len(hooks))
+	env := []string{
+		fmt.Sprintf("%s=%s", HookEnvBackupPath, dm.GetBackupPath(name)),
+		fmt.Sprintf("%s=%s", HookEnvBackupName, name),
+		fmt.Sprintf("%s=%s", HookEnvStage, stage),
+	}
+	isPre := stage == HookStagePreBackup || stage == HookStagePreRestore
+
+	results := make([]HookResult, 0, len(hooks))
+	var errs []error
+	for _, hook := range hooks {
+		result := runHook(hook, env, dm.hookTimeout)
+		result.Stage = stage
+		results = append(results, result)
+		if result.Error == "" {
+			continue
+		}
+		errs = append(errs, fmt.Errorf("%s hook %q failed: %s", stage, hook, result.Error))
+		if isPre {
+			break
+		}
+	}
+	return results, errors.Join(errs...)
+}
+
+// listHooks returns paths of the executable files of the dir sorted by name.
+// Missing dir means there are no hooks.
+func listHooks(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read hooks directory %q: %w", dir, err)
+	}
+
+	hooks := []string{}
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		// Stat follows symlinks, so hooks can be linked from elsewhere.
+		fi, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat hook %q: %w", path, err)
+		}
+		if !fi.Mode().IsRegular() || fi.Mode().Perm()&0111 == 0 {
+			klog.InfoS("Skipping hook which is not an executable file", "path", path, "mode", fi.Mode())
+			continue
+		}
+		hooks = append(hooks, path)
+	}
+	sort.Strings(hooks)
+	return hooks, nil
+}
+
+func runHook(path string, env []string, timeout time.Duration) HookResult {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	output := &bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, path)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout = output
+	cmd.Stderr = output
+	cmd.WaitDelay = hookWaitDelay
+
+	klog.InfoS("Running hook", "path", path)
+	start := time.Now()
+	err := cmd.Run()
+	duration := time.Since(start)
+
+	result := HookResult{
+		Hook:      filepath.Base(path),
+		StartTime: start.UTC(),
+		Duration:  duration.Round(time.Millisecond).String(),
+		ExitCode:  -1,
+		Output:    truncateHookOutput(output.Bytes()),
+	}
+	if cmd.ProcessState != nil {
+		result.ExitCode = cmd.ProcessState.ExitCode()
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("timed out after %s", timeout)
+	}
+	if err != nil {
+		result.Error = err.Error()
+		klog.ErrorS(err, "Hook failed", "path", path, "exitCode", result.ExitCode,
+			"duration", result.Duration, "output", result.Output)
+	} else {
+		klog.InfoS("Hook finished", "path", path, "duration", result.Duration, "output", result.Output)
+	}
+	return result
+}
+
+// truncateHookOutput keeps the end of the output which usually explains the failure.
+func truncateHookOutput(output []byte) string {
+	if len(output) > maxHookOutputSize {
+		output = output[len(output)-maxHookOutputSize:]
+	}
+	return string(output)
+}
+
+func writeHooksRecord(dir string, results []HookResult) error {
+	contents, err := json.MarshalIndent(HooksRecord{Hooks: results}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal hooks record: %w", err)
+	}
+	path := filepath.Join(dir, HooksRecordName)
+	if err := os.WriteFile(path, contents, 0600); err != nil {
+		return fmt.Errorf("failed to write %q: %w", path, err)
+	}
+	return nil
+}
+
+// ReadHooksRecord reads the results of the hooks recorded inside the backup directory.
+// It returns nil if the backup doesn't have the record.
+func ReadHooksRecord(dir string) ([]HookResult, error) {
+	path := filepath.Join(dir, HooksRecordName)
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read %q: %w", path, err)
+	}
+	record := HooksRecord{}
+	if err := json.Unmarshal(contents, &record); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %q: %w", path, err)
+	}
+	return record.Hooks, nil
+}
+
+// RemoveHooksRecord removes the record of the hooks from the data directory
+// restored from a backup directory, because it's not part of the data.
+func RemoveHooksRecord(dataDir string) error {
+	path := filepath.Join(dataDir, HooksRecordName)
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove %q: %w", path, err)
+	}
+	return nil
+}
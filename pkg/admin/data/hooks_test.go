@@ -0,0 +1,112 @@
+package data
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeHook(t *testing.T, hooksDir string, stage HookStage, name, script string) {
+	t.Helper()
+	dir := filepath.Join(hooksDir, string(stage))
+	require.NoError(t, os.MkdirAll(dir, 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script), 0700))
+}
+
+func Test_BackupHooks(t *testing.T) {
+	hooksDir := t.TempDir()
+	storage := StoragePath(t.TempDir())
+	writeHook(t, hooksDir, HookStagePreBackup, "20-second", `echo "second"`)
+	writeHook(t, hooksDir, HookStagePreBackup, "10-first", `echo "$MICROSHIFT_HOOK_STAGE $MICROSHIFT_BACKUP_NAME $MICROSHIFT_BACKUP_PATH"`)
+	writeHook(t, hooksDir, HookStagePostBackup, "10-fails", `echo "oops" >&2; exit 3`)
+	writeHook(t, hooksDir, HookStagePostBackup, "20-runs-anyway", `echo "done"`)
+	// Files that are not executable are not hooks.
+	require.NoError(t, os.WriteFile(filepath.Join(hooksDir, string(HookStagePreBackup), "README"), []byte("x"), 0600))
+
+	dm, err := NewManager(storage, WithHooks(hooksDir, 5*time.Second))
+	require.NoError(t, err)
+
+	var pre []HookResult
+	dest, err := dm.withBackupHooks("backup", func(results []HookResult) (string, error) {
+		pre = results
+		dest := dm.GetBackupPath("backup")
+		return dest, os.MkdirAll(dest, 0700)
+	})
+	require.NoError(t, err)
+
+	require.Len(t, pre, 2)
+	assert.Equal(t, "10-first", pre[0].Hook)
+	assert.Equal(t, "pre-backup backup "+dest+"\n", pre[0].Output)
+	assert.Equal(t, "20-second", pre[1].Hook)
+
+	recorded, err := ReadHooksRecord(dest)
+	require.NoError(t, err)
+	require.Len(t, recorded, 4)
+	assert.Equal(t, HookStagePostBackup, recorded[2].Stage)
+	assert.Equal(t, 3, recorded[2].ExitCode)
+	assert.Equal(t, "oops\n", recorded[2].Output)
+	assert.NotEmpty(t, recorded[2].Error)
+	assert.Equal(t, "done\n", recorded[3].Output)
+	assert.Empty(t, recorded[3].Error)
+}
+
+func Test_BackupHooks_PreHookFailureAborts(t *testing.T) {
+	hooksDir := t.TempDir()
+	writeHook(t, hooksDir, HookStagePreBackup, "10-fails", "exit 1")
+	writeHook(t, hooksDir, HookStagePreBackup, "20-not-run", "touch "+filepath.Join(hooksDir, "ran"))
+
+	dm, err := NewManager(StoragePath(t.TempDir()), WithHooks(hooksDir, 5*time.Second))
+	require.NoError(t, err)
+
+	_, err = dm.withBackupHooks("backup", func([]HookResult) (string, error) {
+		return "", errors.New("backup must not be created")
+	})
+	assert.ErrorContains(t, err, "backup aborted")
+	assert.NoFileExists(t, filepath.Join(hooksDir, "ran"))
+}
+
+func Test_RestoreHooks(t *testing.T) {
+	hooksDir := t.TempDir()
+	writeHook(t, hooksDir, HookStagePreRestore, "10-slow", "exec sleep 10")
+
+	dm, err := NewManager(StoragePath(t.TempDir()), WithHooks(hooksDir, 100*time.Millisecond))
+	require.NoError(t, err)
+
+	restored := false
+	err = dm.RunWithRestoreHooks("backup", func() error {
+		restored = true
+		return nil
+	})
+	assert.ErrorContains(t, err, "timed out")
+	assert.False(t, restored)
+
+	// Without the hooks, only the operation is performed.
+	dm, err = NewManager(StoragePath(t.TempDir()))
+	require.NoError(t, err)
+	assert.NoError(t, dm.RunWithRestoreHooks("backup", func() error {
+		restored = true
+		return nil
+	}))
+	assert.True(t, restored)
+}
+
+func Test_ArchiveOfBackupWithHooksRecord(t *testing.T) {
+	src := createTestDataDir(t)
+	hooks := []HookResult{{Stage: HookStagePreBackup, Hook: "10-flush", Output: "flushed"}}
+	require.NoError(t, writeHooksRecord(src, hooks))
+
+	manifest, err := createManifest(src)
+	require.NoError(t, err)
+	assert.Len(t, manifest.Files, 5)
+
+	archive := filepath.Join(t.TempDir(), "backup.tar.gz")
+	require.NoError(t, writeArchive(src, archive, manifest, nil))
+	dest := filepath.Join(t.TempDir(), "restored")
+	require.NoError(t, ExtractArchive(archive, dest, nil))
+	assert.NoFileExists(t, filepath.Join(dest, HooksRecordName))
+}
@@ -0,0 +1,37 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// VersionFileContents holds contents of the MicroShift data's version file.
+// Unlike the version file handling in the prerun, the version is kept as
+// a string so the metadata of the backups can be read without validating it.
+type VersionFileContents struct {
+	Version      string `json:"version"`
+	DeploymentID string `json:"deployment_id,omitempty"`
+	BootID       string `json:"boot_id"`
+}
+
+// ReadVersionFile reads the version file. It supports both the current
+// JSON schema and the older one which contained only the version string.
+func ReadVersionFile(path string) (VersionFileContents, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return VersionFileContents{}, fmt.Errorf("failed to read version file %q: %w", path, err)
+	}
+
+	vf := VersionFileContents{}
+	if err := json.Unmarshal(contents, &vf); err == nil {
+		return vf, nil
+	}
+
+	ver := strings.TrimSpace(string(contents))
+	if ver == "" {
+		return VersionFileContents{}, fmt.Errorf("version file %q is empty", path)
+	}
+	return VersionFileContents{Version: ver}, nil
+}
@@ -0,0 +1,260 @@
+package data
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/openshift/microshift/pkg/config"
+	"github.com/openshift/microshift/pkg/util"
+	"k8s.io/klog/v2"
+)
+
+const (
+	onlineBackupTimeout = 10 * time.Minute
+)
+
+var (
+	// onlineBackupContent lists data dir paths copied verbatim into an online
+	// backup. etcd is not on the list because it's backed up using a snapshot.
+	onlineBackupContent = []string{
+		".nodename",
+		config.ImmutableFieldsFileName,
+		"certs",
+		"kubelet-plugins",
+		"resources",
+		"version",
+	}
+
+	// EtcdSnapshotDBPath is a path, relative to the backup's root, where
+	// the etcd snapshot is stored. It is the same location etcd uses for its
+	// database so the backup can be restored like any other backup.
+	// Absence of the WAL next to the database informs microshift-etcd
+	// that the database needs to be prepared for use as a new cluster.
+	EtcdSnapshotDBPath = filepath.Join("etcd", "member", "snap", "db")
+)
+
+// BackupOnline creates a backup of MicroShift data without stopping the MicroShift.
+// Instead of copying etcd's directory, a consistent snapshot of the database is
+// obtained using etcd client. Rest of the data is copied as usual.
+func (dm *manager) BackupOnline(name BackupName) (string, error) {
+	return dm.withBackupHooks(name, func([]HookResult) (string, error) {
+		return dm.backupOnline(name)
+	})
+}
+
+func (dm *manager) backupOnline(name BackupName) (string, error) {
+	klog.InfoS("Creating online backup",
+		"storage", dm.storage,
+		"name", name,
+		"data", dm.dataDir,
+	)
+
+	if err := dm.prepareBackupDestination(name); err != nil {
+		return "", err
+	}
+
+	dest := dm.GetBackupPath(name)
+	intermediate, err := GenerateUniqueTempPath(dest)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(intermediate, 0700); err != nil {
+		return "", fmt.Errorf("failed to create intermediate backup directory %q: %w", intermediate, err)
+	}
+
+	if err := createOnlineBackup(dm.dataDir, intermediate); err != nil {
+		if rmErr := os.RemoveAll(intermediate); rmErr != nil {
+			return "", errors.Join(err, fmt.Errorf("failed to remove %q: %w", intermediate, rmErr))
+		}
+		return "", err
+	}
+
+	if dm.encryptionKey != nil {
+		// Intermediate directory is encrypted into another intermediate destination.
+		encrypted := AtomicDirCopy{Source: intermediate, Destination: dest, EncryptionKey: dm.encryptionKey}
+		err := encrypted.CopyToIntermediate()
+		if err == nil {
+			err = encrypted.RenameToFinal()
+		}
+		if rmErr := os.RemoveAll(intermediate); rmErr != nil {
+			klog.ErrorS(rmErr, "Failed to remove intermediate online backup", "path", intermediate)
+		}
+		if err != nil {
+			return "", err
+		}
+	} else {
+		// Intermediate directory is already complete, it only needs to be renamed.
+		final := AtomicDirCopy{Source: intermediate, Destination: dest}
+		if err := final.RenameToFinal(); err != nil {
+			return "", err
+		}
+	}
+
+	klog.InfoS("Created online backup", "backup", dest, "data", dm.dataDir)
+	return dest, nil
+}
+
+// BackupOnlineArchive creates an archive backup without stopping the MicroShift.
+// Online backup needs to be created first to obtain a snapshot of etcd.
+// Then it's used as a source for the archive and removed afterwards.
+// The intermediate backup is not encrypted, only the final archive is.
+func (dm *manager) BackupOnlineArchive(name BackupName) (string, error) {
+	return dm.withBackupHooks(name, func(pre []HookResult) (string, error) {
+		tmpPath, err := GenerateUniqueTempPath(dm.GetBackupPath(name))
+		if err != nil {
+			return "", err
+		}
+		tmpName := BackupName(filepath.Base(tmpPath))
+		plain := &manager{storage: dm.storage, dataDir: dm.dataDir}
+		if _, err := plain.backupOnline(tmpName); err != nil {
+			return "", err
+		}
+		defer func() {
+			if err := plain.RemoveBackup(tmpName); err != nil {
+				klog.ErrorS(err, "Failed to remove intermediate online backup", "path", tmpPath)
+			}
+		}()
+		return dm.backupArchive(tmpPath, name, pre)
+	})
+}
+
+func createOnlineBackup(dataDir, dest string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), onlineBackupTimeout)
+	defer cancel()
+
+	// Snapshot etcd first: it's the part most prone to failure and
+	// there is no point in copying rest of the data if it fails.
+	if err := saveEtcdSnapshot(ctx, dataDir, filepath.Join(dest, EtcdSnapshotDBPath)); err != nil {
+		return err
+	}
+
+	for _, p := range onlineBackupContent {
+		src := filepath.Join(dataDir, p)
+		exists, err := pathExists(src)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			if p == "kubelet-plugins" {
+				// Directory is expected to exist in the backup, even if empty.
+				if err := os.MkdirAll(filepath.Join(dest, p), 0700); err != nil {
+					return fmt.Errorf("failed to create %q: %w", p, err)
+				}
+			}
+			klog.InfoS("Path does not exist - skipping", "path", src)
+			continue
+		}
+		if err := copyIntoDir(src, dest); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func copyIntoDir(src, destDir string) error {
+	cmd := exec.Command("cp", append(cpArgs, src, destDir)...) //nolint:gosec
+	var outb, errb bytes.Buffer
+	cmd.Stdout = &outb
+	cmd.Stderr = &errb
+	if err := cmd.Run(); err != nil {
+		klog.ErrorS(nil, "Failed to copy", "cmd", cmd,
+			"stdout", strings.ReplaceAll(outb.String(), "\n", `, `),
+			"stderr", errb.String())
+		return fmt.Errorf("failed to copy %q to %q: %w", src, destDir, err)
+	}
+	klog.InfoS("Copied path", "cmd", cmd)
+	return nil
+}
+
+// saveEtcdSnapshot streams etcd's snapshot into a file and verifies
+// the SHA-256 checksum which etcd appends to the snapshot.
+func saveEtcdSnapshot(ctx context.Context, dataDir, dbPath string) error {
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0700); err != nil {
+		return fmt.Errorf("failed to create directory for etcd snapshot: %w", err)
+	}
+
+	client, err := util.GetEtcdClient(ctx, dataDir)
+	if err != nil {
+		return fmt.Errorf("failed to obtain etcd client: %w", err)
+	}
+	defer client.Close()
+
+	klog.InfoS("Requesting etcd snapshot")
+	rc, err := client.Snapshot(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to request etcd snapshot: %w", err)
+	}
+	defer rc.Close()
+
+	partPath := dbPath + ".part"
+	f, err := os.OpenFile(partPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create %q: %w", partPath, err)
+	}
+	size, err := io.Copy(f, rc)
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to receive etcd snapshot: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to sync %q: %w", partPath, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close %q: %w", partPath, err)
+	}
+	klog.InfoS("Received etcd snapshot", "path", partPath, "sizeBytes", size)
+
+	if err := verifyAndTrimSnapshotChecksum(partPath, size); err != nil {
+		return err
+	}
+
+	if err := os.Rename(partPath, dbPath); err != nil {
+		return fmt.Errorf("failed to rename %q to %q: %w", partPath, dbPath, err)
+	}
+	return nil
+}
+
+// verifyAndTrimSnapshotChecksum checks the SHA-256 hash etcd appends at the end
+// of the snapshot stream and removes it so the file is a plain bbolt database.
+func verifyAndTrimSnapshotChecksum(path string, size int64) error {
+	// bbolt database size is always a multiple of a page size (512 at minimum),
+	// so the remainder is the checksum appended by etcd.
+	if size%512 != sha256.Size {
+		return fmt.Errorf("etcd snapshot %q has unexpected size %d: missing checksum", path, size)
+	}
+	dbSize := size - sha256.Size
+
+	f, err := os.OpenFile(path, os.O_RDWR, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open %q: %w", path, err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.CopyN(h, f, dbSize); err != nil {
+		return fmt.Errorf("failed to compute checksum of %q: %w", path, err)
+	}
+	expected := make([]byte, sha256.Size)
+	if _, err := io.ReadFull(f, expected); err != nil {
+		return fmt.Errorf("failed to read checksum of %q: %w", path, err)
+	}
+	if actual := h.Sum(nil); !bytes.Equal(actual, expected) {
+		return fmt.Errorf("etcd snapshot %q is corrupted: expected sha256 %x, got %x", path, expected, actual)
+	}
+
+	if err := f.Truncate(dbSize); err != nil {
+		return fmt.Errorf("failed to remove checksum from %q: %w", path, err)
+	}
+	klog.InfoS("Verified etcd snapshot checksum", "path", path)
+	return f.Sync()
+}
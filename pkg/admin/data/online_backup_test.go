@@ -0,0 +1,56 @@
+package data
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_verifyAndTrimSnapshotChecksum(t *testing.T) {
+	db := bytes.Repeat([]byte{0xAB}, 4096)
+	sum := sha256.Sum256(db)
+
+	testData := []struct {
+		name    string
+		content []byte
+		isValid bool
+	}{
+		{
+			name:    "Valid snapshot",
+			content: append(bytes.Clone(db), sum[:]...),
+			isValid: true,
+		},
+		{
+			name:    "Missing checksum",
+			content: db,
+			isValid: false,
+		},
+		{
+			name:    "Corrupted database",
+			content: append(append(bytes.Clone(db[:4095]), 0x00), sum[:]...),
+			isValid: false,
+		},
+	}
+
+	for _, td := range testData {
+		t.Run(td.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "db")
+			assert.NoError(t, os.WriteFile(path, td.content, 0600))
+
+			err := verifyAndTrimSnapshotChecksum(path, int64(len(td.content)))
+			if !td.isValid {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			trimmed, err := os.ReadFile(path)
+			assert.NoError(t, err)
+			assert.Equal(t, db, trimmed)
+		})
+	}
+}
@@ -0,0 +1,303 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"k8s.io/klog/v2"
+)
+
+// Component is a top level directory of the data directory
+// which can be restored separately from the rest of the data.
+type Component string
+
+const (
+	ComponentCerts          Component = "certs"
+	ComponentEtcd           Component = "etcd"
+	ComponentResources      Component = "resources"
+	ComponentKubeletPlugins Component = "kubelet-plugins"
+)
+
+var (
+	// Components lists all components that can be restored separately.
+	Components = []Component{ComponentCerts, ComponentEtcd, ComponentResources, ComponentKubeletPlugins}
+
+	// componentsRestoredTogether lists components which must not be restored
+	// without each other, because doing so would break the trust chain.
+	componentsRestoredTogether = []struct {
+		components []Component
+		reason     string
+	}{
+		{
+			components: []Component{ComponentCerts, ComponentResources},
+			reason: "kubeconfigs in resources embed the certificate authorities " +
+				"and client certificates from certs",
+		},
+	}
+)
+
+// ParseComponents parses names of the components and verifies that
+// restoring them doesn't break the trust chain between certificates and kubeconfigs.
+func ParseComponents(names []string) ([]Component, error) {
+	if len(names) == 0 {
+		return nil, fmt.Errorf("at least one component must be specified")
+	}
+
+	components := []Component{}
+	for _, name := range names {
+		c := Component(strings.TrimSpace(name))
+		if !slices.Contains(Components, c) {
+			return nil, fmt.Errorf("unknown component %q, supported components: %v", name, Components)
+		}
+		if !slices.Contains(components, c) {
+			components = append(components, c)
+		}
+	}
+
+	if err := validateComponents(components); err != nil {
+		return nil, err
+	}
+	return components, nil
+}
+
+func validateComponents(components []Component) error {
+	for _, group := range componentsRestoredTogether {
+		included := []Component{}
+		missing := []Component{}
+		for _, c := range group.components {
+			if slices.Contains(components, c) {
+				included = append(included, c)
+			} else {
+				missing = append(missing, c)
+			}
+		}
+		if len(included) != 0 && len(missing) != 0 {
+			return fmt.Errorf("restoring %v without %v would break the trust chain (%s): restore them together",
+				included, missing, group.reason)
+		}
+	}
+	return nil
+}
+
+// RestoreComponents restores only the given components of the backup
+// keeping the rest of the data directory intact. Each component is first copied
+// next to the data directory and then renamed into its place. Components are
+// swapped only after all of them were copied, and if renaming any of them fails,
+// the ones already swapped are rolled back.
+func (dm *manager) RestoreComponents(name BackupName, components []Component) error {
+	return dm.RunWithRestoreHooks(name, func() error {
+		return dm.restoreComponents(name, components)
+	})
+}
+
+func (dm *manager) restoreComponents(name BackupName, components []Component) error {
+	klog.InfoS("Restoring components of the backup",
+		"storage", dm.storage,
+		"name", name,
+		"components", components,
+		"data", dm.dataDir,
+	)
+
+	if name == "" {
+		return &EmptyArgErr{"name"}
+	}
+	if err := validateComponents(components); err != nil {
+		return err
+	}
+
+	src, cleanup, err := dm.prepareBackupForPartialRestore(dm.GetBackupPath(name))
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	if err := restoreComponentsFrom(src, dm.dataDir, components); err != nil {
+		return err
+	}
+
+	klog.InfoS("Restored components of the backup",
+		"name", name,
+		"components", components,
+		"data", dm.dataDir,
+	)
+	return nil
+}
+
+// restoreComponentsFrom replaces the components of the dataDir
+// with the components of the src directory.
+func restoreComponentsFrom(src, dataDir string, components []Component) error {
+	if err := checkPartialRestoreCompatibility(src, dataDir); err != nil {
+		return err
+	}
+
+	var size uint64
+	for _, c := range components {
+		s, err := GetSizeOfDir(filepath.Join(src, string(c)))
+		if err != nil {
+			return err
+		}
+		size += s
+	}
+	if err := checkIfEnoughSpaceToRestoreSize(size, dataDir); err != nil {
+		return err
+	}
+
+	copiers := make([]*AtomicDirCopy, 0, len(components))
+	rollbackIntermediates := func() {
+		for _, c := range copiers {
+			if err := c.RollbackIntermediate(); err != nil {
+				klog.ErrorS(err, "Failed to remove intermediate copy", "component", c.Destination)
+			}
+		}
+	}
+	for _, c := range components {
+		copier := &AtomicDirCopy{
+			Source:      filepath.Join(src, string(c)),
+			Destination: filepath.Join(dataDir, string(c)),
+		}
+		if err := copier.CopyToIntermediate(); err != nil {
+			rollbackIntermediates()
+			return fmt.Errorf("failed to copy %q component: %w", c, err)
+		}
+		copiers = append(copiers, copier)
+	}
+
+	if err := swapComponents(copiers); err != nil {
+		rollbackIntermediates()
+		return err
+	}
+	return nil
+}
+
+// prepareBackupForPartialRestore returns a directory with the backup's data.
+// Archives and encrypted backups are extracted next to the data directory.
+func (dm *manager) prepareBackupForPartialRestore(path string) (string, func(), error) {
+	noop := func() {}
+
+	if exists, err := pathExists(path); err != nil {
+		return "", noop, err
+	} else if !exists {
+		return "", noop, fmt.Errorf("failed to restore backup, %q does not exist", path)
+	}
+
+	archive := ""
+	if isArchive, err := IsArchive(path); err != nil {
+		return "", noop, err
+	} else if isArchive {
+		archive = path
+	} else if isEncrypted, err := IsEncryptedBackupDir(path); err != nil {
+		return "", noop, err
+	} else if isEncrypted {
+		archive = filepath.Join(path, EncryptedBackupFileName)
+	}
+
+	if archive == "" {
+		if err := IsMicroShiftBackup(path); err != nil {
+			return "", noop, fmt.Errorf("%q is not a valid MicroShift backup: %w", path, err)
+		}
+		return path, noop, nil
+	}
+
+	manifest, err := ReadArchiveManifest(archive, dm.encryptionKey)
+	if err != nil {
+		return "", noop, fmt.Errorf("%q is not a valid MicroShift backup: %w", path, err)
+	}
+	if err := checkIfEnoughSpaceToRestoreManifest(manifest, dm.dataDir); err != nil {
+		return "", noop, err
+	}
+
+	extracted, err := GenerateUniqueTempPath(dm.dataDir)
+	if err != nil {
+		return "", noop, err
+	}
+	cleanup := func() {
+		if err := os.RemoveAll(extracted); err != nil {
+			klog.ErrorS(err, "Failed to remove extracted backup", "path", extracted)
+		}
+	}
+	if err := ExtractArchive(archive, extracted, dm.encryptionKey); err != nil {
+		cleanup()
+		return "", noop, fmt.Errorf("failed to extract %q: %w", archive, err)
+	}
+	if err := IsMicroShiftBackup(extracted); err != nil {
+		cleanup()
+		return "", noop, fmt.Errorf("%q is not a valid MicroShift backup: %w", path, err)
+	}
+	return extracted, cleanup, nil
+}
+
+// checkPartialRestoreCompatibility verifies that the backup was created by
+// the same version of MicroShift as the existing data. Version file is not
+// restored, so components from different version would be mixed with
+// the rest of the data without going through the upgrade.
+func checkPartialRestoreCompatibility(src, dataDir string) error {
+	backupVersion, err := ReadVersionFile(filepath.Join(src, "version"))
+	if err != nil {
+		return err
+	}
+	dataVersion, err := ReadVersionFile(filepath.Join(dataDir, "version"))
+	if err != nil {
+		return err
+	}
+	if backupVersion.Version != dataVersion.Version {
+		return fmt.Errorf("partial restore requires the backup of the same version as the data: backup=%s data=%s",
+			backupVersion.Version, dataVersion.Version)
+	}
+	return nil
+}
+
+// swapComponents moves existing components aside and renames intermediate copies
+// into their places. If any of the renames fails, already swapped components
+// are restored from the saved copies.
+func swapComponents(copiers []*AtomicDirCopy) error {
+	saved := map[string]string{}
+	swapped := []*AtomicDirCopy{}
+
+	rollback := func(cause error) error {
+		errs := []error{cause}
+		for _, c := range swapped {
+			if err := os.RemoveAll(c.Destination); err != nil {
+				errs = append(errs, fmt.Errorf("failed to remove %q: %w", c.Destination, err))
+			}
+		}
+		for dest, tmp := range saved {
+			if err := os.Rename(tmp, dest); err != nil {
+				errs = append(errs, fmt.Errorf("failed to rename %q back to %q: %w", tmp, dest, err))
+			}
+		}
+		return errors.Join(errs...)
+	}
+
+	for _, c := range copiers {
+		if exists, err := pathExists(c.Destination); err != nil {
+			return rollback(err)
+		} else if exists {
+			tmp := fmt.Sprintf("%s.saved", c.Destination)
+			if err := os.RemoveAll(tmp); err != nil {
+				return rollback(fmt.Errorf("failed to remove %q: %w", tmp, err))
+			}
+			klog.InfoS("Renaming existing component", "path", c.Destination, "renamedTo", tmp)
+			if err := os.Rename(c.Destination, tmp); err != nil {
+				return rollback(fmt.Errorf("failed to rename %q to %q: %w", c.Destination, tmp, err))
+			}
+			saved[c.Destination] = tmp
+		}
+
+		if err := c.RenameToFinal(); err != nil {
+			return rollback(err)
+		}
+		swapped = append(swapped, c)
+	}
+
+	for _, tmp := range saved {
+		klog.InfoS("Removing saved component", "path", tmp)
+		if err := os.RemoveAll(tmp); err != nil {
+			klog.ErrorS(err, "Failed to remove saved component, leaving in place", "path", tmp)
+		}
+	}
+	return nil
+}
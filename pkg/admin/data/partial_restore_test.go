@@ -0,0 +1,62 @@
+package data
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ParseComponents(t *testing.T) {
+	testData := []struct {
+		name     string
+		input    []string
+		expected []Component
+		isValid  bool
+	}{
+		{name: "etcd only", input: []string{"etcd"}, expected: []Component{ComponentEtcd}, isValid: true},
+		{name: "certs with resources", input: []string{"certs", "resources"}, expected: []Component{ComponentCerts, ComponentResources}, isValid: true},
+		{name: "duplicates are ignored", input: []string{"etcd", "etcd"}, expected: []Component{ComponentEtcd}, isValid: true},
+		{name: "certs without resources", input: []string{"certs"}, isValid: false},
+		{name: "resources without certs", input: []string{"resources", "kubelet-plugins"}, isValid: false},
+		{name: "unknown component", input: []string{"version"}, isValid: false},
+		{name: "empty", input: []string{}, isValid: false},
+	}
+
+	for _, td := range testData {
+		t.Run(td.name, func(t *testing.T) {
+			components, err := ParseComponents(td.input)
+			if td.isValid {
+				assert.NoError(t, err)
+				assert.Equal(t, td.expected, components)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func Test_restoreComponentsFrom(t *testing.T) {
+	dataDir := createTestDataDir(t)
+	backup := createTestDataDir(t)
+	require.NoError(t, os.WriteFile(filepath.Join(backup, "etcd/member/snap/db"), []byte("old database"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(backup, "certs/ca.crt"), []byte("old certificate"), 0600))
+	require.NoError(t, restoreComponentsFrom(backup, dataDir, []Component{ComponentEtcd}))
+
+	contents, err := os.ReadFile(filepath.Join(dataDir, "etcd/member/snap/db"))
+	require.NoError(t, err)
+	assert.Equal(t, "old database", string(contents))
+	contents, err = os.ReadFile(filepath.Join(dataDir, "certs/ca.crt"))
+	require.NoError(t, err)
+	assert.Equal(t, "certificate", string(contents))
+
+	entries, err := os.ReadDir(dataDir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 5, "intermediate and saved copies should be removed")
+
+	// Backup of another version must not be partially restored.
+	require.NoError(t, os.WriteFile(filepath.Join(backup, "version"), []byte(`{"version":"4.17.0"}`), 0600))
+	assert.Error(t, restoreComponentsFrom(backup, dataDir, []Component{ComponentEtcd}))
+}
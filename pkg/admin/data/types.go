@@ -26,7 +26,12 @@ func (sp StoragePath) SubStorage(subdir string) StoragePath {
 
 type Manager interface {
 	Backup(BackupName) (string, error)
+	BackupOnline(BackupName) (string, error)
+	BackupArchive(src string, name BackupName) (string, error)
+	BackupOnlineArchive(BackupName) (string, error)
 	Restore(BackupName) error
+	RestoreComponents(BackupName, []Component) error
+	RunWithRestoreHooks(BackupName, func() error) error
 
 	BackupExists(BackupName) (bool, error)
 	GetBackupPath(BackupName) string
@@ -6,15 +6,9 @@ import (
 	"os"
 	"path/filepath"
 
-	"github.com/openshift/microshift/pkg/config"
-
 	"k8s.io/klog/v2"
 )
 
-var (
-	healthFilepath = filepath.Join(config.BackupsDir, "health.json")
-)
-
 type HealthInfo struct {
 	Health       string `json:"health"`
 	DeploymentID string `json:"deployment_id"`
@@ -23,7 +17,8 @@ type HealthInfo struct {
 
 // updateHealthInfo updates health.json files with hardcoded "healthy" and
 // deployment and boot IDs from provided argument.
-func updateHealthInfo(vf versionFile) error {
+func updateHealthInfo(backupsDir string, vf versionFile) error {
+	healthFilepath := filepath.Join(backupsDir, "health.json")
 	// health.json in kept in place to support rollback to 4.14.0~rc.1 and earlier,
 	// as lack of the file would result in MicroShift neither backing up the data
 	// nor restoring a backup and we want to always create a backup.
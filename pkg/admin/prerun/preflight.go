@@ -0,0 +1,115 @@
+package prerun
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+
+	"github.com/openshift/microshift/pkg/admin/check"
+	"github.com/openshift/microshift/pkg/admin/data"
+	"github.com/openshift/microshift/pkg/config"
+	"github.com/openshift/microshift/pkg/util"
+)
+
+// PreflightReport lists results of all checks performed before the upgrade.
+type PreflightReport struct {
+	TargetVersion string        `json:"targetVersion"`
+	DataVersion   string        `json:"dataVersion,omitempty"`
+	Checks        check.Results `json:"checks"`
+}
+
+// Preflight evaluates whether the existing data can be upgraded to the target version
+// using the same rules as the pre-run of the target version would: version skew and
+// blocked upgrades. It also checks if there is enough disk space for the backup
+// created before the upgrade.
+// Blocked upgrades are read from the blocksFile, e.g. from the target release,
+// or from the list embedded in the installed MicroShift if blocksFile is empty.
+// Returned error means that the checks could not be performed.
+func Preflight(target, blocksFile string, paths config.Paths) (*PreflightReport, error) {
+	targetVer, err := versionMetadataFromString(target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid target version: %w", err)
+	}
+	report := &PreflightReport{TargetVersion: targetVer.String()}
+
+	dataVer, err := getVersionOfExistingData(paths.DataDir)
+	if err != nil {
+		return nil, err
+	}
+	if dataVer == nil {
+		report.Checks.Add(VersionSkewRule, nil, "data does not exist")
+		report.Checks.Add(UpgradeBlocksRule, nil, "data does not exist")
+		return report, nil
+	}
+	report.DataVersion = dataVer.String()
+
+	report.Checks.Add(VersionSkewRule, checkVersionCompatibility(targetVer, *dataVer),
+		fmt.Sprintf("upgrade from %s to %s is supported", dataVer.String(), targetVer.String()))
+
+	blocks, source, err := getBlockedUpgradesForPreflight(blocksFile)
+	switch {
+	case err != nil:
+		report.Checks.Add(UpgradeBlocksRule, err, "")
+	case blocks == nil:
+		report.Checks.Add(UpgradeBlocksRule, nil, "list of blocked upgrades is not available")
+	default:
+		report.Checks.Add(UpgradeBlocksRule, isUpgradeBlockedByList(blocks, targetVer, *dataVer),
+			fmt.Sprintf("upgrade is not blocked by %s", source))
+	}
+
+	required, available, path, err := getDiskSpaceForBackup(paths)
+	if err != nil {
+		report.Checks.Add("disk-space", err, "")
+	} else if available < required {
+		report.Checks.Add("disk-space", fmt.Errorf("not enough disk space in %q to back up the data: required=%vM available=%vM",
+			path, required/1024/1024, available/1024/1024), "")
+	} else {
+		report.Checks.Add("disk-space", nil, fmt.Sprintf("enough disk space in %q to back up the data: required=%vM available=%vM",
+			path, required/1024/1024, available/1024/1024))
+	}
+
+	return report, nil
+}
+
+func getBlockedUpgradesForPreflight(blocksFile string) ([]byte, string, error) {
+	if blocksFile != "" {
+		buf, err := readBlockedUpgradesFile(blocksFile)
+		return buf, blocksFile, err
+	}
+	buf, err := getBlockedUpgradesAsset()
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, "", nil
+		}
+		return nil, "", err
+	}
+	return buf, "the list embedded in the installed MicroShift", nil
+}
+
+// getDiskSpaceForBackup returns size of the data and available disk space
+// in the backups directory, or its closest existing parent if it doesn't exist yet.
+func getDiskSpaceForBackup(paths config.Paths) (uint64, uint64, string, error) {
+	required, err := data.GetSizeOfMicroShiftData(paths.DataDir)
+	if err != nil {
+		return 0, 0, "", err
+	}
+
+	path := paths.BackupsDir
+	for {
+		exists, err := util.PathExists(path)
+		if err != nil {
+			return 0, 0, "", err
+		}
+		if exists || path == filepath.Dir(path) {
+			break
+		}
+		path = filepath.Dir(path)
+	}
+
+	available, err := data.GetAvailableDiskSpace(path)
+	if err != nil {
+		return 0, 0, "", err
+	}
+	return required, available, path, nil
+}
@@ -12,15 +12,12 @@ import (
 	"k8s.io/klog/v2"
 )
 
-var (
-	restoreFilepath = filepath.Join(config.BackupsDir, "restore")
-)
-
-func DataManagement(dataManager datadir.Manager) error {
+func DataManagement(dataManager datadir.Manager, paths config.Paths) error {
 	klog.InfoS("START pre-run data management")
 
 	dm := dataManagement{
 		dataManager: dataManager,
+		paths:       paths,
 	}
 
 	if err := dm.perform(); err != nil {
@@ -34,14 +31,20 @@ func DataManagement(dataManager datadir.Manager) error {
 
 type dataManagement struct {
 	dataManager datadir.Manager
+	paths       config.Paths
+}
+
+// restoreFilepath is the marker which makes MicroShift restore a backup on the next start.
+func (dm *dataManagement) restoreFilepath() string {
+	return filepath.Join(dm.paths.BackupsDir, "restore")
 }
 
 func (dm *dataManagement) perform() error {
 	if isOstree, err := util.PathExists("/run/ostree-booted"); err != nil {
 		return fmt.Errorf("failed to check if system is ostree: %w", err)
 	} else if !isOstree {
-		klog.InfoS("System is not OSTree-based - skipping data management")
-		return nil
+		klog.InfoS("System is not OSTree-based - managing data on version changes")
+		return dm.performRPM()
 	}
 
 	klog.Info("START creating backup")
@@ -62,7 +65,7 @@ func (dm *dataManagement) perform() error {
 }
 
 func (dm *dataManagement) backup() error {
-	dataExists, err := util.PathExistsAndIsNotEmpty(config.DataDir, ".nodename")
+	dataExists, err := util.PathExistsAndIsNotEmpty(dm.paths.DataDir, ".nodename", config.ImmutableFieldsFileName)
 	if err != nil {
 		return fmt.Errorf("failed to check if data directory exists: %w", err)
 	}
@@ -71,7 +74,7 @@ func (dm *dataManagement) backup() error {
 		return nil
 	}
 
-	versionFileExists, err := util.PathExistsAndIsNotEmpty(versionFilePath)
+	versionFileExists, err := util.PathExistsAndIsNotEmpty(versionFilePath(dm.paths.DataDir))
 	if err != nil {
 		return fmt.Errorf("checking if version metadata exists failed: %w", err)
 	}
@@ -80,7 +83,7 @@ func (dm *dataManagement) backup() error {
 		return dm.backup413()
 	}
 
-	versionFile, err := getVersionFile()
+	versionFile, err := getVersionFile(dm.paths.DataDir)
 	if err != nil {
 		return fmt.Errorf("loading version metadata failed: %w", err)
 	}
@@ -127,18 +130,18 @@ func (dm *dataManagement) backup() error {
 }
 
 func (dm *dataManagement) optionalRestore() error {
-	restoreFileExists, err := util.PathExists(restoreFilepath)
+	restoreFileExists, err := util.PathExists(dm.restoreFilepath())
 	if err != nil {
 		return err
 	}
 
 	if !restoreFileExists {
 		klog.InfoS("Restore marker file does not exist - skipping restore, "+
-			"continuing startup with current data", "path", restoreFilepath)
+			"continuing startup with current data", "path", dm.restoreFilepath())
 		return nil
 	}
 	klog.InfoS("Restore marker file exists - attempting to restore",
-		"path", restoreFilepath)
+		"path", dm.restoreFilepath())
 
 	currentDeploymentID, err := GetCurrentDeploymentID()
 	if err != nil {
@@ -159,7 +162,7 @@ func (dm *dataManagement) optionalRestore() error {
 		return nil
 	}
 
-	dataExists, err := util.PathExistsAndIsNotEmpty(config.DataDir, ".nodename")
+	dataExists, err := util.PathExistsAndIsNotEmpty(dm.paths.DataDir, ".nodename", config.ImmutableFieldsFileName)
 	if err != nil {
 		return fmt.Errorf("failed to check if data directory exists: %w", err)
 	}
@@ -177,7 +180,7 @@ func (dm *dataManagement) optionalRestore() error {
 		return nil
 	}
 
-	versionFileExists, err := util.PathExistsAndIsNotEmpty(versionFilePath)
+	versionFileExists, err := util.PathExistsAndIsNotEmpty(versionFilePath(dm.paths.DataDir))
 	if err != nil {
 		return fmt.Errorf("checking if version metadata exists failed: %w", err)
 	}
@@ -201,7 +204,7 @@ func (dm *dataManagement) optionalRestore() error {
 		return nil
 	}
 
-	versionFile, err := getVersionFile()
+	versionFile, err := getVersionFile(dm.paths.DataDir)
 	if err != nil {
 		return fmt.Errorf("loading version metadata failed: %w", err)
 	}
@@ -245,14 +248,14 @@ func (dm *dataManagement) optionalRestore() error {
 }
 
 func (dm *dataManagement) removeRestoreFile() error {
-	klog.InfoS("Removing restore marker filepath", "path", restoreFilepath)
-	if err := os.Remove(restoreFilepath); err != nil {
+	klog.InfoS("Removing restore marker filepath", "path", dm.restoreFilepath())
+	if err := os.Remove(dm.restoreFilepath()); err != nil {
 		klog.ErrorS(err, "FATAL ERROR: Failed to remove file - existence of the file will result in unexpected data restores: "+
 			"remove the file manually and make sure microshift.service can manipulate it",
-			"file", restoreFilepath)
+			"file", dm.restoreFilepath())
 		return err
 	}
-	klog.InfoS("Removed restore marker filepath", "path", restoreFilepath)
+	klog.InfoS("Removed restore marker filepath", "path", dm.restoreFilepath())
 	return nil
 }
 
@@ -0,0 +1,215 @@
+package prerun
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/openshift/microshift/pkg/admin/data"
+	"github.com/openshift/microshift/pkg/config"
+	"github.com/openshift/microshift/pkg/util"
+	"k8s.io/klog/v2"
+)
+
+// On RPM systems there are no deployments to roll back to, so the backups
+// are created when the MicroShift executable is upgraded: before the new version
+// migrates the data. If the new version fails, the MicroShift RPM can be
+// downgraded and the backup restored by creating the restore marker file.
+
+const (
+	preUpgradeBackupPrefix = "pre-upgrade"
+)
+
+// preUpgradeBackupName returns a name of the backup of the data
+// created before upgrading from one version to another.
+func preUpgradeBackupName(from, to versionMetadata) data.BackupName {
+	return data.BackupName(fmt.Sprintf("%s_%s_to_%s", preUpgradeBackupPrefix, from.String(), to.String()))
+}
+
+// parsePreUpgradeBackupName returns versions encoded in the name of the pre-upgrade backup.
+// The last return value is false if the name is not a pre-upgrade backup's name.
+func parsePreUpgradeBackupName(name data.BackupName) (versionMetadata, versionMetadata, bool) {
+	spl := strings.Split(string(name), "_")
+	if len(spl) != 4 || spl[0] != preUpgradeBackupPrefix || spl[2] != "to" {
+		return versionMetadata{}, versionMetadata{}, false
+	}
+	from, err := versionMetadataFromString(spl[1])
+	if err != nil {
+		return versionMetadata{}, versionMetadata{}, false
+	}
+	to, err := versionMetadataFromString(spl[3])
+	if err != nil {
+		return versionMetadata{}, versionMetadata{}, false
+	}
+	return from, to, true
+}
+
+// getPreUpgrade returns pre-upgrade backups of the data of given version.
+func (bs Backups) getPreUpgrade(from *versionMetadata) Backups {
+	return bs.filter(func(name data.BackupName) bool {
+		f, _, ok := parsePreUpgradeBackupName(name)
+		return ok && (from == nil || f == *from)
+	})
+}
+
+// isNewerThan returns true if v is a more recent version than other.
+func (v versionMetadata) isNewerThan(other versionMetadata) bool {
+	if v.Major != other.Major {
+		return v.Major > other.Major
+	}
+	if v.Minor != other.Minor {
+		return v.Minor > other.Minor
+	}
+	return v.Patch > other.Patch
+}
+
+func (dm *dataManagement) performRPM() error {
+	klog.Info("START creating pre-upgrade backup")
+	if err := dm.preUpgradeBackup(); err != nil {
+		klog.ErrorS(err, "FAIL creating pre-upgrade backup")
+		return err
+	}
+	klog.Info("END creating pre-upgrade backup")
+
+	klog.InfoS("START optional restore of pre-upgrade backup")
+	if err := dm.optionalPreUpgradeRestore(); err != nil {
+		klog.ErrorS(err, "FAIL optional restore of pre-upgrade backup")
+		return err
+	}
+	klog.InfoS("END optional restore of pre-upgrade backup")
+
+	return nil
+}
+
+func (dm *dataManagement) preUpgradeBackup() error {
+	dataExists, err := util.PathExistsAndIsNotEmpty(dm.paths.DataDir, ".nodename", config.ImmutableFieldsFileName)
+	if err != nil {
+		return fmt.Errorf("failed to check if data directory exists: %w", err)
+	}
+	if !dataExists {
+		klog.InfoS("MicroShift data does not exist - skipping backup, continuing startup")
+		return nil
+	}
+
+	versionFileExists, err := util.PathExistsAndIsNotEmpty(versionFilePath(dm.paths.DataDir))
+	if err != nil {
+		return fmt.Errorf("checking if version metadata exists failed: %w", err)
+	}
+	if !versionFileExists {
+		klog.InfoS("Data exists, but version file is missing - assuming pre-4.14 data")
+		return dm.backup413()
+	}
+
+	versionFile, err := getVersionFile(dm.paths.DataDir)
+	if err != nil {
+		return fmt.Errorf("loading version metadata failed: %w", err)
+	}
+	klog.InfoS("Contents of version file", "contents", versionFile)
+
+	execVer, err := GetVersionOfExecutable()
+	if err != nil {
+		return fmt.Errorf("failed to get version of MicroShift executable: %w", err)
+	}
+
+	if !execVer.isNewerThan(versionFile.Version) {
+		klog.InfoS("Executable is not newer than the data - skipping backup",
+			"exec", execVer.String(), "data", versionFile.Version.String())
+		return nil
+	}
+
+	if err := checkVersionCompatibility(execVer, versionFile.Version); err != nil {
+		// Version management will refuse to start MicroShift before the data is changed.
+		klog.InfoS("Executable is not compatible with the data - skipping backup", "reason", err)
+		return nil
+	}
+
+	existingBackups, err := getBackups(dm.dataManager)
+	if err != nil {
+		return err
+	}
+
+	newBackupName := preUpgradeBackupName(versionFile.Version, execVer)
+	if existingBackups.has(newBackupName) {
+		// Version file is updated before the data is migrated, so the backup
+		// must be a leftover of an upgrade attempt that failed early. Current
+		// data is the most up to date one.
+		klog.InfoS("Pre-upgrade backup already exists - removing and creating a new one", "name", newBackupName)
+		if err := dm.dataManager.RemoveBackup(newBackupName); err != nil {
+			return fmt.Errorf("failed to remove existing backup %q: %w", newBackupName, err)
+		}
+	}
+
+	if _, err := dm.dataManager.Backup(newBackupName); err != nil {
+		return fmt.Errorf("failed to create backup %q: %w", newBackupName, err)
+	}
+	klog.InfoS("Created pre-upgrade backup. If MicroShift fails to start after the upgrade, "+
+		"downgrade MicroShift to the version of the backup and create the restore marker file "+
+		"to restore the backup on the next start",
+		"name", newBackupName,
+		"version", versionFile.Version.String(),
+		"restoreMarker", dm.restoreFilepath())
+
+	// Only the backup for the most recent upgrade is useful for rolling back.
+	existingBackups.getPreUpgrade(nil).
+		filter(func(name data.BackupName) bool { return name != newBackupName }).
+		removeAll(dm.dataManager)
+
+	return nil
+}
+
+func (dm *dataManagement) optionalPreUpgradeRestore() error {
+	restoreFileExists, err := util.PathExists(dm.restoreFilepath())
+	if err != nil {
+		return err
+	}
+
+	if !restoreFileExists {
+		klog.InfoS("Restore marker file does not exist - skipping restore, "+
+			"continuing startup with current data", "path", dm.restoreFilepath())
+		return nil
+	}
+	klog.InfoS("Restore marker file exists - attempting to restore",
+		"path", dm.restoreFilepath())
+
+	execVer, err := GetVersionOfExecutable()
+	if err != nil {
+		return fmt.Errorf("failed to get version of MicroShift executable: %w", err)
+	}
+
+	existingBackups, err := getBackups(dm.dataManager)
+	if err != nil {
+		return err
+	}
+
+	backup := existingBackups.getPreUpgrade(&execVer).getOneOrNone()
+	if backup == "" {
+		klog.InfoS("WARNING: MicroShift was instructed to restore a backup, "+
+			"but there is no pre-upgrade backup for the version of the executable - continuing start up with current data",
+			"version", execVer.String())
+		return nil
+	}
+
+	dataExists, err := util.PathExistsAndIsNotEmpty(dm.paths.DataDir, ".nodename", config.ImmutableFieldsFileName)
+	if err != nil {
+		return fmt.Errorf("failed to check if data directory exists: %w", err)
+	}
+	if dataExists {
+		dataVer, err := getVersionOfData(dm.paths.DataDir)
+		if err != nil {
+			return fmt.Errorf("loading version metadata failed: %w", err)
+		}
+		if dataVer == execVer {
+			// Data was not migrated to the newer version (or it was already restored).
+			klog.InfoS("Version of the data and the executable are the same - " +
+				"not restoring, continuing startup with current data.")
+			return dm.removeRestoreFile()
+		}
+	}
+
+	if err := dm.dataManager.Restore(backup); err != nil {
+		klog.ErrorS(err, "Failed to restore")
+		return fmt.Errorf("failed to restore backup: %w", err)
+	}
+	klog.InfoS("Restored pre-upgrade backup", "name", backup)
+
+	return dm.removeRestoreFile()
+}
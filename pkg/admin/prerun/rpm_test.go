@@ -0,0 +1,52 @@
+package prerun
+
+import (
+	"testing"
+
+	"github.com/openshift/microshift/pkg/admin/data"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_preUpgradeBackupName(t *testing.T) {
+	from := versionMetadata{Major: 4, Minor: 17, Patch: 3}
+	to := versionMetadata{Major: 4, Minor: 18, Patch: 0}
+
+	name := preUpgradeBackupName(from, to)
+	assert.Equal(t, data.BackupName("pre-upgrade_4.17.3_to_4.18.0"), name)
+
+	parsedFrom, parsedTo, ok := parsePreUpgradeBackupName(name)
+	assert.True(t, ok)
+	assert.Equal(t, from, parsedFrom)
+	assert.Equal(t, to, parsedTo)
+
+	for _, n := range []data.BackupName{
+		"4.13",
+		"rhel-35d7b5c80f0f1378d6846f6dc1304bbf1dcdc5847198fcd4e6099364eaf99048.0_80364fcf3df54284a6902687e2cdd4c2",
+		"pre-upgrade_4.17_to_4.18.0",
+		"pre-upgrade_4.17.3_4.18.0",
+	} {
+		_, _, ok := parsePreUpgradeBackupName(n)
+		assert.False(t, ok, n)
+	}
+}
+
+func Test_getPreUpgrade(t *testing.T) {
+	backups := Backups{
+		"4.13",
+		"pre-upgrade_4.16.1_to_4.17.0",
+		"pre-upgrade_4.17.3_to_4.18.0",
+		"manual-backup",
+	}
+
+	assert.Equal(t, Backups{"pre-upgrade_4.16.1_to_4.17.0", "pre-upgrade_4.17.3_to_4.18.0"}, backups.getPreUpgrade(nil))
+	assert.Equal(t, Backups{"pre-upgrade_4.17.3_to_4.18.0"}, backups.getPreUpgrade(&versionMetadata{Major: 4, Minor: 17, Patch: 3}))
+	assert.Empty(t, backups.getPreUpgrade(&versionMetadata{Major: 4, Minor: 18, Patch: 0}))
+}
+
+func Test_isNewerThan(t *testing.T) {
+	v := versionMetadata{Major: 4, Minor: 18, Patch: 2}
+	assert.True(t, v.isNewerThan(versionMetadata{Major: 4, Minor: 18, Patch: 1}))
+	assert.True(t, v.isNewerThan(versionMetadata{Major: 4, Minor: 17, Patch: 9}))
+	assert.False(t, v.isNewerThan(v))
+	assert.False(t, v.isNewerThan(versionMetadata{Major: 4, Minor: 19, Patch: 0}))
+}
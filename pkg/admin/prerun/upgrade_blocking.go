@@ -1,16 +1,69 @@
 package prerun
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io/fs"
+	"os"
+	"slices"
 	"strings"
 
+	"github.com/blang/semver/v4"
 	embedded "github.com/openshift/microshift/assets"
 	"k8s.io/klog/v2"
 )
 
+// upgradeBlock describes upgrades that are not allowed: from versions of the data
+// matching From range to versions of the executable matching To range.
+// Ranges are expressions like ">=4.14.0 <4.14.5" or "4.15.2 || 4.15.3"
+// (see github.com/blang/semver's ParseRange).
+type upgradeBlock struct {
+	To     string `json:"to"`
+	From   string `json:"from"`
+	Reason string `json:"reason,omitempty"`
+	// KB is a link to the knowledge base article explaining the block.
+	KB string `json:"kb,omitempty"`
+
+	toRange   semver.Range
+	fromRange semver.Range
+}
+
+func (b *upgradeBlock) parse() error {
+	var err error
+	if b.toRange, err = semver.ParseRange(b.To); err != nil {
+		return fmt.Errorf("invalid range of target versions %q: %w", b.To, err)
+	}
+	if b.fromRange, err = semver.ParseRange(b.From); err != nil {
+		return fmt.Errorf("invalid range of source versions %q: %w", b.From, err)
+	}
+	return nil
+}
+
+func (b *upgradeBlock) matches(execVersion, dataVersion versionMetadata) bool {
+	return b.toRange(execVersion.semver()) && b.fromRange(dataVersion.semver())
+}
+
+// BlockedUpgradeError is returned when the upgrade matches one of the blocks.
+type BlockedUpgradeError struct {
+	From   string
+	To     string
+	Reason string
+	KB     string
+}
+
+func (e *BlockedUpgradeError) Error() string {
+	msg := fmt.Sprintf("upgrade from %q to %q is blocked", e.From, e.To)
+	if e.Reason != "" {
+		msg += ": " + e.Reason
+	}
+	if e.KB != "" {
+		msg += fmt.Sprintf(" (see %s)", e.KB)
+	}
+	return msg
+}
+
 func isUpgradeBlocked(execVersion versionMetadata, dataVersion versionMetadata) error {
 	klog.InfoS("START obtaining list of blocked upgrades")
 	buf, err := getBlockedUpgradesAsset()
@@ -22,15 +75,19 @@ func isUpgradeBlocked(execVersion versionMetadata, dataVersion versionMetadata)
 		klog.ErrorS(err, "FAIL obtaining list of blocked upgrades")
 		return err
 	}
-	m, err := unmarshalBlockedUpgrades(buf)
+	return isUpgradeBlockedByList(buf, execVersion, dataVersion)
+}
+
+func isUpgradeBlockedByList(buf []byte, execVersion versionMetadata, dataVersion versionMetadata) error {
+	blocks, err := unmarshalBlockedUpgrades(buf)
 	if err != nil {
 		klog.ErrorS(err, "FAIL unmarshal blocked upgrades asset", "asset", strings.ReplaceAll(string(buf), "\n", ""))
 		return err
 	}
-	klog.InfoS("END obtaining list of blocked upgrades", "blocked-upgrades", m)
+	klog.InfoS("END obtaining list of blocked upgrades", "blocked-upgrades", blocks)
 
 	klog.InfoS("START checking if upgrade is blocked", "existing-data-version", dataVersion, "new-binary-version", execVersion)
-	if err := isBlocked(m, execVersion.String(), dataVersion.String()); err != nil {
+	if err := isBlocked(blocks, execVersion, dataVersion); err != nil {
 		klog.ErrorS(err, "FAIL upgrade is blocked")
 		return err
 	}
@@ -42,22 +99,63 @@ func getBlockedUpgradesAsset() ([]byte, error) {
 	return embedded.Asset("release/upgrade-blocks.json")
 }
 
-func unmarshalBlockedUpgrades(data []byte) (map[string][]string, error) {
-	var blockedEdges map[string][]string
-	err := json.Unmarshal(data, &blockedEdges)
+// readBlockedUpgradesFile reads the list of blocked upgrades from the file,
+// e.g. from the release that is going to be installed.
+func readBlockedUpgradesFile(path string) ([]byte, error) {
+	buf, err := os.ReadFile(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal %q: %w", string(data), err)
+		return nil, fmt.Errorf("failed to read blocked upgrades from %q: %w", path, err)
+	}
+	return buf, nil
+}
+
+// unmarshalBlockedUpgrades parses the list of blocked upgrades:
+//
+//	{"blocks": [{"to": ">=4.14.10", "from": ">=4.14.0 <4.14.5", "reason": "...", "kb": "https://..."}]}
+//
+// The older schema mapping a target version to the list of source versions
+// (`{"4.14.10": ["4.14.5", "4.14.6"]}`) is also supported.
+func unmarshalBlockedUpgrades(data []byte) ([]upgradeBlock, error) {
+	var blocks []upgradeBlock
+
+	var list struct {
+		Blocks *[]upgradeBlock `json:"blocks"`
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&list); err == nil && list.Blocks != nil {
+		blocks = *list.Blocks
+	} else {
+		var blockedEdges map[string][]string
+		if err := json.Unmarshal(data, &blockedEdges); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal %q: %w", string(data), err)
+		}
+		blocks = make([]upgradeBlock, 0, len(blockedEdges))
+		for to, from := range blockedEdges {
+			if len(from) == 0 {
+				continue
+			}
+			blocks = append(blocks, upgradeBlock{To: to, From: strings.Join(from, " || ")})
+		}
+		slices.SortFunc(blocks, func(a, b upgradeBlock) int { return strings.Compare(a.To, b.To) })
+	}
+
+	for i := range blocks {
+		if err := blocks[i].parse(); err != nil {
+			return nil, fmt.Errorf("invalid blocked upgrade #%d: %w", i, err)
+		}
 	}
-	return blockedEdges, nil
+	return blocks, nil
 }
 
-func isBlocked(blockedUpgrades map[string][]string, execVersion, dataVersion string) error {
-	for targetVersion, fromVersions := range blockedUpgrades {
-		if targetVersion == execVersion {
-			for _, from := range fromVersions {
-				if from == dataVersion {
-					return fmt.Errorf("upgrade from %q to %q is blocked", dataVersion, execVersion)
-				}
+func isBlocked(blocks []upgradeBlock, execVersion, dataVersion versionMetadata) error {
+	for _, b := range blocks {
+		if b.matches(execVersion, dataVersion) {
+			return &BlockedUpgradeError{
+				From:   dataVersion.String(),
+				To:     execVersion.String(),
+				Reason: b.Reason,
+				KB:     b.KB,
 			}
 		}
 	}
@@ -4,35 +4,67 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func Test_UnmarshalBlockedUpgrades(t *testing.T) {
 	testData := []struct {
 		input          string
-		expectedOutput map[string][]string
+		expectedOutput []upgradeBlock
+		errExpected    bool
 	}{
 		{
 			input:          `{"4.14.10": ["4.14.5", "4.14.4"]}`,
-			expectedOutput: map[string][]string{"4.14.10": {"4.14.5", "4.14.4"}},
+			expectedOutput: []upgradeBlock{{To: "4.14.10", From: "4.14.5 || 4.14.4"}},
 		},
 		{
 			input:          `{}`,
-			expectedOutput: make(map[string][]string),
+			expectedOutput: []upgradeBlock{},
+		},
+		{
+			input: `{"blocks": [{"to": ">=4.14.10", "from": ">=4.14.0 <4.14.5", "reason": "etcd corruption", "kb": "https://access.redhat.com/solutions/1"}]}`,
+			expectedOutput: []upgradeBlock{{
+				To: ">=4.14.10", From: ">=4.14.0 <4.14.5", Reason: "etcd corruption", KB: "https://access.redhat.com/solutions/1",
+			}},
+		},
+		{
+			input:          `{"blocks": []}`,
+			expectedOutput: []upgradeBlock{},
+		},
+		{
+			input:       `{"blocks": [{"to": "4.14", "from": "4.13.0"}]}`,
+			errExpected: true,
+		},
+		{
+			input:       `{"4.14.10": "4.14.5"}`,
+			errExpected: true,
 		},
 	}
 
 	for _, td := range testData {
 		result, err := unmarshalBlockedUpgrades([]byte(td.input))
+		if td.errExpected {
+			assert.Error(t, err)
+			continue
+		}
 		assert.NoError(t, err)
+		// Compare without the parsed ranges.
+		for i := range result {
+			result[i].toRange, result[i].fromRange = nil, nil
+		}
 		assert.Equal(t, td.expectedOutput, result)
 	}
 }
 
 func Test_IsBlocked(t *testing.T) {
-	edges := map[string][]string{
-		"4.14.10": {"4.14.5", "4.14.6"},
-		"4.15.5":  {"4.15.2"},
-	}
+	blocks, err := unmarshalBlockedUpgrades([]byte(`{
+		"blocks": [
+			{"to": "4.14.10", "from": "4.14.5 || 4.14.6"},
+			{"to": "4.15.5", "from": "4.15.2"},
+			{"to": ">=4.16.0 <4.17.0", "from": ">=4.15.0 <4.15.3", "reason": "reason", "kb": "https://kb"}
+		]
+	}`))
+	require.NoError(t, err)
 
 	testData := []struct {
 		dataVersion string
@@ -69,13 +101,39 @@ func Test_IsBlocked(t *testing.T) {
 			execVersion: "4.15.5",
 			errExpected: true,
 		},
+		{
+			dataVersion: "4.15.2",
+			execVersion: "4.16.3",
+			errExpected: true,
+		},
+		{
+			dataVersion: "4.15.3",
+			execVersion: "4.16.3",
+			errExpected: false,
+		},
+		{
+			dataVersion: "4.15.0",
+			execVersion: "4.17.0",
+			errExpected: false,
+		},
 	}
 
 	for _, td := range testData {
+		execVersion, err := versionMetadataFromString(td.execVersion)
+		require.NoError(t, err)
+		dataVersion, err := versionMetadataFromString(td.dataVersion)
+		require.NoError(t, err)
+
+		err = isBlocked(blocks, execVersion, dataVersion)
 		if td.errExpected {
-			assert.Error(t, isBlocked(edges, td.execVersion, td.dataVersion))
+			assert.Error(t, err, "%s -> %s", td.dataVersion, td.execVersion)
 		} else {
-			assert.NoError(t, isBlocked(edges, td.execVersion, td.dataVersion))
+			assert.NoError(t, err, "%s -> %s", td.dataVersion, td.execVersion)
 		}
 	}
+
+	err = isBlocked(blocks, versionMetadata{Major: 4, Minor: 16, Patch: 0}, versionMetadata{Major: 4, Minor: 15, Patch: 1})
+	var blockedErr *BlockedUpgradeError
+	require.ErrorAs(t, err, &blockedErr)
+	assert.Equal(t, `upgrade from "4.15.1" to "4.16.0" is blocked: reason (see https://kb)`, err.Error())
 }
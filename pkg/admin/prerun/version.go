@@ -9,6 +9,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/blang/semver/v4"
 	"github.com/openshift/microshift/pkg/admin/data"
 	"github.com/openshift/microshift/pkg/config"
 	"github.com/openshift/microshift/pkg/util"
@@ -17,8 +18,6 @@ import (
 )
 
 var (
-	versionFilePath = filepath.Join(config.DataDir, "version")
-
 	errDataVersionDoesNotExist = errors.New("version file for MicroShift data does not exist")
 )
 
@@ -34,9 +33,14 @@ func (hi *versionFile) BackupName() data.BackupName {
 	return data.BackupName(fmt.Sprintf("%s_%s", hi.DeploymentID, hi.BootID))
 }
 
-func VersionMetadataManagement() error {
+// versionFilePath returns path of the file with version of the data in the dataDir.
+func versionFilePath(dataDir string) string {
+	return filepath.Join(dataDir, "version")
+}
+
+func VersionMetadataManagement(paths config.Paths) error {
 	klog.InfoS("START version metadata management")
-	if err := versionMetadataManagement(); err != nil {
+	if err := versionMetadataManagement(paths); err != nil {
 		klog.ErrorS(err, "FAIL version metadata management")
 		return err
 	}
@@ -44,9 +48,9 @@ func VersionMetadataManagement() error {
 	return nil
 }
 
-func versionMetadataManagement() error {
+func versionMetadataManagement(paths config.Paths) error {
 	klog.InfoS("START getting versions")
-	ver, err := getVersions()
+	ver, err := getVersions(paths.DataDir)
 	if err != nil {
 		klog.ErrorS(err, "FAIL getting versions")
 		return err
@@ -72,7 +76,7 @@ func versionMetadataManagement() error {
 	}
 
 	klog.InfoS("START updating version file")
-	if err := updateVersionFile(ver.exec); err != nil {
+	if err := updateVersionFile(paths, ver.exec); err != nil {
 		klog.ErrorS(err, "FAIL updating version file")
 		return err
 	}
@@ -88,49 +92,55 @@ type versions struct {
 
 // getVersions obtains and returns versions of executable and data dir.
 // Version of data will be nil if the MicroShift data does not exist yet.
-func getVersions() (versions, error) {
+func getVersions(dataDir string) (versions, error) {
 	execVer, err := GetVersionOfExecutable()
 	if err != nil {
 		return versions{}, fmt.Errorf("failed to get version of MicroShift executable: %w", err)
 	}
 
-	vs := versions{
-		exec: execVer,
-		data: nil,
+	dataVer, err := getVersionOfExistingData(dataDir)
+	if err != nil {
+		return versions{}, err
 	}
 
-	dataVer, err := getVersionOfData()
+	return versions{
+		exec: execVer,
+		data: dataVer,
+	}, nil
+}
+
+// getVersionOfExistingData returns version of the data dir
+// or nil if the MicroShift data does not exist yet.
+func getVersionOfExistingData(dataDir string) (*versionMetadata, error) {
+	dataVer, err := getVersionOfData(dataDir)
 	if err == nil {
-		vs.data = &dataVer
-		return vs, nil
+		return &dataVer, nil
 	}
 
 	if !errors.Is(err, errDataVersionDoesNotExist) {
 		// error is something else than "file does not exist", like permissions
-		return versions{}, fmt.Errorf("failed to get version of existing MicroShift data: %w", err)
+		return nil, fmt.Errorf("failed to get version of existing MicroShift data: %w", err)
 	}
 
 	// Ignoring .nodename to not get false positives from mere existence of the path
-	dataExists, err := util.PathExistsAndIsNotEmpty(config.DataDir, ".nodename")
+	dataExists, err := util.PathExistsAndIsNotEmpty(dataDir, ".nodename", config.ImmutableFieldsFileName)
 	if err != nil {
-		return versions{}, err
+		return nil, err
 	}
 
 	if !dataExists {
 		// Data directory does not exist so it's first run of MicroShift
 		klog.InfoS("Version file does not exist yet - assuming first run of MicroShift")
-		vs.data = nil // repeated for clarity
-		return vs, nil
+		return nil, nil
 	}
 
 	// Data exists but without version file, let's assume 4.13 and compare versions
 	klog.InfoS("MicroShift data directory exists, but doesn't contain version file" +
 		" - assuming 4.13.0 and proceeding with version compatibility checks")
-	vs.data = &versionMetadata{Major: 4, Minor: 13, Patch: 0}
-	return vs, nil
+	return &versionMetadata{Major: 4, Minor: 13, Patch: 0}, nil
 }
 
-func updateVersionFile(ver versionMetadata) error {
+func updateVersionFile(paths config.Paths, ver versionMetadata) error {
 	currentDeploymentID := ""
 	isOstree, err := util.PathExists("/run/ostree-booted")
 	if err != nil {
@@ -160,12 +170,12 @@ func updateVersionFile(ver versionMetadata) error {
 		return fmt.Errorf("failed to marshal %v: %w", v, err)
 	}
 
-	if err := os.WriteFile(versionFilePath, data, 0600); err != nil {
-		return fmt.Errorf("writing %q to %q failed: %w", string(data), versionFilePath, err)
+	if err := os.WriteFile(versionFilePath(paths.DataDir), data, 0600); err != nil {
+		return fmt.Errorf("writing %q to %q failed: %w", string(data), versionFilePath(paths.DataDir), err)
 	}
 
 	if isOstree {
-		if err := updateHealthInfo(v); err != nil {
+		if err := updateHealthInfo(paths.BackupsDir, v); err != nil {
 			return fmt.Errorf("failed to update health.json: %w", err)
 		}
 	}
@@ -184,6 +194,10 @@ func (v *versionMetadata) String() string {
 	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
 }
 
+func (v versionMetadata) semver() semver.Version {
+	return semver.Version{Major: uint64(v.Major), Minor: uint64(v.Minor), Patch: uint64(v.Patch)}
+}
+
 func (v versionMetadata) MarshalJSON() ([]byte, error) {
 	return json.Marshal(v.String())
 }
@@ -236,9 +250,9 @@ func GetVersionOfExecutable() (versionMetadata, error) {
 	return versionMetadataFromString(fmt.Sprintf("%s.%s.%s", ver.Major, ver.Minor, ver.Patch))
 }
 
-func getVersionOfData() (versionMetadata, error) {
+func getVersionOfData(dataDir string) (versionMetadata, error) {
 	klog.InfoS("START reading version file")
-	verFile, err := getVersionFile()
+	verFile, err := getVersionFile(dataDir)
 	if err != nil {
 		klog.ErrorS(err, "FAIL reading version file")
 		return versionMetadata{}, err
@@ -247,8 +261,9 @@ func getVersionOfData() (versionMetadata, error) {
 	return verFile.Version, nil
 }
 
-func getVersionFile() (versionFile, error) {
-	exists, err := util.PathExistsAndIsNotEmpty(versionFilePath)
+func getVersionFile(dataDir string) (versionFile, error) {
+	path := versionFilePath(dataDir)
+	exists, err := util.PathExistsAndIsNotEmpty(path)
 	if err != nil {
 		return versionFile{}, fmt.Errorf("checking if path exists failed: %w", err)
 	}
@@ -257,9 +272,9 @@ func getVersionFile() (versionFile, error) {
 		return versionFile{}, errDataVersionDoesNotExist
 	}
 
-	versionFileContents, err := os.ReadFile(versionFilePath)
+	versionFileContents, err := os.ReadFile(path)
 	if err != nil {
-		return versionFile{}, fmt.Errorf("reading %q failed: %w", versionFilePath, err)
+		return versionFile{}, fmt.Errorf("reading %q failed: %w", path, err)
 	}
 	return parseVersionFile(versionFileContents)
 }
@@ -285,11 +300,11 @@ func parseVersionFile(contents []byte) (versionFile, error) {
 	}, nil
 }
 
-func GetVersionStringOfData() string {
-	versionMetadata, err := getVersionOfData()
+func GetVersionStringOfData(dataDir string) string {
+	versionMetadata, err := getVersionOfData(dataDir)
 	if err != nil {
 		if errors.Is(err, errDataVersionDoesNotExist) {
-			dataExists, err := util.PathExistsAndIsNotEmpty(config.DataDir, ".nodename")
+			dataExists, err := util.PathExistsAndIsNotEmpty(dataDir, ".nodename", config.ImmutableFieldsFileName)
 			if err == nil && dataExists {
 				// version does not exists, but data exists
 				return "4.13"
@@ -332,3 +347,84 @@ func checkVersionCompatibility(execVer, dataVer versionMetadata) error {
 	klog.InfoS("All version checks passed")
 	return nil
 }
+
+const (
+	// VersionSkewRule is a name of the rule checking if the version of the data
+	// is supported by the executable: same major version, not newer minor version,
+	// and minor version difference within MAX_VERSION_SKEW.
+	VersionSkewRule = "version-skew"
+	// UpgradeBlocksRule is a name of the rule checking if the upgrade
+	// from the version of the data to the executable's version is not blocked.
+	UpgradeBlocksRule = "upgrade-blocks"
+	// VersionFormatRule is a name of the rule checking if the version of the data
+	// can be parsed, so the other rules can be checked.
+	VersionFormatRule = "version-format"
+)
+
+// IncompatibleVersionError is returned when the data is not compatible
+// with the MicroShift executable according to one of the rules.
+type IncompatibleVersionError struct {
+	Rule string
+	Err  error
+}
+
+func (e *IncompatibleVersionError) Error() string {
+	return fmt.Sprintf("%s rule failed: %v", e.Rule, e.Err)
+}
+
+func (e *IncompatibleVersionError) Unwrap() error {
+	return e.Err
+}
+
+// CheckVersionFileCompatibility checks if the data described by given version file
+// (e.g. from a backup) is compatible with the MicroShift executable.
+// It uses the same rules as the pre-run: version skew and blocked upgrades.
+func CheckVersionFileCompatibility(path string) error {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading %q failed: %w", path, err)
+	}
+	vf, err := parseVersionFile(contents)
+	if err != nil {
+		return &IncompatibleVersionError{Rule: VersionFormatRule, Err: err}
+	}
+	execVer, err := GetVersionOfExecutable()
+	if err != nil {
+		return fmt.Errorf("failed to get version of MicroShift executable: %w", err)
+	}
+	return checkDataVersionCompatibility(execVer, vf.Version)
+}
+
+// CheckDataVersionCompatibility checks if the data of given version
+// (e.g. from a backup's manifest) is compatible with the MicroShift executable.
+// Returned *IncompatibleVersionError describes which rule failed,
+// including a malformed version of the data.
+func CheckDataVersionCompatibility(dataVersion string) error {
+	return checkDataVersionStringCompatibility(GetVersionOfExecutable, dataVersion)
+}
+
+func checkDataVersionStringCompatibility(getExecVer func() (versionMetadata, error), dataVersion string) error {
+	dataVer, err := versionMetadataFromString(dataVersion)
+	if err != nil {
+		return &IncompatibleVersionError{Rule: VersionFormatRule, Err: err}
+	}
+	execVer, err := getExecVer()
+	if err != nil {
+		return fmt.Errorf("failed to get version of MicroShift executable: %w", err)
+	}
+	return checkDataVersionCompatibility(execVer, dataVer)
+}
+
+func checkDataVersionCompatibility(execVer, dataVer versionMetadata) error {
+	if err := checkVersionCompatibility(execVer, dataVer); err != nil {
+		return &IncompatibleVersionError{Rule: VersionSkewRule, Err: err}
+	}
+	if err := isUpgradeBlocked(execVer, dataVer); err != nil {
+		var blockedErr *BlockedUpgradeError
+		if errors.As(err, &blockedErr) {
+			return &IncompatibleVersionError{Rule: UpgradeBlocksRule, Err: err}
+		}
+		return err
+	}
+	return nil
+}
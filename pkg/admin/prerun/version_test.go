@@ -66,6 +66,42 @@ func TestCheckVersionDiff(t *testing.T) {
 	}
 }
 
+func TestCheckDataVersionCompatibility(t *testing.T) {
+	getExecVer := func() (versionMetadata, error) {
+		return versionMetadata{Major: 4, Minor: 18, Patch: 2}, nil
+	}
+	testData := []struct {
+		name         string
+		dataVersion  string
+		expectedRule string
+	}{
+		{name: "same version", dataVersion: "4.18.2"},
+		{name: "older patch version", dataVersion: "4.18.0"},
+		{name: "older minor version within the skew", dataVersion: "4.16.5"},
+		{name: "newer minor version", dataVersion: "4.19.0", expectedRule: VersionSkewRule},
+		{name: "newer patch version", dataVersion: "4.18.3"},
+		{name: "older minor version over the skew", dataVersion: "4.15.0", expectedRule: VersionSkewRule},
+		{name: "different major version", dataVersion: "5.18.2", expectedRule: VersionSkewRule},
+		{name: "malformed version", dataVersion: "4.18", expectedRule: VersionFormatRule},
+		{name: "non-numeric version", dataVersion: "4.x.0", expectedRule: VersionFormatRule},
+		{name: "empty version", dataVersion: "", expectedRule: VersionFormatRule},
+	}
+
+	for _, td := range testData {
+		t.Run(td.name, func(t *testing.T) {
+			err := checkDataVersionStringCompatibility(getExecVer, td.dataVersion)
+			if td.expectedRule == "" {
+				assert.NoError(t, err)
+				return
+			}
+			var incompatibleErr *IncompatibleVersionError
+			if assert.ErrorAs(t, err, &incompatibleErr) {
+				assert.Equal(t, td.expectedRule, incompatibleErr.Rule)
+			}
+		})
+	}
+}
+
 func TestVersionFileSerialization(t *testing.T) {
 	expected := `{"version":"4.14.0","deployment_id":"deploy-id","boot_id":"b-id"}`
 
@@ -0,0 +1,437 @@
+package reset
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/moby/sys/mountinfo"
+	"github.com/openshift/microshift/pkg/config/lvmd"
+	"github.com/openshift/microshift/pkg/util"
+	"golang.org/x/sys/unix"
+	"k8s.io/klog/v2"
+)
+
+const (
+	crioSocket    = "/var/run/crio/crio.sock"
+	kubeletDir    = "/var/lib/kubelet"
+	ovnNamespace  = "openshift-ovn-kubernetes"
+	certsDirName  = "certs"
+	etcdScopeName = "microshift-etcd.scope"
+
+	etcdScopeStopTimeout = 30 * time.Second
+	podRemovalRetries    = 5
+)
+
+var (
+	units = []string{"microshift.service", etcdScopeName}
+
+	// ovnPaths are files and directories of OVN-Kubernetes residing outside
+	// of the MicroShift data directory.
+	ovnPaths = []string{
+		"/var/run/ovn",
+		"/var/run/ovn-kubernetes",
+		"/etc/cni/net.d/10-ovn-kubernetes.conf",
+		"/run/cni/bin/ovn-k8s-cni-overlay",
+	}
+
+	// TopoLVM names logical volumes after IDs of the volumes which are UUIDs.
+	topolvmVolumeName = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+)
+
+// Options select what is preserved by the reset.
+type Options struct {
+	// DataDir is MicroShift's data directory to remove.
+	DataDir string
+	// KeepCerts preserves certificates so the clients don't need new kubeconfigs.
+	KeepCerts bool
+	// KeepPVs preserves logical volumes created by TopoLVM.
+	KeepPVs bool
+	// KeepImages preserves container images in the CRI-O storage.
+	KeepImages bool
+}
+
+// Summary lists what was removed by the reset.
+type Summary struct {
+	StoppedUnits   []string `json:"stoppedUnits"`
+	RemovedPods    []string `json:"removedPods"`
+	RemovedImages  bool     `json:"removedImages"`
+	RemovedVolumes []string `json:"removedVolumes"`
+	RemovedPaths   []string `json:"removedPaths"`
+	Kept           []string `json:"kept"`
+}
+
+// Reset stops MicroShift and removes its data, workloads and OVN state
+// so the next start initializes a new cluster.
+// It is a counterpart of `microshift-cleanup-data --all`.
+func Reset(opts Options) (*Summary, error) {
+	s := &Summary{}
+	if opts.DataDir == "" {
+		return s, fmt.Errorf("data directory to reset is not set")
+	}
+
+	// Volume groups must be obtained before the runtime lvmd config is removed.
+	volumeGroups, err := getVolumeGroups(opts.DataDir)
+	if err != nil {
+		return s, err
+	}
+
+	if err := stopUnits(s); err != nil {
+		return s, err
+	}
+	if err := removePods(s); err != nil {
+		return s, err
+	}
+	if opts.KeepImages {
+		s.Kept = append(s.Kept, "container images")
+	} else if err := removeImages(s); err != nil {
+		return s, err
+	}
+	if err := removeOVNState(s); err != nil {
+		return s, err
+	}
+	// Unmount volumes of the pods so the logical volumes and kubelet's
+	// directory can be removed.
+	if err := unmountUnder(kubeletDir); err != nil {
+		return s, err
+	}
+	if opts.KeepPVs {
+		s.Kept = append(s.Kept, "persistent volumes")
+	} else if err := removeLogicalVolumes(s, volumeGroups); err != nil {
+		return s, err
+	}
+	if err := removePath(s, kubeletDir); err != nil {
+		return s, err
+	}
+
+	keep := []string{}
+	if opts.KeepCerts {
+		keep = append(keep, certsDirName)
+		s.Kept = append(s.Kept, filepath.Join(opts.DataDir, certsDirName))
+	}
+	removed, err := removeDataDir(opts.DataDir, keep)
+	s.RemovedPaths = append(s.RemovedPaths, removed...)
+	if err != nil {
+		return s, err
+	}
+
+	return s, nil
+}
+
+func stopUnits(s *Summary) error {
+	for _, unit := range units {
+		state, err := getUnitState(unit)
+		if err != nil {
+			return err
+		}
+		if state == "inactive" || state == "failed" {
+			_ = exec.Command("systemctl", "reset-failed", unit).Run()
+			continue
+		}
+
+		klog.InfoS("Stopping unit", "unit", unit, "state", state)
+		if out, err := exec.Command("systemctl", "stop", unit).CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to stop %q: %s: %w", unit, strings.TrimSpace(string(out)), err)
+		}
+		if unit == etcdScopeName {
+			// Stopping the scope sends SIGTERM to etcd, wait for it to shut down
+			// gracefully instead of killing it.
+			if err := waitForUnitToStop(unit, etcdScopeStopTimeout); err != nil {
+				return err
+			}
+		}
+		_ = exec.Command("systemctl", "reset-failed", unit).Run()
+		s.StoppedUnits = append(s.StoppedUnits, unit)
+	}
+	return nil
+}
+
+func getUnitState(unit string) (string, error) {
+	out, err := exec.Command("systemctl", "show", "-p", "ActiveState", "--value", unit).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("error when checking state of %q: %w", unit, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func waitForUnitToStop(unit string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		state, err := getUnitState(unit)
+		if err != nil {
+			return err
+		}
+		if state == "inactive" || state == "failed" {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%q did not stop within %v (state: %q)", unit, timeout, state)
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+type crictlPod struct {
+	ID       string `json:"id"`
+	Metadata struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"metadata"`
+}
+
+func listPods() ([]crictlPod, error) {
+	out, err := exec.Command("crictl", "pods", "--output", "json").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	var pods struct {
+		Items []crictlPod `json:"items"`
+	}
+	if err := json.Unmarshal(out, &pods); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal list of pods: %w", err)
+	}
+	return pods.Items, nil
+}
+
+// orderPodsForRemoval sorts the pods so the OVN-Kubernetes pods are removed last.
+// Other pods' networking can only be torn down while OVN-Kubernetes is still present.
+func orderPodsForRemoval(pods []crictlPod) []crictlPod {
+	ordered := slices.Clone(pods)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		iOVN := ordered[i].Metadata.Namespace == ovnNamespace
+		jOVN := ordered[j].Metadata.Namespace == ovnNamespace
+		if iOVN != jOVN {
+			return jOVN
+		}
+		return ordered[i].Metadata.Namespace < ordered[j].Metadata.Namespace
+	})
+	return ordered
+}
+
+func removePods(s *Summary) error {
+	exists, err := util.PathExists(crioSocket)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		klog.InfoS("CRI-O socket does not exist - skipping removal of pods", "path", crioSocket)
+		return nil
+	}
+
+	for i := 0; i < podRemovalRetries; i++ {
+		pods, err := listPods()
+		if err != nil {
+			return err
+		}
+		if len(pods) == 0 {
+			return nil
+		}
+		for _, pod := range orderPodsForRemoval(pods) {
+			name := pod.Metadata.Namespace + "/" + pod.Metadata.Name
+			if out, err := exec.Command("crictl", "rmp", "-f", pod.ID).CombinedOutput(); err != nil {
+				klog.ErrorS(err, "Failed to remove pod - will retry", "pod", name, "output", strings.TrimSpace(string(out)))
+				continue
+			}
+			s.RemovedPods = append(s.RemovedPods, name)
+		}
+		time.Sleep(time.Second)
+	}
+
+	pods, err := listPods()
+	if err != nil {
+		return err
+	}
+	if len(pods) != 0 {
+		return fmt.Errorf("failed to remove %d pods", len(pods))
+	}
+	return nil
+}
+
+func removeImages(s *Summary) error {
+	exists, err := util.PathExists(crioSocket)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		klog.InfoS("CRI-O socket does not exist - skipping removal of images", "path", crioSocket)
+		return nil
+	}
+	if out, err := exec.Command("crictl", "rmi", "--all").CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to remove container images: %s: %w", strings.TrimSpace(string(out)), err)
+	}
+	s.RemovedImages = true
+	return nil
+}
+
+func removeOVNState(s *Summary) error {
+	// Removal of the bridge is not detected by the kubelet, so it can only
+	// be done when all the pods are removed as well.
+	if err := exec.Command("ovs-vsctl", "list-ifaces", "br-int").Run(); err == nil {
+		if out, err := exec.Command("ovs-vsctl", "del-br", "br-int").CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to delete br-int bridge: %s: %w", strings.TrimSpace(string(out)), err)
+		}
+		s.RemovedPaths = append(s.RemovedPaths, "ovs bridge br-int")
+	}
+
+	for _, p := range ovnPaths {
+		if err := removePath(s, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unmountUnder unmounts all the mount points under the path, the nested ones first.
+func unmountUnder(path string) error {
+	mounts, err := mountinfo.GetMounts(mountinfo.PrefixFilter(path))
+	if err != nil {
+		return fmt.Errorf("failed to get mounts under %q: %w", path, err)
+	}
+	sort.Slice(mounts, func(i, j int) bool { return len(mounts[i].Mountpoint) > len(mounts[j].Mountpoint) })
+	for _, m := range mounts {
+		klog.InfoS("Unmounting", "mountpoint", m.Mountpoint)
+		if err := unix.Unmount(m.Mountpoint, 0); err != nil && err != unix.EINVAL && err != unix.ENOENT {
+			return fmt.Errorf("failed to unmount %q: %w", m.Mountpoint, err)
+		}
+	}
+	return nil
+}
+
+// getVolumeGroups returns volume groups used by TopoLVM according to
+// the lvmd config generated by MicroShift.
+func getVolumeGroups(dataDir string) ([]string, error) {
+	exists, err := util.PathExists(lvmd.RuntimeLvmdConfigFile(dataDir))
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, nil
+	}
+	cfg, err := lvmd.NewLvmdConfigFromFile(lvmd.RuntimeLvmdConfigFile(dataDir))
+	if err != nil {
+		return nil, err
+	}
+	vgs := []string{}
+	for _, dc := range cfg.DeviceClasses {
+		if dc != nil && dc.VolumeGroup != "" && !slices.Contains(vgs, dc.VolumeGroup) {
+			vgs = append(vgs, dc.VolumeGroup)
+		}
+	}
+	return vgs, nil
+}
+
+// parseTopoLVMVolumes returns "vg/lv" names of the logical volumes created by TopoLVM
+// from the output of `lvs --reportformat json`.
+func parseTopoLVMVolumes(buf []byte) ([]string, error) {
+	var report struct {
+		Report []struct {
+			LV []struct {
+				Name string `json:"lv_name"`
+				VG   string `json:"vg_name"`
+			} `json:"lv"`
+		} `json:"report"`
+	}
+	if err := json.Unmarshal(buf, &report); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal list of logical volumes: %w", err)
+	}
+	volumes := []string{}
+	for _, r := range report.Report {
+		for _, lv := range r.LV {
+			if topolvmVolumeName.MatchString(lv.Name) {
+				volumes = append(volumes, lv.VG+"/"+lv.Name)
+			}
+		}
+	}
+	return volumes, nil
+}
+
+func removeLogicalVolumes(s *Summary, volumeGroups []string) error {
+	if len(volumeGroups) == 0 {
+		klog.InfoS("TopoLVM is not configured - skipping removal of logical volumes")
+		return nil
+	}
+	if err := lvmd.LvmPresentOnMachine(); err != nil {
+		return err
+	}
+
+	args := append([]string{"--reportformat", "json", "--options", "lv_name,vg_name"}, volumeGroups...)
+	out, err := exec.Command("lvs", args...).Output()
+	if err != nil {
+		return fmt.Errorf("failed to list logical volumes in %v: %w", volumeGroups, err)
+	}
+	volumes, err := parseTopoLVMVolumes(out)
+	if err != nil {
+		return err
+	}
+	// Snapshots are removed before their origin volumes.
+	slices.Reverse(volumes)
+	for _, v := range volumes {
+		klog.InfoS("Removing logical volume", "volume", v)
+		if out, err := exec.Command("lvremove", "--yes", v).CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to remove logical volume %q: %s: %w", v, strings.TrimSpace(string(out)), err)
+		}
+		s.RemovedVolumes = append(s.RemovedVolumes, v)
+	}
+	return nil
+}
+
+func removePath(s *Summary, path string) error {
+	exists, err := util.PathExists(path)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return nil
+	}
+	if err := os.RemoveAll(path); err != nil {
+		return fmt.Errorf("failed to remove %q: %w", path, err)
+	}
+	klog.InfoS("Removed", "path", path)
+	s.RemovedPaths = append(s.RemovedPaths, path)
+	return nil
+}
+
+// removeDataDir removes the data directory except the top level entries listed in keep.
+// If nothing is kept, the directory itself is removed.
+func removeDataDir(dataDir string, keep []string) ([]string, error) {
+	exists, err := util.PathExists(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	if len(keep) == 0 {
+		if err := os.RemoveAll(dataDir); err != nil {
+			return nil, fmt.Errorf("failed to remove MicroShift data: %w", err)
+		}
+		klog.InfoS("Removed", "path", dataDir)
+		return []string{dataDir}, nil
+	}
+
+	entries, err := os.ReadDir(dataDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %q: %w", dataDir, err)
+	}
+	removed := []string{}
+	for _, e := range entries {
+		if slices.Contains(keep, e.Name()) {
+			continue
+		}
+		path := filepath.Join(dataDir, e.Name())
+		if err := os.RemoveAll(path); err != nil {
+			return removed, fmt.Errorf("failed to remove %q: %w", path, err)
+		}
+		klog.InfoS("Removed", "path", path)
+		removed = append(removed, path)
+	}
+	return removed, nil
+}
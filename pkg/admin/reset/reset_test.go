@@ -0,0 +1,88 @@
+package reset
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func pod(id, namespace string) crictlPod {
+	p := crictlPod{ID: id}
+	p.Metadata.Name = id
+	p.Metadata.Namespace = namespace
+	return p
+}
+
+func Test_orderPodsForRemoval(t *testing.T) {
+	pods := []crictlPod{
+		pod("ovnkube-master", ovnNamespace),
+		pod("router", "openshift-ingress"),
+		pod("ovnkube-node", ovnNamespace),
+		pod("app", "default"),
+	}
+
+	ordered := orderPodsForRemoval(pods)
+
+	ids := []string{}
+	for _, p := range ordered {
+		ids = append(ids, p.ID)
+	}
+	assert.Equal(t, []string{"app", "router", "ovnkube-master", "ovnkube-node"}, ids)
+}
+
+func Test_parseTopoLVMVolumes(t *testing.T) {
+	out := `{
+		"report": [
+			{
+				"lv": [
+					{"lv_name":"thin-pool-1", "vg_name":"rhel"},
+					{"lv_name":"root", "vg_name":"rhel"},
+					{"lv_name":"8a3f1c2e-5b7d-4e9f-a1b2-c3d4e5f6a7b8", "vg_name":"rhel"},
+					{"lv_name":"0f0e0d0c-0b0a-0908-0706-050403020100", "vg_name":"microshift"}
+				]
+			}
+		]
+	}`
+
+	volumes, err := parseTopoLVMVolumes([]byte(out))
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"rhel/8a3f1c2e-5b7d-4e9f-a1b2-c3d4e5f6a7b8",
+		"microshift/0f0e0d0c-0b0a-0908-0706-050403020100",
+	}, volumes)
+}
+
+func Test_removeDataDir(t *testing.T) {
+	dataDir := filepath.Join(t.TempDir(), "microshift")
+	for _, d := range []string{"certs/ca", "etcd/member", "resources", "kubelet-plugins"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(dataDir, d), 0700))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dataDir, "version"), []byte("{}"), 0600))
+
+	removed, err := removeDataDir(dataDir, []string{certsDirName})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		filepath.Join(dataDir, "etcd"),
+		filepath.Join(dataDir, "kubelet-plugins"),
+		filepath.Join(dataDir, "resources"),
+		filepath.Join(dataDir, "version"),
+	}, removed)
+	assert.DirExists(t, filepath.Join(dataDir, "certs", "ca"))
+
+	removed, err = removeDataDir(dataDir, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{dataDir}, removed)
+	assert.NoDirExists(t, dataDir)
+
+	removed, err = removeDataDir(dataDir, nil)
+	require.NoError(t, err)
+	assert.Empty(t, removed)
+}
+
+func TestReset_requiresDataDir(t *testing.T) {
+	_, err := Reset(Options{})
+	assert.ErrorContains(t, err, "data directory to reset is not set")
+}
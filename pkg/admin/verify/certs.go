@@ -0,0 +1,144 @@
+package verify
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// verifyCerts parses every certificate (*.crt, *.pem) and key (*.key) file in the
+// certs directory. If the certificate has a matching key in the same directory
+// (e.g. ca.crt and ca.key), it also verifies that they belong together.
+// It returns number of verified files.
+func verifyCerts(certsDir string) (int, error) {
+	errs := []error{}
+	count := 0
+
+	err := filepath.WalkDir(certsDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		rel, _ := filepath.Rel(certsDir, path)
+		switch filepath.Ext(path) {
+		case ".crt", ".pem":
+			count++
+			certs, err := parseCertificates(path)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", rel, err))
+				return nil
+			}
+			keyPath := strings.TrimSuffix(path, filepath.Ext(path)) + ".key"
+			if _, err := os.Stat(keyPath); err == nil {
+				if err := certMatchesKey(certs[0], keyPath); err != nil {
+					errs = append(errs, fmt.Errorf("%s: %w", rel, err))
+				}
+			}
+		case ".key":
+			count++
+			if _, err := parsePrivateKey(path); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", rel, err))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return count, fmt.Errorf("failed to walk %q: %w", certsDir, err)
+	}
+	if count == 0 {
+		return 0, fmt.Errorf("no certificates found in %q", certsDir)
+	}
+
+	return count, errors.Join(errs...)
+}
+
+func parseCertificates(path string) ([]*x509.Certificate, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	certs := []*x509.Certificate{}
+	for {
+		var block *pem.Block
+		block, contents = pem.Decode(contents)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no PEM encoded certificates found")
+	}
+	return certs, nil
+}
+
+func parsePrivateKey(path string) (crypto.Signer, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(contents)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM encoded key found")
+	}
+
+	var key any
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	return signer, nil
+}
+
+func certMatchesKey(cert *x509.Certificate, keyPath string) error {
+	key, err := parsePrivateKey(keyPath)
+	if err != nil {
+		return fmt.Errorf("failed to parse matching key %q: %w", filepath.Base(keyPath), err)
+	}
+
+	type publicKey interface {
+		Equal(crypto.PublicKey) bool
+	}
+	pub, ok := key.Public().(publicKey)
+	if !ok {
+		return fmt.Errorf("unsupported public key type %s", reflect.TypeOf(key.Public()))
+	}
+	if !pub.Equal(cert.PublicKey) {
+		return fmt.Errorf("certificate does not match the key %q", filepath.Base(keyPath))
+	}
+	return nil
+}
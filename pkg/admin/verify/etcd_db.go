@@ -0,0 +1,69 @@
+package verify
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// EtcdDBStatus contains the same information as `etcdutl snapshot status`.
+type EtcdDBStatus struct {
+	// Hash is a CRC-32C of all the buckets' names, keys, and values.
+	Hash      uint32 `json:"hash"`
+	Revision  int64  `json:"revision"`
+	TotalKey  int    `json:"totalKey"`
+	TotalSize int64  `json:"totalSize"`
+	// TxID is the ID of the last committed transaction of the bbolt database.
+	TxID uint64 `json:"txid"`
+}
+
+// GetEtcdDBStatus opens etcd's database in read-only mode, checks its consistency
+// and traverses all the buckets to compute the hash and the revision.
+func GetEtcdDBStatus(path string) (EtcdDBStatus, error) {
+	db, err := bolt.Open(path, 0400, &bolt.Options{ReadOnly: true, Timeout: time.Second})
+	if err != nil {
+		return EtcdDBStatus{}, fmt.Errorf("failed to open %q: %w", path, err)
+	}
+	defer db.Close()
+
+	status := EtcdDBStatus{}
+	h := crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	err = db.View(func(tx *bolt.Tx) error {
+		checkErrs := []error{}
+		for err := range tx.Check() {
+			checkErrs = append(checkErrs, err)
+		}
+		if err := errors.Join(checkErrs...); err != nil {
+			return fmt.Errorf("database is inconsistent: %w", err)
+		}
+
+		status.TotalSize = tx.Size()
+		status.TxID = uint64(tx.ID())
+		// Top level buckets are etcd's buckets, the same hash is computed by etcdutl.
+		return tx.ForEach(func(bucket []byte, b *bolt.Bucket) error {
+			_, _ = h.Write(bucket)
+			isKeyBucket := string(bucket) == "key"
+			return b.ForEach(func(k, v []byte) error {
+				_, _ = h.Write(k)
+				_, _ = h.Write(v)
+				if isKeyBucket {
+					if len(k) < 8 {
+						return fmt.Errorf("invalid revision key %x in bucket %q", k, bucket)
+					}
+					status.Revision = int64(binary.BigEndian.Uint64(k[0:8]))
+				}
+				status.TotalKey++
+				return nil
+			})
+		})
+	})
+	if err != nil {
+		return EtcdDBStatus{}, fmt.Errorf("%q: %w", path, err)
+	}
+	status.Hash = h.Sum32()
+	return status, nil
+}
@@ -0,0 +1,92 @@
+package verify
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/openshift/microshift/pkg/admin/check"
+	"github.com/openshift/microshift/pkg/admin/data"
+	"github.com/openshift/microshift/pkg/admin/prerun"
+	"k8s.io/klog/v2"
+)
+
+// Report lists results of all checks performed on a backup.
+type Report struct {
+	Path   string        `json:"path"`
+	Checks check.Results `json:"checks"`
+}
+
+// VerifyBackup checks if the backup (a directory or an archive) can be restored
+// without actually restoring it: its structure, integrity of etcd database,
+// parsability of certificates, and compatibility with the MicroShift executable.
+// Archives are extracted next to the data directory, like restore does, because
+// /tmp is often a tmpfs. Encrypted backups require the key.
+// Returned error means that the verification itself could not be performed.
+func VerifyBackup(path, dataDir string, key []byte) (*Report, error) {
+	report := &Report{Path: path}
+
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("failed to access backup %q: %w", path, err)
+	}
+
+	archive := ""
+	if isArchive, err := data.IsArchive(path); err != nil {
+		return nil, err
+	} else if isArchive {
+		archive = path
+	} else if isEncrypted, err := data.IsEncryptedBackupDir(path); err != nil {
+		return nil, err
+	} else if isEncrypted {
+		archive = filepath.Join(path, data.EncryptedBackupFileName)
+	}
+
+	dir := path
+	if archive != "" {
+		manifest, err := data.ReadArchiveManifest(archive, key)
+		if err != nil {
+			report.Checks.Add("archive", err, "")
+			return report, nil
+		}
+		if err := data.CheckIfEnoughSpaceToExtract(manifest, dataDir); err != nil {
+			return nil, err
+		}
+
+		dir, err = data.GenerateUniqueTempPath(dataDir)
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			if err := os.RemoveAll(dir); err != nil {
+				klog.ErrorS(err, "Failed to remove temporary directory", "path", dir)
+			}
+		}()
+
+		err = data.ExtractArchive(archive, dir, key)
+		report.Checks.Add("archive", err, "all files match the manifest")
+		if err != nil {
+			return report, nil
+		}
+	}
+
+	err := data.IsMicroShiftBackup(dir)
+	report.Checks.Add("structure", err, "all expected subdirs exist")
+	if err != nil {
+		return report, nil
+	}
+
+	status, err := GetEtcdDBStatus(filepath.Join(dir, data.EtcdSnapshotDBPath))
+	if err == nil && status.Revision == 0 {
+		err = fmt.Errorf("etcd database does not contain any revision")
+	}
+	report.Checks.Add("etcd", err, fmt.Sprintf("hash: %d, revision: %d, total keys: %d, total size: %d",
+		status.Hash, status.Revision, status.TotalKey, status.TotalSize))
+
+	count, err := verifyCerts(filepath.Join(dir, "certs"))
+	report.Checks.Add("certs", err, fmt.Sprintf("%d certificates and keys are valid", count))
+
+	err = prerun.CheckVersionFileCompatibility(filepath.Join(dir, "version"))
+	report.Checks.Add("version", err, "backup is compatible with MicroShift executable")
+
+	return report, nil
+}
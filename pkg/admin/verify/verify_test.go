@@ -0,0 +1,119 @@
+package verify
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func writeCertAndKey(t *testing.T, dir, name string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name+".crt"),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name+".key"),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+}
+
+func Test_verifyCerts(t *testing.T) {
+	t.Run("Valid certificates", func(t *testing.T) {
+		dir := t.TempDir()
+		writeCertAndKey(t, dir, "ca")
+		require.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0700))
+		writeCertAndKey(t, filepath.Join(dir, "sub"), "server")
+
+		count, err := verifyCerts(dir)
+		assert.NoError(t, err)
+		assert.Equal(t, 4, count)
+	})
+
+	t.Run("Truncated certificate", func(t *testing.T) {
+		dir := t.TempDir()
+		writeCertAndKey(t, dir, "ca")
+		contents, err := os.ReadFile(filepath.Join(dir, "ca.crt"))
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "ca.crt"), contents[:len(contents)/2], 0600))
+
+		_, err = verifyCerts(dir)
+		assert.ErrorContains(t, err, "ca.crt")
+	})
+
+	t.Run("Mismatched key", func(t *testing.T) {
+		dir := t.TempDir()
+		writeCertAndKey(t, dir, "ca")
+		writeCertAndKey(t, dir, "other")
+		require.NoError(t, os.Rename(filepath.Join(dir, "other.key"), filepath.Join(dir, "ca.key")))
+
+		_, err := verifyCerts(dir)
+		assert.ErrorContains(t, err, "does not match")
+	})
+
+	t.Run("No certificates", func(t *testing.T) {
+		_, err := verifyCerts(t.TempDir())
+		assert.Error(t, err)
+	})
+}
+
+func Test_GetEtcdDBStatus(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db")
+	db, err := bolt.Open(path, 0600, nil)
+	require.NoError(t, err)
+	require.NoError(t, db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucket([]byte("key"))
+		if err != nil {
+			return err
+		}
+		for rev := uint64(1); rev <= 3; rev++ {
+			k := make([]byte, 17)
+			binary.BigEndian.PutUint64(k, rev)
+			if err := b.Put(k, []byte("value")); err != nil {
+				return err
+			}
+		}
+		_, err = tx.CreateBucket([]byte("meta"))
+		return err
+	}))
+	require.NoError(t, db.Close())
+
+	status, err := GetEtcdDBStatus(path)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), status.Revision)
+	assert.Equal(t, 3, status.TotalKey)
+	assert.NotZero(t, status.Hash)
+	assert.NotZero(t, status.TxID)
+
+	// Corrupting the meta pages makes the database unreadable.
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	for i := 16; i < 64; i++ {
+		data[i] = 0xff
+		data[4096+i] = 0xff
+	}
+	require.NoError(t, os.WriteFile(path, data, 0600))
+	_, err = GetEtcdDBStatus(path)
+	assert.Error(t, err)
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -9,6 +10,8 @@ import (
 
 	"github.com/openshift/microshift/pkg/admin/autorecovery"
 	"github.com/openshift/microshift/pkg/admin/data"
+	"github.com/openshift/microshift/pkg/admin/prerun"
+	"github.com/openshift/microshift/pkg/config"
 	"github.com/openshift/microshift/pkg/util"
 
 	"github.com/spf13/cobra"
@@ -45,6 +48,23 @@ func servicesShouldBeInactive(backingUp bool) error {
 	return nil
 }
 
+func etcdShouldBeActive() error {
+	service := "microshift-etcd.scope"
+	cmd := exec.Command("systemctl", "show", "-p", "ActiveState", "--value", service)
+	out, err := cmd.CombinedOutput()
+	state := strings.TrimSpace(string(out))
+	if err != nil {
+		return fmt.Errorf("error when checking if %q is active: %w", service, err)
+	}
+
+	if state != "active" {
+		return fmt.Errorf("MicroShift must be running to create an online backup (%q is %q, should be %q)",
+			service, state, "active")
+	}
+
+	return nil
+}
+
 func checkPathExistence(path string, shouldExist bool) error {
 	exists, err := util.PathExists(path)
 	if err != nil {
@@ -102,8 +122,21 @@ func backupRestorePreRun(backingUp bool) func(*cobra.Command, []string) error {
 			return err
 		}
 
-		if err := servicesShouldBeInactive(backingUp); err != nil {
+		// Dry run doesn't modify anything, so MicroShift doesn't need to be stopped.
+		dryRun, err := isDryRun(cmd)
+		if err != nil {
+			return err
+		}
+		if online, err := isOnlineBackup(cmd); err != nil {
 			return err
+		} else if online {
+			if err := etcdShouldBeActive(); err != nil {
+				return err
+			}
+		} else if !dryRun {
+			if err := servicesShouldBeInactive(backingUp); err != nil {
+				return err
+			}
 		}
 
 		if autorec, err := cmd.Flags().GetBool("auto-recovery"); err != nil {
@@ -115,7 +148,7 @@ func backupRestorePreRun(backingUp bool) func(*cobra.Command, []string) error {
 		}
 
 		path := args[0]
-		_, _, err := backupPathToStorageAndName(path)
+		_, _, err = backupPathToStorageAndName(path)
 		if err != nil {
 			return err
 		}
@@ -129,6 +162,30 @@ func backupRestorePreRun(backingUp bool) func(*cobra.Command, []string) error {
 	}
 }
 
+// isOnlineBackup returns true if the command has `--online` flag and it is set.
+func isOnlineBackup(cmd *cobra.Command) (bool, error) {
+	if cmd.Flags().Lookup("online") == nil {
+		return false, nil
+	}
+	online, err := cmd.Flags().GetBool("online")
+	if err != nil {
+		return false, fmt.Errorf("failed to get `online` flag: %w", err)
+	}
+	return online, nil
+}
+
+// isDryRun returns true if the command has `--dry-run` flag and it is set.
+func isDryRun(cmd *cobra.Command) (bool, error) {
+	if cmd.Flags().Lookup("dry-run") == nil {
+		return false, nil
+	}
+	dryRun, err := cmd.Flags().GetBool("dry-run")
+	if err != nil {
+		return false, fmt.Errorf("failed to get `dry-run` flag: %w", err)
+	}
+	return dryRun, nil
+}
+
 func validateArgs(cmd *cobra.Command, args []string) error {
 	var err error
 	if len(args) == 0 {
@@ -149,8 +206,75 @@ func validateArgs(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// loadEncryptionKey loads the key from the file specified with --encrypt-key-file.
+// Empty path means that the encryption is not requested and nil key is returned.
+func loadEncryptionKey(path, dataDir string) ([]byte, error) {
+	if path == "" {
+		return nil, nil
+	}
+	return data.LoadEncryptionKey(path, dataDir)
+}
+
+func addEncryptKeyFileFlag(cmd *cobra.Command, path *string, usage string) {
+	cmd.Flags().StringVar(path, "encrypt-key-file", "", usage+`
+The file must contain 32 bytes, either raw or base64 encoded
+(for example, created with "openssl rand -base64 32"),
+must be accessible only by its owner, and must be stored outside
+of MicroShift's data directory.`)
+}
+
+type backupOptions struct {
+	online bool
+	format data.BackupFormat
+	// encryptionKey, if not nil, is used to encrypt the backup.
+	encryptionKey []byte
+	deduplicate   bool
+}
+
+func (o backupOptions) validate() error {
+	if o.deduplicate && (o.online || o.format != data.BackupFormatDir || o.encryptionKey != nil) {
+		return fmt.Errorf("--deduplicate cannot be used with --online, --format=%s, or --encrypt-key-file", data.BackupFormatTarGz)
+	}
+	return nil
+}
+
+// createBackup creates a backup using requested method and format.
+func createBackup(storage data.StoragePath, name data.BackupName, paths config.Paths, opts backupOptions) (string, error) {
+	managerOpts := []data.ManagerOption{
+		data.WithDataDir(paths.DataDir),
+		data.WithEncryptionKey(opts.encryptionKey),
+		data.WithHooks(paths.BackupHooksDir(), data.DefaultHookTimeout),
+	}
+	if opts.deduplicate {
+		managerOpts = append(managerOpts, data.WithDeduplication())
+	}
+	dataManager, err := data.NewManager(storage, managerOpts...)
+	if err != nil {
+		return "", err
+	}
+
+	online, format := opts.online, opts.format
+	if format == data.BackupFormatDir {
+		if online {
+			return dataManager.BackupOnline(name)
+		}
+		return dataManager.Backup(name)
+	}
+
+	if !online {
+		// MicroShift is stopped, so the data directory can be archived directly.
+		return dataManager.BackupArchive(paths.DataDir, name)
+	}
+	return dataManager.BackupOnlineArchive(name)
+}
+
 func NewBackupCommand() *cobra.Command {
 	autorec := false
+	online := false
+	formatStr := string(data.BackupFormatDir)
+	retention := retentionOptions{}
+	keyFile := ""
+	deduplicate := false
 
 	cmd := &cobra.Command{
 		Use:               "backup PATH",
@@ -160,33 +284,61 @@ func NewBackupCommand() *cobra.Command {
 		PersistentPreRunE: backupRestorePreRun(true),
 
 		RunE: func(cmd *cobra.Command, args []string) error {
+			format, err := data.ParseBackupFormat(formatStr)
+			if err != nil {
+				return err
+			}
+
+			policy, err := retention.Policy()
+			if err != nil {
+				return err
+			}
+			if !autorec && retention.changed(cmd.Flags()) {
+				return fmt.Errorf("--keep-last, --max-age, and --max-size can only be used with --auto-recovery")
+			}
+
+			paths := config.PathsFromFlags(cmd.Flags())
+			key, err := loadEncryptionKey(keyFile, paths.DataDir)
+			if err != nil {
+				return err
+			}
+
+			opts := backupOptions{online: online, format: format, encryptionKey: key, deduplicate: deduplicate}
+			if err := opts.validate(); err != nil {
+				return err
+			}
+
 			// err is checked in PersistentPreRunE
 			storage, name, _ := backupPathToStorageAndName(args[0])
 
 			if autorec {
+				if format != data.BackupFormatDir {
+					return fmt.Errorf("--format=%s cannot be used with --auto-recovery", format)
+				}
 				// For auto-recovery mode we treat given path as a directory where the backup subdirectory will be created.
 				// Normally it's interpreted as final destination.
 				storage = data.StoragePath(args[0])
 				if err := autorecovery.CreateStorageIfAbsent(storage); err != nil {
 					return err
 				}
-				var err error
 				name, err = autorecovery.GetBackupName()
 				if err != nil {
 					return err
 				}
 			}
 
-			dataManager, err := data.NewManager(storage)
+			backupPath, err := createBackup(storage, name, paths, opts)
 			if err != nil {
 				return err
 			}
+			fmt.Printf("%s\n", backupPath)
 
-			backupPath, err := dataManager.Backup(name)
-			if err != nil {
-				return err
+			if autorec {
+				// Backup was created successfully, so failure to prune old backups is only reported.
+				if _, err := autorecovery.Prune(storage, paths.DataDir, policy, name); err != nil {
+					fmt.Fprintf(cmd.ErrOrStderr(), "WARNING: Failed to prune old auto-recovery backups: %v\n", err)
+				}
 			}
-			fmt.Printf("%s\n", backupPath)
 			return nil
 		},
 	}
@@ -196,39 +348,142 @@ func NewBackupCommand() *cobra.Command {
 The PATH argument will be treated as a directory where backups are
 created using the naming scheme compatible with "restore --auto-recovery"`)
 
+	cmd.Flags().BoolVar(&online, "online", false,
+		`Create a backup while MicroShift is running.
+Instead of copying etcd's data directory, a consistent snapshot
+of the database is obtained from the running etcd.`)
+
+	cmd.Flags().StringVar(&formatStr, "format", formatStr,
+		`Format of the backup. One of "dir" or "tar.gz".
+The "tar.gz" creates a single compressed file at PATH which contains
+a manifest with SHA-256 checksums of all the files.
+The "restore" command accepts such archives directly.`)
+
+	addEncryptKeyFileFlag(cmd, &keyFile,
+		`Encrypt the backup with AES-256-GCM using the key from the file.
+Unless --format=tar.gz is used, the backup is a directory containing
+a single encrypted archive.`)
+
+	cmd.Flags().BoolVar(&deduplicate, "deduplicate", false,
+		`Store files which did not change since previous backups only once.
+Files of the backup are hard links to a content-addressed pool
+of files shared by all the backups in the same directory.`)
+
+	retention.AddFlags(cmd.Flags())
+
+	cmd.AddCommand(NewBackupVerifyCommand())
+	cmd.AddCommand(NewBackupPruneCommand())
+	cmd.AddCommand(NewBackupListCommand())
+	cmd.AddCommand(NewBackupInspectCommand())
+
 	return cmd
 }
 
+// checkBackupCompatibility refuses to restore the backup whose version is not
+// compatible with the installed MicroShift, unless forced. It uses the same rules
+// as MicroShift's startup, which would otherwise fail only after the restore.
+func checkBackupCompatibility(path string, key []byte, force bool) error {
+	info, err := data.GetBackupInfo(path, key)
+	if err != nil {
+		return err
+	}
+
+	if info.Version == "" {
+		err = fmt.Errorf("failed to read version of the backup: %s", info.Error)
+	} else if err = prerun.CheckDataVersionCompatibility(info.Version); err != nil {
+		var incompatibleErr *prerun.IncompatibleVersionError
+		if !errors.As(err, &incompatibleErr) {
+			return err
+		}
+	}
+	if err == nil {
+		return nil
+	}
+
+	if force {
+		fmt.Fprintf(os.Stderr, "WARNING: Restoring backup %q despite incompatibility: %v\n", path, err)
+		return nil
+	}
+	return fmt.Errorf("backup %q (version %s) is not compatible with installed MicroShift: %w - use --force to restore it anyway",
+		path, valueOrNone(info.Version), err)
+}
+
 func NewRestoreCommand() *cobra.Command {
 	autorec := false
 	dontSaveFailed := false
+	keyFile := ""
+	only := []string{}
+	dryRun := false
+	force := false
 
 	cmd := &cobra.Command{
 		Use:               "restore PATH",
 		Short:             "Restore MicroShift data from a backup",
+		Long:              "Restore MicroShift data from a backup. PATH can be a backup directory or an archive created with \"backup --format\".",
 		Args:              validateArgs,
 		PersistentPreRunE: backupRestorePreRun(false),
 
 		RunE: func(cmd *cobra.Command, args []string) error {
+			paths := config.PathsFromFlags(cmd.Flags())
+			key, err := loadEncryptionKey(keyFile, paths.DataDir)
+			if err != nil {
+				return err
+			}
+
+			var components []data.Component
+			if f := cmd.Flag("only"); f != nil && f.Changed {
+				if autorec {
+					return fmt.Errorf("--only cannot be used with --auto-recovery")
+				}
+				components, err = data.ParseComponents(only)
+				if err != nil {
+					return err
+				}
+			}
+
+			if autorec && force {
+				return fmt.Errorf("--force cannot be used with --auto-recovery")
+			}
+
 			if autorec {
-				acManager, err := autorecovery.NewManager(data.StoragePath(args[0]), !dontSaveFailed)
+				acManager, err := autorecovery.NewManager(data.StoragePath(args[0]), paths.DataDir, !dontSaveFailed, key,
+					data.WithHooks(paths.BackupHooksDir(), data.DefaultHookTimeout))
 				if err != nil {
 					return err
 				}
+				if dryRun {
+					plan, err := acManager.PlanRestore()
+					if plan != nil {
+						printRestorePlan(cmd.OutOrStdout(), plan)
+					}
+					return err
+				}
 				return acManager.PerformRestore()
 			}
 
+			if dryRun {
+				return fmt.Errorf("--dry-run cannot be used without --auto-recovery")
+			}
+
+			if err := checkBackupCompatibility(args[0], key, force); err != nil {
+				return err
+			}
+
 			if f := cmd.Flag("dont-save-failed"); f != nil && f.Changed {
 				return fmt.Errorf("--dont-save-failed cannot be used without --auto-recovery")
 			}
 
 			// err is checked in PersistentPreRunE
 			storage, name, _ := backupPathToStorageAndName(args[0])
-			dataManager, err := data.NewManager(storage)
+			dataManager, err := data.NewManager(storage, data.WithDataDir(paths.DataDir), data.WithEncryptionKey(key),
+				data.WithHooks(paths.BackupHooksDir(), data.DefaultHookTimeout))
 			if err != nil {
 				return err
 			}
 
+			if components != nil {
+				return dataManager.RestoreComponents(name, components)
+			}
 			return dataManager.Restore(name)
 		},
 	}
@@ -243,5 +498,28 @@ created with "backup --auto-recovery" command.`)
 Don't make a copy of MicroShift data directory inside
 "failed" subdirectory for later analysis.`)
 
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false,
+		`Only applicable if --auto-recovery is also specified.
+Print the backup that would be restored and why, skipped backups,
+disk space checks, and file system operations without performing them.
+MicroShift doesn't need to be stopped.`)
+
+	cmd.Flags().BoolVar(&force, "force", false,
+		`Restore the backup even if its version is not compatible with
+the installed MicroShift (unsupported version skew or blocked upgrade).
+MicroShift will refuse to start with such data.`)
+
+	cmd.Flags().StringSliceVar(&only, "only", only,
+		fmt.Sprintf(`Restore only the given components of the backup and keep the rest
+of the data directory intact. Supported components: %v.
+"certs" and "resources" (which contains kubeconfigs) must be restored together.
+The backup must be of the same version as the existing data.`, data.Components))
+
+	addEncryptKeyFileFlag(cmd, &keyFile,
+		`Decrypt the backup using the key from the file.
+Restoring fails if the encrypted backup was modified or truncated.
+With --auto-recovery, the copy of the data inside "failed"
+subdirectory is also encrypted.`)
+
 	return cmd
 }
@@ -1,10 +1,13 @@
 package cmd
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/openshift/microshift/pkg/admin/data"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func Test_backupPathToStorageAndName(t *testing.T) {
@@ -97,3 +100,28 @@ func Test_backupPathToStorageAndName(t *testing.T) {
 		})
 	}
 }
+
+func Test_checkBackupCompatibility(t *testing.T) {
+	testData := []struct {
+		name        string
+		versionFile string
+	}{
+		{name: "malformed version", versionFile: `{"version":"4.18"}`},
+		{name: "non-numeric version", versionFile: "4.x.0"},
+		{name: "unreadable version", versionFile: ""},
+	}
+
+	for _, td := range testData {
+		t.Run(td.name, func(t *testing.T) {
+			backup := t.TempDir()
+			for _, dir := range []string{"certs", "etcd", "kubelet-plugins", "resources"} {
+				require.NoError(t, os.Mkdir(filepath.Join(backup, dir), 0700))
+			}
+			require.NoError(t, os.WriteFile(filepath.Join(backup, "version"), []byte(td.versionFile), 0600))
+
+			err := checkBackupCompatibility(backup, nil, false)
+			assert.ErrorContains(t, err, "use --force to restore it anyway")
+			assert.NoError(t, checkBackupCompatibility(backup, nil, true))
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/openshift/microshift/pkg/admin/autorecovery"
+	"github.com/openshift/microshift/pkg/admin/data"
+	"github.com/openshift/microshift/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+func validateListOutput(output string) error {
+	if output != "" && output != "json" {
+		return fmt.Errorf("unsupported output format %q, supported formats: json", output)
+	}
+	return nil
+}
+
+func NewBackupListCommand() *cobra.Command {
+	output := ""
+	keyFile := ""
+
+	cmd := &cobra.Command{
+		Use:   "list [PATH]",
+		Short: "List MicroShift backups",
+		Long: fmt.Sprintf(`List MicroShift backups stored in the PATH directory
+(default: the backups directory, %q unless overridden with --%s).
+Auto-recovery backup storages are supported as well.`, config.DefaultBackupsDir, config.FlagBackupsDir),
+		Args: cobra.MaximumNArgs(1),
+		// Override backup's PersistentPreRunE: listing doesn't
+		// require MicroShift to be stopped.
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return shouldRunPrivileged()
+		},
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateListOutput(output); err != nil {
+				return err
+			}
+			paths := config.PathsFromFlags(cmd.Flags())
+			key, err := loadEncryptionKey(keyFile, paths.DataDir)
+			if err != nil {
+				return err
+			}
+
+			storage := data.StoragePath(paths.BackupsDir)
+			if len(args) == 1 {
+				storage = data.StoragePath(args[0])
+			}
+
+			backups, skipped, err := data.ListBackups(storage, key)
+			if err != nil {
+				return err
+			}
+			for _, s := range skipped {
+				if !autorecovery.IsStorageEntry(s.Name) {
+					fmt.Fprintf(cmd.ErrOrStderr(), "WARNING: Skipped %q: %v\n", s.Name, s.Err)
+				}
+			}
+			if err := completeBackupInfo(storage, backups...); err != nil {
+				return err
+			}
+			// Sorted after completing the info, because it may change the creation time.
+			slices.SortStableFunc(backups, func(a, b *data.BackupInfo) int {
+				return b.CreationTime.Compare(a.CreationTime)
+			})
+
+			if output == "json" {
+				return printJSON(cmd.OutOrStdout(), backups)
+			}
+			printBackupTable(cmd.OutOrStdout(), backups)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", output, "Output format. One of: json. Default is a table.")
+	addEncryptKeyFileFlag(cmd, &keyFile, "Read metadata of encrypted backups using the key from the file.")
+
+	return cmd
+}
+
+func NewBackupInspectCommand() *cobra.Command {
+	output := ""
+	keyFile := ""
+
+	cmd := &cobra.Command{
+		Use:   "inspect PATH",
+		Short: "Show metadata of a MicroShift backup",
+		Args:  validateArgs,
+		// Override backup's PersistentPreRunE: inspecting doesn't
+		// require MicroShift to be stopped.
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return shouldRunPrivileged()
+		},
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateListOutput(output); err != nil {
+				return err
+			}
+			paths := config.PathsFromFlags(cmd.Flags())
+			key, err := loadEncryptionKey(keyFile, paths.DataDir)
+			if err != nil {
+				return err
+			}
+
+			path := filepath.Clean(args[0])
+			info, err := data.GetBackupInfo(path, key)
+			if err != nil {
+				return err
+			}
+			if err := completeBackupInfo(data.StoragePath(filepath.Dir(path)), info); err != nil {
+				return err
+			}
+
+			if output == "json" {
+				return printJSON(cmd.OutOrStdout(), info)
+			}
+			printBackupInfo(cmd.OutOrStdout(), info)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", output, "Output format. One of: json. Default is a human readable list.")
+	addEncryptKeyFileFlag(cmd, &keyFile, "Read metadata of encrypted backup using the key from the file.")
+
+	return cmd
+}
+
+// completeBackupInfo fills in the information from the auto-recovery storage:
+// whether the backup is the LastBackup and creation time from the backup's name.
+func completeBackupInfo(storage data.StoragePath, backups ...*data.BackupInfo) error {
+	state, err := autorecovery.GetState(storage)
+	if err != nil {
+		return err
+	}
+
+	for _, b := range backups {
+		if state != nil && data.BackupName(b.Name) == state.LastBackup {
+			b.LastBackup = true
+		}
+		if b.Type == data.BackupTypeDir {
+			if arBackup, err := autorecovery.ParseBackupName(b.Name); err == nil {
+				b.CreationTime = arBackup.LocalCreationTime()
+			}
+		}
+	}
+	return nil
+}
+
+func printJSON(out io.Writer, v any) error {
+	marshalled, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out, string(marshalled))
+	return nil
+}
+
+func valueOrNone(s string) string {
+	if s == "" {
+		return "<none>"
+	}
+	return s
+}
+
+func printBackupTable(out io.Writer, backups []*data.BackupInfo) {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "NAME\tTYPE\tCREATED\tSIZE\tVERSION\tDEPLOYMENT ID\tBOOT ID\tLAST BACKUP\n")
+	for _, b := range backups {
+		last := ""
+		if b.LastBackup {
+			last = "*"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			b.Name, b.Type, b.CreationTime.Format(time.DateTime), units.BytesSize(float64(b.Size)),
+			valueOrNone(b.Version), valueOrNone(b.DeploymentID), valueOrNone(b.BootID), last)
+	}
+	_ = w.Flush()
+}
+
+func printBackupInfo(out io.Writer, b *data.BackupInfo) {
+	w := tabwriter.NewWriter(out, 0, 4, 1, ' ', 0)
+	fmt.Fprintf(w, "Name:\t%s\n", b.Name)
+	fmt.Fprintf(w, "Path:\t%s\n", b.Path)
+	fmt.Fprintf(w, "Type:\t%s\n", b.Type)
+	fmt.Fprintf(w, "Created:\t%s\n", b.CreationTime.Format(time.RFC3339))
+	fmt.Fprintf(w, "Size:\t%s\n", units.BytesSize(float64(b.Size)))
+	fmt.Fprintf(w, "Data size:\t%s\n", units.BytesSize(float64(b.DataSize)))
+	fmt.Fprintf(w, "Files:\t%d\n", b.Files)
+	fmt.Fprintf(w, "Version:\t%s\n", valueOrNone(b.Version))
+	fmt.Fprintf(w, "Deployment ID:\t%s\n", valueOrNone(b.DeploymentID))
+	fmt.Fprintf(w, "Boot ID:\t%s\n", valueOrNone(b.BootID))
+	fmt.Fprintf(w, "Deduplicated:\t%t\n", b.Deduplicated)
+	fmt.Fprintf(w, "Auto-recovery last backup:\t%t\n", b.LastBackup)
+	if len(b.Hooks) != 0 {
+		fmt.Fprintf(w, "Hooks:\t\n")
+		for _, h := range b.Hooks {
+			result := "succeeded"
+			if h.Error != "" {
+				result = "failed: " + h.Error
+			}
+			fmt.Fprintf(w, "  %s/%s:\t%s in %s\n", h.Stage, h.Hook, result, h.Duration)
+		}
+	}
+	if b.Error != "" {
+		fmt.Fprintf(w, "Error:\t%s\n", b.Error)
+	}
+	_ = w.Flush()
+}
@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/openshift/microshift/pkg/config"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupListCommand_writesToCommandOutput(t *testing.T) {
+	listCmd := NewBackupListCommand()
+	listCmd.PersistentPreRunE = nil
+
+	root := &cobra.Command{Use: "microshift"}
+	config.AddPathFlags(root.PersistentFlags())
+	root.AddCommand(listCmd)
+	out := &bytes.Buffer{}
+	root.SetOut(out)
+	root.SetArgs([]string{"list", t.TempDir(), "--" + config.FlagDataDir, t.TempDir()})
+
+	require.NoError(t, root.Execute())
+	assert.Contains(t, out.String(), "NAME")
+}
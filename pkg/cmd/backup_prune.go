@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openshift/microshift/pkg/admin/autorecovery"
+	"github.com/openshift/microshift/pkg/admin/data"
+	"github.com/openshift/microshift/pkg/config"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+type retentionOptions struct {
+	keepLast int
+	maxAge   string
+	maxSize  string
+}
+
+func (o *retentionOptions) AddFlags(fs *pflag.FlagSet) {
+	fs.IntVar(&o.keepLast, "keep-last", 0,
+		"Number of the most recent auto-recovery backups to keep in each storage (0 means no limit)")
+	fs.StringVar(&o.maxAge, "max-age", "",
+		`Maximum age of auto-recovery backups, for example "36h" or "30d" (empty means no limit)`)
+	fs.StringVar(&o.maxSize, "max-size", "",
+		`Maximum total size of auto-recovery backups in each storage, for example "10Gi" (empty means no limit)`)
+}
+
+func (o *retentionOptions) changed(fs *pflag.FlagSet) bool {
+	return fs.Changed("keep-last") || fs.Changed("max-age") || fs.Changed("max-size")
+}
+
+func (o *retentionOptions) Policy() (autorecovery.RetentionPolicy, error) {
+	policy := autorecovery.RetentionPolicy{KeepLast: o.keepLast}
+
+	if o.maxAge != "" {
+		age, err := parseDuration(o.maxAge)
+		if err != nil {
+			return policy, fmt.Errorf("invalid --max-age %q: %w", o.maxAge, err)
+		}
+		policy.MaxAge = age
+	}
+
+	if o.maxSize != "" {
+		size, err := resource.ParseQuantity(o.maxSize)
+		if err != nil {
+			return policy, fmt.Errorf("invalid --max-size %q: %w", o.maxSize, err)
+		}
+		if size.Sign() < 0 {
+			return policy, fmt.Errorf("invalid --max-size %q: size cannot be negative", o.maxSize)
+		}
+		policy.MaxTotalSize = uint64(size.Value())
+	}
+
+	return policy, policy.Validate()
+}
+
+// parseDuration extends time.ParseDuration with days, e.g. "30d".
+func parseDuration(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+func NewBackupPruneCommand() *cobra.Command {
+	opts := retentionOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "prune PATH",
+		Short: "Remove old auto-recovery backups",
+		Long: `Remove auto-recovery backups exceeding the retention limits.
+PATH is a directory holding the auto-recovery backups. The limits are applied
+separately to the PATH and its "failed" and "restored" subdirectories.
+The backup that was restored most recently is never removed.`,
+		Args: validateArgs,
+		// Override backup's PersistentPreRunE: pruning doesn't
+		// require MicroShift to be stopped.
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return shouldRunPrivileged()
+		},
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			policy, err := opts.Policy()
+			if err != nil {
+				return err
+			}
+			if policy.IsEmpty() {
+				return fmt.Errorf("at least one of --keep-last, --max-age, or --max-size must be provided")
+			}
+
+			paths := config.PathsFromFlags(cmd.Flags())
+			removed, err := autorecovery.Prune(data.StoragePath(args[0]), paths.DataDir, policy)
+			for _, r := range removed {
+				fmt.Printf("Removed %s\n", r)
+			}
+			return err
+		},
+	}
+
+	opts.AddFlags(cmd.Flags())
+
+	return cmd
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/openshift/microshift/pkg/admin/check"
+	"github.com/openshift/microshift/pkg/admin/verify"
+	"github.com/openshift/microshift/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+func NewBackupVerifyCommand() *cobra.Command {
+	keyFile := ""
+
+	cmd := &cobra.Command{
+		Use:   "verify PATH",
+		Short: "Verify integrity of a MicroShift backup",
+		Long: `Verify integrity of a MicroShift backup without restoring it.
+PATH can be a backup directory or an archive created with "backup --format".
+The command checks that the backup contains all the expected data,
+that the etcd database is not corrupted, that all the certificates and keys
+can be parsed, and that the backup is compatible with the installed MicroShift.`,
+		Args: validateArgs,
+		// Override backup's PersistentPreRunE: verification doesn't
+		// require MicroShift to be stopped.
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return shouldRunPrivileged()
+		},
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			paths := config.PathsFromFlags(cmd.Flags())
+			key, err := loadEncryptionKey(keyFile, paths.DataDir)
+			if err != nil {
+				return err
+			}
+
+			report, err := verify.VerifyBackup(args[0], paths.DataDir, key)
+			if err != nil {
+				return err
+			}
+			printCheckResults(cmd.OutOrStdout(), report.Checks)
+			if !report.Checks.Passed() {
+				return fmt.Errorf("backup %q failed verification", args[0])
+			}
+			return nil
+		},
+	}
+
+	addEncryptKeyFileFlag(cmd, &keyFile, "Decrypt the backup using the key from the file.")
+
+	return cmd
+}
+
+// printCheckResults prints a table of the checks and their outcomes.
+func printCheckResults(out io.Writer, checks check.Results) {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "CHECK\tRESULT\tDETAILS\n")
+	for _, c := range checks {
+		result := "PASS"
+		if !c.Passed {
+			result = "FAIL"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", c.Name, result, c.Message)
+	}
+	_ = w.Flush()
+}
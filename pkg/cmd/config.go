@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/openshift/microshift/pkg/config"
+	"github.com/openshift/microshift/pkg/node"
+	"github.com/openshift/microshift/pkg/util"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func NewConfigCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Commands for working with MicroShift's configuration",
+	}
+	cmd.AddCommand(NewConfigValidateCommand())
+	cmd.AddCommand(NewConfigAcceptImmutableChangesCommand())
+	cmd.AddCommand(NewConfigMigrateCommand())
+	return cmd
+}
+
+func NewConfigValidateCommand() *cobra.Command {
+	opts := config.ValidateOptions{
+		Validators: []func(*config.Config) field.ErrorList{node.ValidateKubeletConfig},
+	}
+
+	cmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Validate MicroShift's configuration files",
+		Long: fmt.Sprintf(`Validate MicroShift's configuration files and report all the errors and warnings,
+each with the path of the offending field and the file and line setting it.
+Files given with --file are merged in order, followed by the YAML files of --dropin-dir.
+Without --file and --dropin-dir, the files MicroShift reads are validated:
+%s and %s, unless overridden with --%s and --%s.
+The command doesn't require root privileges.`, config.DefaultConfigFile, config.DefaultConfigDropInDir,
+			config.FlagConfigFile, config.FlagConfigDropInDir),
+		Args: cobra.NoArgs,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			paths := config.PathsFromFlags(cmd.Flags())
+			opts.DataDir = paths.DataDir
+			if !cmd.Flags().Changed("file") && !cmd.Flags().Changed("dropin-dir") {
+				if err := defaultValidateOptions(&opts, paths); err != nil {
+					return err
+				}
+			}
+
+			report, err := config.ValidateFiles(opts)
+			if err != nil {
+				return err
+			}
+			printValidationReport(cmd.OutOrStdout(), report)
+			if report.HasErrors() {
+				return fmt.Errorf("configuration is not valid")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringArrayVarP(&opts.Files, "file", "f", opts.Files,
+		"Configuration file to validate. Can be repeated, the files are merged in order.")
+	cmd.Flags().StringVar(&opts.DropInDir, "dropin-dir", opts.DropInDir,
+		"Directory with configuration drop-in files to validate after the --file files.")
+	cmd.Flags().BoolVar(&opts.Offline, "offline", opts.Offline,
+		`Skip the checks which need to probe the host, like looking up its hostnames,
+IP addresses, and NICs, e.g. to validate the configuration for another machine.`)
+
+	return cmd
+}
+
+func NewConfigAcceptImmutableChangesCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "accept-immutable-changes",
+		Short: "Accept changes of configuration fields which cannot be changed after the first start",
+		Long: `Accept changes of configuration fields which cannot be changed after the first start
+of MicroShift: network.clusterNetwork, network.serviceNetwork, and dns.baseDomain.
+MicroShift refuses to start when they change, because the existing pods, services,
+and routes keep using the previous values. The command records the current values
+as established, so MicroShift starts with them. Use it only for deliberate migrations,
+after making sure the cluster's workloads can cope with the change, or after
+removing MicroShift's data.`,
+		Args: cobra.NoArgs,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return shouldRunPrivileged()
+		},
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.ActiveConfig(config.PathsFromFlags(cmd.Flags()))
+			if err != nil {
+				return err
+			}
+			changes, err := cfg.ImmutableFieldChanges()
+			if err != nil {
+				return err
+			}
+			if len(changes) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "No changes of immutable configuration fields")
+				return nil
+			}
+			for _, change := range changes {
+				fmt.Fprintln(cmd.OutOrStdout(), change.String())
+			}
+			if err := cfg.PersistImmutableFields(); err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), "Accepted the changes, they take effect when MicroShift is restarted")
+			return nil
+		},
+	}
+	return cmd
+}
+
+func NewConfigMigrateCommand() *cobra.Command {
+	dryRun := false
+
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Migrate deprecated keys of MicroShift's configuration files",
+		Long: `Migrate deprecated keys of MicroShift's configuration files: renamed keys are
+replaced with the keys replacing them, values in deprecated formats are converted,
+and removed keys are dropped. MicroShift migrates the keys when it reads the files
+as well, warning about each of them. The command rewrites the files in place,
+keeping the comments, after copying each of them to a backup next to it.
+Files without deprecated keys are left untouched.`,
+		Args: cobra.NoArgs,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if dryRun {
+				return nil
+			}
+			return shouldRunPrivileged()
+		},
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			migrated, err := config.MigrateFiles(config.PathsFromFlags(cmd.Flags()), dryRun)
+			if err != nil {
+				return err
+			}
+			if len(migrated) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "No deprecated configuration keys found")
+				return nil
+			}
+			for _, fm := range migrated {
+				for _, m := range fm.Migrations {
+					fmt.Fprintf(cmd.OutOrStdout(), "%s:%d: %s\n", fm.File, m.Line, m)
+				}
+				if fm.Backup != "" {
+					fmt.Fprintf(cmd.OutOrStdout(), "Migrated %s, the original is saved in %s\n", fm.File, fm.Backup)
+				}
+			}
+			if dryRun {
+				fmt.Fprintln(cmd.OutOrStdout(), "Dry run, no files were changed")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", dryRun, "Only print the deprecated keys, without changing the files.")
+	return cmd
+}
+
+// defaultValidateOptions selects the files MicroShift reads when it starts.
+func defaultValidateOptions(opts *config.ValidateOptions, paths config.Paths) error {
+	if exists, err := util.PathExists(paths.ConfigFile); err != nil {
+		return err
+	} else if exists {
+		opts.Files = []string{paths.ConfigFile}
+	}
+	if exists, err := util.PathExists(paths.ConfigDropInDir); err != nil {
+		return err
+	} else if exists {
+		opts.DropInDir = paths.ConfigDropInDir
+	}
+	return nil
+}
+
+func printValidationReport(out io.Writer, report *config.ValidationReport) {
+	errs, warnings := 0, 0
+	for _, i := range report.Issues {
+		fmt.Fprintln(out, i.String())
+		if i.Severity == config.SeverityError {
+			errs++
+		} else {
+			warnings++
+		}
+	}
+	fmt.Fprintf(out, "Validated %d file(s): %d error(s), %d warning(s)\n", len(report.Files), errs, warnings)
+}
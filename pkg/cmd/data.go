@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/openshift/microshift/pkg/admin/reset"
+	"github.com/openshift/microshift/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+func NewDataCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "data",
+		Short: "Commands managing MicroShift data",
+	}
+
+	cmd.AddCommand(NewDataResetCommand())
+
+	return cmd
+}
+
+func NewDataResetCommand() *cobra.Command {
+	return newDataResetCommand(reset.Reset)
+}
+
+// newDataResetCommand creates the reset command running resetFn, so tests can replace it.
+func newDataResetCommand(resetFn func(reset.Options) (*reset.Summary, error)) *cobra.Command {
+	opts := reset.Options{}
+	yes := false
+
+	cmd := &cobra.Command{
+		Use:   "reset",
+		Short: "Stop MicroShift and remove all its data",
+		Long: `Stop MicroShift and remove all its data so the next start creates a new cluster.
+The command stops microshift.service and waits for microshift-etcd.scope to stop,
+removes the pods (OVN-Kubernetes ones last) and container images, the OVN state,
+logical volumes created by TopoLVM, kubelet's directory and the MicroShift data.`,
+		Args: cobra.NoArgs,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return shouldRunPrivileged()
+		},
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !yes && !confirmReset(cmd.InOrStdin(), cmd.OutOrStdout()) {
+				fmt.Fprintln(cmd.OutOrStdout(), "Aborting reset")
+				return nil
+			}
+
+			opts.DataDir = config.PathsFromFlags(cmd.Flags()).DataDir
+			summary, err := resetFn(opts)
+			printResetSummary(cmd.OutOrStdout(), summary)
+			if err != nil {
+				return fmt.Errorf("reset failed: %w", err)
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), "Reset succeeded")
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.KeepCerts, "keep-certs", opts.KeepCerts,
+		"Keep the certificates, so existing kubeconfigs remain valid for the new cluster.")
+	cmd.Flags().BoolVar(&opts.KeepPVs, "keep-pvs", opts.KeepPVs,
+		"Keep the logical volumes created by TopoLVM for persistent volumes.")
+	cmd.Flags().BoolVar(&opts.KeepImages, "keep-images", opts.KeepImages,
+		"Keep the container images.")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", yes, "Do not ask for confirmation.")
+
+	return cmd
+}
+
+func confirmReset(in io.Reader, out io.Writer) bool {
+	fmt.Fprint(out, "DATA LOSS WARNING: Do you wish to stop MicroShift and remove its data and workloads? [y/N]: ")
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && answer == "" {
+		fmt.Fprintln(out)
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+func printResetSummary(out io.Writer, s *reset.Summary) {
+	if s == nil {
+		return
+	}
+	printList := func(title string, items []string) {
+		if len(items) == 0 {
+			return
+		}
+		fmt.Fprintf(out, "%s:\n", title)
+		for _, i := range items {
+			fmt.Fprintf(out, "  %s\n", i)
+		}
+	}
+
+	printList("Stopped", s.StoppedUnits)
+	printList("Removed pods", s.RemovedPods)
+	if s.RemovedImages {
+		fmt.Fprintln(out, "Removed container images")
+	}
+	printList("Removed logical volumes", s.RemovedVolumes)
+	printList("Removed", s.RemovedPaths)
+	printList("Kept", s.Kept)
+}
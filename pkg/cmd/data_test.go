@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openshift/microshift/pkg/admin/reset"
+	"github.com/openshift/microshift/pkg/config"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDataResetCommand_removesDataDir(t *testing.T) {
+	dataDir := filepath.Join(t.TempDir(), "microshift")
+	require.NoError(t, os.MkdirAll(filepath.Join(dataDir, "etcd"), 0700))
+
+	// The fake removes only the directory it is given, like the real reset does.
+	resetCmd := newDataResetCommand(func(opts reset.Options) (*reset.Summary, error) {
+		if err := os.RemoveAll(opts.DataDir); err != nil {
+			return nil, err
+		}
+		return &reset.Summary{RemovedPaths: []string{opts.DataDir}}, nil
+	})
+	resetCmd.PersistentPreRunE = nil
+
+	root := &cobra.Command{Use: "microshift"}
+	config.AddPathFlags(root.PersistentFlags())
+	root.AddCommand(resetCmd)
+	out := &bytes.Buffer{}
+	root.SetOut(out)
+	root.SetArgs([]string{"reset", "--yes", "--" + config.FlagDataDir, dataDir})
+
+	require.NoError(t, root.Execute())
+	assert.NoDirExists(t, dataDir)
+	assert.Contains(t, out.String(), "Reset succeeded")
+}
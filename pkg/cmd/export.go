@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/openshift/microshift/pkg/admin/bundle"
+	"github.com/openshift/microshift/pkg/config"
+	"github.com/openshift/microshift/pkg/version"
+	"github.com/spf13/cobra"
+)
+
+func NewExportCommand() *cobra.Command {
+	opts := bundle.ExportOptions{}
+	file := ""
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export API objects of user namespaces to a portable bundle",
+		Long: `Export API objects of user namespaces to a portable YAML bundle which can be
+imported on another MicroShift node using 'microshift import'.
+Objects of namespaces managed by MicroShift and objects created by controllers
+or by the cluster itself are not exported. Fields assigned by the cluster,
+such as UIDs, cluster IPs and bound persistent volumes, are removed.
+Contents of persistent volumes are not exported.`,
+		Args: cobra.NoArgs,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return shouldRunPrivileged()
+		},
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.ActiveConfig(config.PathsFromFlags(cmd.Flags()))
+			if err != nil {
+				return err
+			}
+			clients, err := bundle.NewClients(cfg.KubeConfigPath(config.KubeAdmin))
+			if err != nil {
+				return err
+			}
+
+			ver := version.Get()
+			source := bundle.Source{
+				NodeName:   cfg.CanonicalNodeName(),
+				BaseDomain: cfg.DNS.BaseDomain,
+				Version:    fmt.Sprintf("%s.%s.%s", ver.Major, ver.Minor, ver.Patch),
+			}
+			b, err := bundle.Export(context.Background(), clients, source, opts)
+			if err != nil {
+				return err
+			}
+
+			if file == "" || file == "-" {
+				return b.Write(cmd.OutOrStdout())
+			}
+			f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+			if err != nil {
+				return fmt.Errorf("failed to create %q: %w", file, err)
+			}
+			if err := b.Write(f); err != nil {
+				_ = f.Close()
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return fmt.Errorf("failed to close %q: %w", file, err)
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "Exported %d objects to %s\n", len(b.Items), file)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&file, "file", "f", file, "File to write the bundle to. Defaults to standard output.")
+	cmd.Flags().StringSliceVarP(&opts.Namespaces, "namespace", "n", opts.Namespaces,
+		"Namespaces to export. Defaults to all user namespaces.")
+
+	return cmd
+}
+
+func NewImportCommand() *cobra.Command {
+	opts := bundle.ImportOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "import BUNDLE",
+		Short: "Import API objects from a bundle created by 'microshift export'",
+		Long: `Import API objects from a bundle created by 'microshift export', e.g. on another node.
+Before the objects are applied, references to the node name of the exporting node
+are replaced with the name of this node, and hosts of Routes and Ingresses
+in the exporting node's base domain are moved to this node's base domain.
+Objects are applied in order using server side apply. Failure to import an object
+does not stop the import of the remaining ones. Bundles containing objects
+in namespaces managed by MicroShift are refused.`,
+		Args: cobra.ExactArgs(1),
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return shouldRunPrivileged()
+		},
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			b, err := bundle.ReadFile(args[0])
+			if err != nil {
+				return err
+			}
+
+			cfg, err := config.ActiveConfig(config.PathsFromFlags(cmd.Flags()))
+			if err != nil {
+				return err
+			}
+			clients, err := bundle.NewClients(cfg.KubeConfigPath(config.KubeAdmin))
+			if err != nil {
+				return err
+			}
+
+			target := bundle.Target{NodeName: cfg.CanonicalNodeName(), BaseDomain: cfg.DNS.BaseDomain}
+			results, err := bundle.Import(context.Background(), clients, b, target, opts)
+			printImportResults(cmd.OutOrStdout(), results)
+			if err != nil {
+				return fmt.Errorf("import was not successful: %w", err)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.DryRun, "dry-run", opts.DryRun,
+		"Validate the objects with the API server without persisting them. "+
+			"Objects in namespaces created by the bundle cannot be validated.")
+
+	return cmd
+}
+
+func printImportResults(out io.Writer, results []bundle.ImportResult) {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "OBJECT\tRESULT\n")
+	for _, r := range results {
+		result := "IMPORTED"
+		if r.Error != "" {
+			result = "FAILED: " + r.Error
+		} else if r.Warning != "" {
+			result = "WARNING: " + r.Warning
+		}
+		fmt.Fprintf(w, "%s\t%s\n", r.Object, result)
+	}
+	_ = w.Flush()
+}
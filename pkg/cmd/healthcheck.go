@@ -6,6 +6,7 @@ import (
 	"os"
 	"time"
 
+	"github.com/openshift/microshift/pkg/config"
 	"github.com/openshift/microshift/pkg/healthcheck"
 	"github.com/spf13/cobra"
 	"k8s.io/klog/v2"
@@ -51,15 +52,16 @@ Checking health of a custom workloads can be achieved in two ways:
 				klog.Warning("WARNING: --custom was provided, ignoring --deployments, --daemonsets, and --statefulsets")
 			}
 
+			paths := config.PathsFromFlags(cmd.Flags())
 			if len(custom) != 0 {
-				return healthcheck.CustomWorkloadHealthcheck(context.Background(), timeout, custom)
+				return healthcheck.CustomWorkloadHealthcheck(context.Background(), paths, timeout, custom)
 			}
 
 			if namespace != "" {
-				return healthcheck.EasyCustomWorkloadHealthcheck(context.Background(), timeout, namespace, deployments, daemonsets, statefulsets)
+				return healthcheck.EasyCustomWorkloadHealthcheck(context.Background(), paths, timeout, namespace, deployments, daemonsets, statefulsets)
 			}
 
-			return healthcheck.MicroShiftHealthcheck(context.Background(), timeout)
+			return healthcheck.MicroShiftHealthcheck(context.Background(), paths, timeout)
 		},
 	}
 
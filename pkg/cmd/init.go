@@ -86,7 +86,7 @@ func certSetup(cfg *config.Config) (*certchains.CertificateChains, error) {
 		externalCertNames = append(externalCertNames, cfg.Node.NodeIP)
 	}
 
-	certsDir := cryptomaterial.CertsDirectory(config.DataDir)
+	certsDir := cryptomaterial.CertsDirectory(cfg.Paths.DataDir)
 
 	certChains, err := certchains.NewCertificateChains(
 		// ------------------------------
@@ -351,7 +351,7 @@ func certSetup(cfg *config.Config) (*certchains.CertificateChains, error) {
 		return nil, err
 	}
 
-	saKeyDir := filepath.Join(config.DataDir, "/resources/kube-apiserver/secrets/service-account-key")
+	saKeyDir := filepath.Join(cfg.Paths.DataDir, "/resources/kube-apiserver/secrets/service-account-key")
 	if err := util.EnsureKeyPair(
 		filepath.Join(saKeyDir, "service-account.pub"),
 		filepath.Join(saKeyDir, "service-account.key"),
@@ -371,11 +371,11 @@ func initKubeconfigs(
 	cfg *config.Config,
 	certChains *certchains.CertificateChains,
 ) error {
-	externalTrustPEM, err := os.ReadFile(cryptomaterial.CACertPath(cryptomaterial.KubeAPIServerExternalSigner(cryptomaterial.CertsDirectory(config.DataDir))))
+	externalTrustPEM, err := os.ReadFile(cryptomaterial.CACertPath(cryptomaterial.KubeAPIServerExternalSigner(cryptomaterial.CertsDirectory(cfg.Paths.DataDir))))
 	if err != nil {
 		return fmt.Errorf("failed to load the external trust signer: %v", err)
 	}
-	internalTrustPEM, err := os.ReadFile(cryptomaterial.CACertPath(cryptomaterial.KubeAPIServerLocalhostSigner(cryptomaterial.CertsDirectory(config.DataDir))))
+	internalTrustPEM, err := os.ReadFile(cryptomaterial.CACertPath(cryptomaterial.KubeAPIServerLocalhostSigner(cryptomaterial.CertsDirectory(cfg.Paths.DataDir))))
 	if err != nil {
 		return fmt.Errorf("failed to load the internal trust signer: %v", err)
 	}
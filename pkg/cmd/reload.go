@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+
+	"github.com/openshift/microshift/pkg/components"
+	"github.com/openshift/microshift/pkg/config"
+	"github.com/openshift/microshift/pkg/kustomize"
+	"github.com/spf13/pflag"
+	"k8s.io/klog/v2"
+)
+
+// configReloader applies the changes of the configuration files to the running
+// MicroShift when it receives SIGHUP. Only the reloadable fields are applied,
+// changes of the other fields are logged as requiring a restart.
+type configReloader struct {
+	// started is the configuration MicroShift started with.
+	started *config.Config
+	// applied is the configuration whose reloadable fields were applied last.
+	applied    *config.Config
+	flags      *pflag.FlagSet
+	kustomizer *kustomize.Kustomizer
+}
+
+func newConfigReloader(cfg *config.Config, flags *pflag.FlagSet, kustomizer *kustomize.Kustomizer) *configReloader {
+	return &configReloader{
+		started:    cfg,
+		applied:    cfg,
+		flags:      flags,
+		kustomizer: kustomizer,
+	}
+}
+
+func (r *configReloader) reload(ctx context.Context) {
+	klog.Info("Reloading configuration")
+	cfg, err := config.ActiveConfig(r.started.Paths)
+	if err != nil {
+		klog.ErrorS(err, "Failed to reload configuration - keeping the running configuration")
+		return
+	}
+	cfg = config.ConfigMultiNode(cfg, r.started.MultiNode.Enabled)
+	logConfigWarnings(cfg.Warnings)
+
+	changed, err := config.ChangedFields(r.started, cfg)
+	if err != nil {
+		klog.ErrorS(err, "Failed to compare reloaded configuration with the running configuration")
+		return
+	}
+	restart := []string{}
+	for _, f := range changed {
+		if !config.IsReloadable(f) {
+			restart = append(restart, f)
+		}
+	}
+	if len(restart) != 0 {
+		klog.InfoS("Configuration changes require restarting MicroShift to take effect", "fields", restart)
+	}
+
+	// Fields which failed to apply are retried on the next reload.
+	applied := *r.applied
+	if cfg.Debugging.LogLevel != applied.Debugging.LogLevel {
+		verbosity := cfg.GetVerbosity()
+		// `v` is a flag registered in klog's init()
+		if vFlag := r.flags.Lookup("v"); vFlag != nil {
+			if err := vFlag.Value.Set(strconv.Itoa(verbosity)); err != nil {
+				klog.ErrorS(err, "Failed to set log verbosity")
+			} else {
+				applied.Debugging = cfg.Debugging
+				klog.InfoS("Applied log level", "logLevel", cfg.Debugging.LogLevel, "verbosity", verbosity)
+			}
+		}
+	}
+
+	if !reflect.DeepEqual(cfg.Ingress.TuningOptions, applied.Ingress.TuningOptions) {
+		// The router is rendered from the running configuration, except for the tuning options.
+		routerCfg := *r.started
+		routerCfg.Ingress.TuningOptions = cfg.Ingress.TuningOptions
+		if err := components.ApplyRouterDeployment(ctx, &routerCfg); err != nil {
+			klog.ErrorS(err, "Failed to apply ingress tuning options")
+		} else {
+			applied.Ingress.TuningOptions = cfg.Ingress.TuningOptions
+			klog.InfoS("Applied ingress tuning options")
+		}
+	}
+
+	// Manifests are applied on every reload, because their files might have changed.
+	if err := r.kustomizer.Reapply(ctx, cfg.Manifests); err != nil {
+		klog.ErrorS(err, "Failed to apply manifests")
+	}
+	applied.Manifests = cfg.Manifests
+
+	r.applied = &applied
+	klog.Info("Configuration reloaded")
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/docker/go-units"
+	"github.com/openshift/microshift/pkg/admin/autorecovery"
+)
+
+func printRestorePlan(out io.Writer, plan *autorecovery.RestorePlan) {
+	fmt.Fprintf(out, "Storage:     %s\n", plan.Storage)
+	fmt.Fprintf(out, "Version:     %s\n", plan.Version)
+	fmt.Fprintf(out, "Last backup: %s\n", valueOrNone(string(plan.LastBackup)))
+	if plan.Candidate != "" {
+		fmt.Fprintf(out, "Candidate:   %s\n", plan.Candidate)
+		fmt.Fprintf(out, "Reason:      %s\n", plan.Reason)
+		if plan.Encrypted {
+			fmt.Fprintf(out, "Encrypted:   true\n")
+		}
+	} else {
+		fmt.Fprintf(out, "Candidate:   <none>\n")
+	}
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	if len(plan.Skipped) != 0 {
+		fmt.Fprintf(w, "\nSKIPPED BACKUP\tREASON\n")
+		for _, s := range plan.Skipped {
+			fmt.Fprintf(w, "%s\t%s\n", s.Name, s.Reason)
+		}
+	}
+
+	if len(plan.DiskSpaceChecks) != 0 {
+		fmt.Fprintf(w, "\nDISK SPACE CHECK\tPATH\tREQUIRED\tAVAILABLE\tRESULT\n")
+		for _, c := range plan.DiskSpaceChecks {
+			result := "PASS"
+			if !c.Passed() {
+				result = "FAIL"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", c.Purpose, c.Path,
+				units.BytesSize(float64(c.Required)), units.BytesSize(float64(c.Available)), result)
+		}
+	}
+	_ = w.Flush()
+
+	if len(plan.Operations) != 0 {
+		fmt.Fprintf(out, "\nOperations:\n")
+		for i, op := range plan.Operations {
+			if op.Source == "" {
+				fmt.Fprintf(out, "%2d. %-8s %s\n", i+1, op.Action, op.Destination)
+			} else {
+				fmt.Fprintf(out, "%2d. %-8s %s -> %s\n", i+1, op.Action, op.Source, op.Destination)
+			}
+		}
+	}
+}
@@ -28,6 +28,7 @@ import (
 	"github.com/openshift/microshift/pkg/util/cryptomaterial/certchains"
 	"github.com/openshift/microshift/pkg/version"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
 	logsAPIV1 "k8s.io/component-base/logs/api/v1"
 	"k8s.io/klog/v2"
@@ -38,12 +39,10 @@ const (
 	gracefulShutdownTimeout = 15
 )
 
-var (
-	preRunFailedLogPath = util.LogFilePath(filepath.Join(config.BackupsDir, "prerun_failed.log"))
-	cleanUpFileLogPaths = []util.LogFilePath{
-		preRunFailedLogPath,
-	}
-)
+// preRunFailedLogPath is the log file with the error of the failed pre-run.
+func preRunFailedLogPath(backupsDir string) util.LogFilePath {
+	return util.LogFilePath(filepath.Join(backupsDir, "prerun_failed.log"))
+}
 
 func NewRunMicroshiftCommand() *cobra.Command {
 	cmd := &cobra.Command{
@@ -64,10 +63,16 @@ func NewRunMicroshiftCommand() *cobra.Command {
 		versionInfo := version.Get()
 		klog.InfoS("Version", "microshift", versionInfo.String(), "base", release.Base)
 
-		cfg, err := config.ActiveConfig()
+		cfg, err := config.ActiveConfig(config.PathsFromFlags(cmd.Flags()))
 		if err != nil {
 			return err
 		}
+		// Logged before the checks below, so the warnings are in the journal even if
+		// the start fails. The warnings added by the checks are logged after them.
+		logConfigWarnings(cfg.Warnings)
+		if errs := node.ValidateKubeletConfig(cfg); len(errs) != 0 {
+			return fmt.Errorf("invalid configuration: %w", errs.ToAggregate())
+		}
 
 		// `v` is a flag registered in klog's init()
 		if vFlag := flags.Lookup("v"); vFlag != nil {
@@ -79,24 +84,30 @@ func NewRunMicroshiftCommand() *cobra.Command {
 
 		cfg = config.ConfigMultiNode(cfg, multinode)
 
-		for _, w := range cfg.Warnings {
-			klog.Warningf("Configuration warning: %s", w)
-		}
-
 		// Things to very badly if the node's name has changed
 		// since the last time the server started.
+		logged := len(cfg.Warnings)
 		err = cfg.EnsureNodeNameHasNotChanged()
+		logConfigWarnings(cfg.Warnings[logged:])
 		if err != nil {
 			return err
 		}
-		return RunMicroshift(cfg)
+		return RunMicroshift(cfg, flags)
 	}
 
 	return cmd
 }
 
-func cleanUpPreviousLogFiles() {
-	for _, p := range cleanUpFileLogPaths {
+// logConfigWarnings logs each of the warnings with its code and severity
+// as fields of the log entry, so they can be found in the journal by tools.
+func logConfigWarnings(warnings []config.Warning) {
+	for _, w := range warnings {
+		klog.InfoS("Configuration warning", "code", w.Code, "severity", w.Severity, "path", w.Path, "message", w.Message)
+	}
+}
+
+func cleanUpPreviousLogFiles(paths config.Paths) {
+	for _, p := range []util.LogFilePath{preRunFailedLogPath(paths.BackupsDir)} {
 		if errLog := p.Remove(); errLog != nil {
 			klog.ErrorS(errLog, "Failed to remove log file", "path", p)
 		}
@@ -120,16 +131,18 @@ func logConfig(cfg *config.Config) {
 	}
 }
 
-func prerunDataManagement() error {
-	dataManager, err := data.NewManager(config.BackupsDir)
+func prerunDataManagement(paths config.Paths) error {
+	// Backups are created on each deployment change (or upgrade of the RPMs), so files
+	// which didn't change since previous backups are deduplicated.
+	dataManager, err := data.NewManager(data.StoragePath(paths.BackupsDir), data.WithDeduplication(), data.WithDataDir(paths.DataDir))
 	if err != nil {
 		return fmt.Errorf("failed to create data manager: %w", err)
 	}
 
-	return prerun.DataManagement(dataManager)
+	return prerun.DataManagement(dataManager, paths)
 }
 
-func RunMicroshift(cfg *config.Config) error {
+func RunMicroshift(cfg *config.Config, flags *pflag.FlagSet) error {
 	// fail early if we don't have enough privileges
 	if os.Geteuid() > 0 {
 		klog.Fatalf("MicroShift must be run privileged")
@@ -149,10 +162,14 @@ func RunMicroshift(cfg *config.Config) error {
 	// k8s.io/component-base/logs/api/v1/options.go for details.
 	logsAPIV1.ReapplyHandling = logsAPIV1.ReapplyHandlingIgnoreUnchanged
 
-	cleanUpPreviousLogFiles()
+	cleanUpPreviousLogFiles(cfg.Paths)
+
+	// Recorded before the pre-run, so the failures of the data management are counted too.
+	starts := trackStart(cfg.AutoRecovery, cfg.Paths)
 
-	if err := prerunDataManagement(); err != nil {
-		writeLogFileError(preRunFailedLogPath, err)
+	if err := prerunDataManagement(cfg.Paths); err != nil {
+		writeLogFileError(preRunFailedLogPath(cfg.Paths.BackupsDir), err)
+		starts.failed("prerun", err)
 		return err
 	}
 
@@ -173,12 +190,21 @@ func RunMicroshift(cfg *config.Config) error {
 		klog.Fatal(err)
 	}
 
-	if err := util.MakeDir(config.DataDir); err != nil {
-		return fmt.Errorf("failed to create dir %q: %w", config.DataDir, err)
+	if err := util.MakeDir(cfg.Paths.DataDir); err != nil {
+		return fmt.Errorf("failed to create dir %q: %w", cfg.Paths.DataDir, err)
 	}
 
-	if err := prerun.VersionMetadataManagement(); err != nil {
-		writeLogFileError(preRunFailedLogPath, err)
+	if err := prerun.VersionMetadataManagement(cfg.Paths); err != nil {
+		writeLogFileError(preRunFailedLogPath(cfg.Paths.BackupsDir), err)
+		starts.failed("prerun", err)
+		return err
+	}
+
+	// Checked after the pre-run, so the values are compared with the data which
+	// is going to be used, after any backup was restored. Restoring a backup
+	// doesn't undo a change of the config, so the attempt isn't counted as failed.
+	if err := cfg.EnsureImmutableFieldsHaveNotChanged(); err != nil {
+		starts.forget()
 		return err
 	}
 
@@ -209,7 +235,8 @@ func RunMicroshift(cfg *config.Config) error {
 	util.Must(m.AddService(controllers.NewInfrastructureServices(cfg)))
 	util.Must(m.AddService(controllers.NewClusterPolicyController(cfg)))
 	util.Must(m.AddService(controllers.NewVersionManager(cfg)))
-	util.Must(m.AddService(kustomize.NewKustomizer(cfg)))
+	kustomizer := kustomize.NewKustomizer(cfg)
+	util.Must(m.AddService(kustomizer))
 	util.Must(m.AddService(node.NewKubeletServer(cfg)))
 	util.Must(m.AddService(loadbalancerservice.NewLoadbalancerServiceController(cfg)))
 	util.Must(m.AddService(controllers.NewKubeStorageVersionMigrator(cfg)))
@@ -258,10 +285,17 @@ func RunMicroshift(cfg *config.Config) error {
 	// Connect signal handler
 	sigTerm := make(chan os.Signal, 1)
 	signal.Notify(sigTerm, os.Interrupt, syscall.SIGTERM)
+	// SIGHUP received before MicroShift is ready is handled once it's ready.
+	sigHup := make(chan os.Signal, 1)
+	signal.Notify(sigHup, syscall.SIGHUP)
+	reloader := newConfigReloader(cfg, flags, kustomizer)
 
+	isReady := false
 	select {
 	case <-ready:
+		isReady = true
 		startRec.MicroshiftReady()
+		starts.succeeded()
 
 		os.Setenv("NOTIFY_SOCKET", notifySocket)
 		if supported, err := daemon.SdNotify(false, daemon.SdNotifyReady); err != nil {
@@ -272,8 +306,16 @@ func RunMicroshift(cfg *config.Config) error {
 			klog.Info("service does not support sd_notify readiness messages")
 		}
 
-		// Watch for SIGTERM to exit, now that we are ready.
-		<-sigTerm
+		// Watch for SIGTERM to exit, and for SIGHUP to reload the configuration, now that we are ready.
+	waitForInterrupt:
+		for {
+			select {
+			case <-sigHup:
+				reloader.reload(runCtx)
+			case <-sigTerm:
+				break waitForInterrupt
+			}
+		}
 		klog.Info("Interrupt received")
 	case <-sigTerm:
 		// A signal that comes in before we are ready is handled here.
@@ -292,5 +334,13 @@ func RunMicroshift(cfg *config.Config) error {
 		klog.InfoS("MICROSHIFT STOP TIMED OUT", "since-stop", time.Since(microshiftStop))
 	}
 	klog.InfoS("MICROSHIFT STOPPED", "since-stop", time.Since(microshiftStop))
+
+	if !isReady {
+		if service, err := m.FailedService(); service != "" {
+			starts.failed(service, err)
+		} else {
+			starts.interrupted()
+		}
+	}
 	return nil
 }
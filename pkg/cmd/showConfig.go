@@ -1,7 +1,9 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/openshift/microshift/pkg/config"
@@ -11,14 +13,22 @@ import (
 	"sigs.k8s.io/yaml"
 )
 
+// showConfigOutput is printed by show-config in json output.
+type showConfigOutput struct {
+	Config   any              `json:"config"`
+	Warnings []config.Warning `json:"warnings"`
+}
+
 type showConfigOptions struct {
-	Mode string
+	Mode   string
+	Output string
 	genericclioptions.IOStreams
 }
 
 func NewShowConfigCommand(ioStreams genericclioptions.IOStreams) *cobra.Command {
 	opts := showConfigOptions{
-		Mode: "effective",
+		Mode:   "effective",
+		Output: "yaml",
 	}
 
 	cmd := &cobra.Command{
@@ -31,10 +41,18 @@ func NewShowConfigCommand(ioStreams genericclioptions.IOStreams) *cobra.Command
 			if os.Geteuid() > 0 {
 				cmdutil.CheckErr(fmt.Errorf("command requires root privileges"))
 			}
+			if opts.Output != "yaml" && opts.Output != "json" {
+				cmdutil.CheckErr(fmt.Errorf("unrecognized output format %q", opts.Output))
+			}
+			if opts.Output == "json" && opts.Mode == "provenance" {
+				cmdutil.CheckErr(fmt.Errorf("mode %q supports only yaml output", opts.Mode))
+			}
 
+			var marshalled []byte
+			var settings any
 			switch opts.Mode {
 			case "effective":
-				cfg, err = config.ActiveConfig()
+				cfg, err = config.ActiveConfig(config.PathsFromFlags(cmd.Flags()))
 				if err != nil {
 					cmdutil.CheckErr(err)
 				}
@@ -44,12 +62,35 @@ func NewShowConfigCommand(ioStreams genericclioptions.IOStreams) *cobra.Command
 				}
 			case "default":
 				cfg = config.NewDefault()
+			case "provenance":
+				var provenance *config.Provenance
+				cfg, provenance, err = config.ActiveConfigWithProvenance(config.PathsFromFlags(cmd.Flags()))
+				cmdutil.CheckErr(err)
+				cmdutil.CheckErr(cfg.EnsureNodeNameHasNotChanged())
+				marshalled, err = provenance.AnnotatedYAML(cfg)
+				cmdutil.CheckErr(err)
+			case "user":
+				cfg, err = config.ActiveConfig(config.PathsFromFlags(cmd.Flags()))
+				cmdutil.CheckErr(err)
+				settings, err = cfg.UserSettings()
+				cmdutil.CheckErr(err)
 			default:
 				cmdutil.CheckErr(fmt.Errorf("unrecognized mode %q", opts.Mode))
 			}
 
-			marshalled, err := yaml.Marshal(cfg)
-			cmdutil.CheckErr(err)
+			if settings == nil {
+				settings = cfg
+			}
+
+			if opts.Output == "json" {
+				cmdutil.CheckErr(printConfigJSON(ioStreams.Out, settings, cfg.Warnings))
+				return
+			}
+
+			if marshalled == nil {
+				marshalled, err = yaml.Marshal(settings)
+				cmdutil.CheckErr(err)
+			}
 
 			fmt.Fprintf(ioStreams.Out, "%s\n", string(marshalled))
 
@@ -60,7 +101,25 @@ func NewShowConfigCommand(ioStreams genericclioptions.IOStreams) *cobra.Command
 	}
 
 	flags := cmd.Flags()
-	flags.StringVarP(&opts.Mode, "mode", "m", opts.Mode, "One of 'default' or 'effective'.")
+	flags.StringVarP(&opts.Mode, "mode", "m", opts.Mode, "One of 'default', 'effective', 'provenance', or 'user'. "+
+		"'provenance' annotates each effective value with its source: default, computed, or the file and line setting it. "+
+		"'user' prints only the values set in the config files.")
+	flags.StringVarP(&opts.Output, "output", "o", opts.Output, "One of 'yaml' or 'json'. "+
+		"'json' prints an object with the configuration and the list of warnings, each with its code.")
 
 	return cmd
 }
+
+// printConfigJSON prints the settings and the warnings as a single object.
+// The warnings are always a list, so tools don't need to handle null.
+func printConfigJSON(out io.Writer, settings any, warnings []config.Warning) error {
+	if warnings == nil {
+		warnings = []config.Warning{}
+	}
+	marshalled, err := json.MarshalIndent(showConfigOutput{Config: settings, Warnings: warnings}, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "%s\n", string(marshalled))
+	return nil
+}
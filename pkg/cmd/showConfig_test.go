@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openshift/microshift/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type showConfigJSON struct {
+	Config   map[string]any   `json:"config"`
+	Warnings []config.Warning `json:"warnings"`
+}
+
+func Test_printConfigJSON(t *testing.T) {
+	cfg := config.NewDefault()
+	out := &bytes.Buffer{}
+	require.NoError(t, printConfigJSON(out, cfg, cfg.Warnings))
+
+	assert.Contains(t, out.String(), `"warnings": []`)
+	result := showConfigJSON{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &result))
+	assert.Contains(t, result.Config, "network")
+	assert.Empty(t, result.Warnings)
+}
+
+func Test_printConfigJSON_NodeNameChangedByHostname(t *testing.T) {
+	cfg := config.NewDefault()
+	cfg.Paths.DataDir = t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(cfg.Paths.DataDir, ".nodename"), []byte("previous-node"), 0600))
+	require.NoError(t, cfg.EnsureNodeNameHasNotChanged())
+
+	out := &bytes.Buffer{}
+	require.NoError(t, printConfigJSON(out, cfg, cfg.Warnings))
+
+	result := showConfigJSON{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &result))
+	require.Len(t, result.Warnings, 1)
+	assert.Equal(t, config.WarningNodeNameChangedByHostname, result.Warnings[0].Code)
+	assert.Equal(t, config.SeverityWarning, result.Warnings[0].Severity)
+	assert.Equal(t, "node.hostnameOverride", result.Warnings[0].Path)
+	assert.Contains(t, result.Warnings[0].Message, `"previous-node"`)
+	assert.Equal(t, "previous-node", result.Config["node"].(map[string]any)["hostnameOverride"])
+}
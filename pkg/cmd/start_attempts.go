@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/openshift/microshift/pkg/admin/autorecovery"
+	"github.com/openshift/microshift/pkg/admin/data"
+	"github.com/openshift/microshift/pkg/config"
+	"k8s.io/klog/v2"
+)
+
+// startTracker persists attempts to start MicroShift to detect a crash loop
+// and to break it by restoring a backup from the auto-recovery storage.
+type startTracker struct {
+	attempts *autorecovery.StartAttempts
+}
+
+// trackStart records the start of MicroShift. If MicroShift failed to start
+// too many times within the configured window, the most recent suitable backup
+// is restored from the auto-recovery storage before continuing the startup.
+func trackStart(cfg config.AutoRecovery, paths config.Paths) *startTracker {
+	attempts, err := autorecovery.LoadStartAttempts(paths.BackupsDir)
+	if err != nil {
+		klog.ErrorS(err, "Failed to load previous start attempts - crash loop detection is disabled")
+		return &startTracker{}
+	}
+
+	now := time.Now()
+	failed := attempts.FailedWithin(now, cfg.Window.Duration)
+	klog.InfoS("Previous failed start attempts", "count", len(failed), "window", cfg.Window.Duration, "attempts", failed)
+
+	if len(failed) >= cfg.MaxFailedStarts {
+		if cfg.IsEnabled() {
+			klog.InfoS("MicroShift failed to start too many times - restoring backup from auto-recovery storage",
+				"failedStarts", len(failed), "maxFailedStarts", cfg.MaxFailedStarts, "storage", cfg.Storage)
+			if err := restoreAfterCrashLoop(cfg.Storage, paths); err != nil {
+				klog.ErrorS(err, "Failed to restore backup from auto-recovery storage - continuing startup with current data")
+			} else if err := attempts.Clear(); err != nil {
+				klog.ErrorS(err, "Failed to clear start attempts")
+			}
+		} else {
+			klog.InfoS("MicroShift failed to start too many times, but autoRecovery.storage is not configured - continuing startup",
+				"failedStarts", len(failed), "maxFailedStarts", cfg.MaxFailedStarts)
+		}
+	}
+
+	attempts.Begin(now, cfg.Window.Duration)
+	t := &startTracker{attempts: attempts}
+	t.save()
+	return t
+}
+
+func restoreAfterCrashLoop(storage string, paths config.Paths) error {
+	m, err := autorecovery.NewManager(data.StoragePath(storage), paths.DataDir, true, nil,
+		data.WithHooks(paths.BackupHooksDir(), data.DefaultHookTimeout))
+	if err != nil {
+		return err
+	}
+	return m.PerformRestore()
+}
+
+// succeeded forgets all the attempts because MicroShift is ready.
+func (t *startTracker) succeeded() {
+	if t.attempts == nil {
+		return
+	}
+	if err := t.attempts.Clear(); err != nil {
+		klog.ErrorS(err, "Failed to clear start attempts")
+	}
+}
+
+// failed records the service which failed the start.
+func (t *startTracker) failed(service string, err error) {
+	if t.attempts == nil {
+		return
+	}
+	t.attempts.Fail(service, err)
+	t.save()
+}
+
+// interrupted handles MicroShift being stopped before it got ready without
+// any service failing. The attempt is only forgotten if MicroShift was stopped
+// explicitly, otherwise, e.g. when systemd killed it because it didn't get ready
+// within the start timeout, the attempt counts as failed.
+func (t *startTracker) interrupted() {
+	if t.attempts == nil {
+		return
+	}
+	if stoppedExplicitly() {
+		t.forget()
+		return
+	}
+	t.attempts.Fail("", fmt.Errorf("stopped before getting ready"))
+	t.save()
+}
+
+// forget discards the current attempt, so it counts neither as failed nor as succeeded.
+func (t *startTracker) forget() {
+	if t.attempts == nil {
+		return
+	}
+	t.attempts.Discard()
+	t.save()
+}
+
+// stoppedExplicitly returns true if MicroShift runs outside of systemd (so it was
+// interrupted by the user), or if microshift.service is being stopped on request,
+// e.g. with 'systemctl stop'. A unit stopped because its start timed out is
+// deactivating too, but its result is not "success".
+func stoppedExplicitly() bool {
+	if os.Getenv("INVOCATION_ID") == "" {
+		return true
+	}
+	out, err := exec.Command("systemctl", "show", "-p", "ActiveState", "-p", "Result", "microshift.service").CombinedOutput()
+	if err != nil {
+		klog.ErrorS(err, "Failed to get state of microshift.service - counting the start as failed", "output", string(out))
+		return false
+	}
+	return isExplicitStop(string(out))
+}
+
+// isExplicitStop checks the output of 'systemctl show -p ActiveState -p Result'.
+func isExplicitStop(properties string) bool {
+	props := map[string]string{}
+	for _, line := range strings.Split(properties, "\n") {
+		if k, v, ok := strings.Cut(strings.TrimSpace(line), "="); ok {
+			props[k] = v
+		}
+	}
+	klog.InfoS("State of microshift.service", "activeState", props["ActiveState"], "result", props["Result"])
+	return props["ActiveState"] == "deactivating" && props["Result"] == "success"
+}
+
+func (t *startTracker) save() {
+	if err := t.attempts.Save(); err != nil {
+		klog.ErrorS(err, "Failed to save start attempts")
+	}
+}
@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_isExplicitStop(t *testing.T) {
+	testData := []struct {
+		name       string
+		properties string
+		expected   bool
+	}{
+		{name: "systemctl stop", properties: "ActiveState=deactivating\nResult=success\n", expected: true},
+		{name: "start timed out", properties: "ActiveState=deactivating\nResult=timeout\n", expected: false},
+		{name: "still activating", properties: "ActiveState=activating\nResult=success\n", expected: false},
+		{name: "unexpected output", properties: "Failed to connect to bus", expected: false},
+	}
+
+	for _, td := range testData {
+		t.Run(td.name, func(t *testing.T) {
+			assert.Equal(t, td.expected, isExplicitStop(td.properties))
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/openshift/microshift/pkg/admin/prerun"
+	"github.com/openshift/microshift/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+func NewUpgradeCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "upgrade",
+		Short: "Commands helping with MicroShift upgrades",
+	}
+
+	cmd.AddCommand(NewUpgradePreflightCommand())
+
+	return cmd
+}
+
+func NewUpgradePreflightCommand() *cobra.Command {
+	targetVersion := ""
+	blocksFile := ""
+	output := ""
+
+	cmd := &cobra.Command{
+		Use:   "preflight",
+		Short: "Check if MicroShift data can be upgraded to the target version",
+		Long: `Check if MicroShift data can be upgraded to the target version before
+installing the new version or rebooting into a new deployment.
+The command evaluates the same rules as MicroShift does when it starts:
+supported version skew and blocked upgrades. It also checks if there is enough
+disk space for the backup created before the upgrade.`,
+		Args: cobra.NoArgs,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return shouldRunPrivileged()
+		},
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateListOutput(output); err != nil {
+				return err
+			}
+			if targetVersion == "" {
+				return fmt.Errorf("--target-version is required")
+			}
+
+			report, err := prerun.Preflight(targetVersion, blocksFile, config.PathsFromFlags(cmd.Flags()))
+			if err != nil {
+				return err
+			}
+
+			if output == "json" {
+				if err := printJSON(cmd.OutOrStdout(), report); err != nil {
+					return err
+				}
+			} else {
+				printPreflightReport(cmd.OutOrStdout(), report)
+			}
+			if !report.Checks.Passed() {
+				return fmt.Errorf("upgrade to %s is not possible", report.TargetVersion)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&targetVersion, "target-version", targetVersion,
+		"Version of MicroShift to upgrade to, in the Major.Minor.Patch format.")
+	cmd.Flags().StringVar(&blocksFile, "upgrade-blocks-file", blocksFile,
+		`File with the list of blocked upgrades, e.g. from the target release.
+Defaults to the list embedded in the installed MicroShift.`)
+	cmd.Flags().StringVarP(&output, "output", "o", output, "Output format. One of: json. Default is a table.")
+
+	return cmd
+}
+
+func printPreflightReport(out io.Writer, report *prerun.PreflightReport) {
+	fmt.Fprintf(out, "Data version:   %s\n", valueOrNone(report.DataVersion))
+	fmt.Fprintf(out, "Target version: %s\n\n", report.TargetVersion)
+
+	printCheckResults(out, report.Checks)
+}
@@ -50,7 +50,7 @@ func startServiceCAController(ctx context.Context, cfg *config.Config, kubeconfi
 		cmName     = "signing-cabundle"
 	)
 
-	serviceCADir := cryptomaterial.ServiceCADir(cryptomaterial.CertsDirectory(config.DataDir))
+	serviceCADir := cryptomaterial.ServiceCADir(cryptomaterial.CertsDirectory(cfg.Paths.DataDir))
 	caCertPath := cryptomaterial.CACertPath(serviceCADir)
 	caKeyPath := cryptomaterial.CAKeyPath(serviceCADir)
 
@@ -122,9 +122,6 @@ func startIngressController(ctx context.Context, cfg *config.Config, kubeconfigP
 			"components/openshift-router/cluster-role-aggregate-route.yaml",
 			"components/openshift-router/cluster-role-system-router.yaml",
 		}
-		apps = []string{
-			"components/openshift-router/deployment.yaml",
-		}
 		ns = []string{
 			"components/openshift-router/namespace.yaml",
 		}
@@ -172,7 +169,7 @@ func startIngressController(ctx context.Context, cfg *config.Config, kubeconfigP
 		return err
 	}
 
-	serviceCADir := cryptomaterial.ServiceCADir(cryptomaterial.CertsDirectory(config.DataDir))
+	serviceCADir := cryptomaterial.ServiceCADir(cryptomaterial.CertsDirectory(cfg.Paths.DataDir))
 	caCertPath := cryptomaterial.CACertPath(serviceCADir)
 	cmData := map[string]string{}
 
@@ -206,7 +203,23 @@ func startIngressController(ctx context.Context, cfg *config.Config, kubeconfigP
 		return err
 	}
 
-	if err := assets.ApplyDeployments(ctx, apps, renderTemplate, renderParamsFromConfig(cfg, extraParams), kubeconfigPath); err != nil {
+	return applyRouterDeployment(ctx, cfg, kubeconfigPath)
+}
+
+// ApplyRouterDeployment applies the router's deployment, e.g. after the reload
+// of the configuration changed the ingress tuning options.
+func ApplyRouterDeployment(ctx context.Context, cfg *config.Config) error {
+	if cfg.Ingress.Status == config.StatusRemoved {
+		return nil
+	}
+	return applyRouterDeployment(ctx, cfg, cfg.KubeConfigPath(config.KubeAdmin))
+}
+
+func applyRouterDeployment(ctx context.Context, cfg *config.Config, kubeconfigPath string) error {
+	apps := []string{
+		"components/openshift-router/deployment.yaml",
+	}
+	if err := assets.ApplyDeployments(ctx, apps, renderTemplate, renderParamsFromConfig(cfg, generateIngressParams(cfg)), kubeconfigPath); err != nil {
 		klog.Warningf("Failed to apply apps %v: %v", apps, err)
 		return err
 	}
@@ -52,9 +52,9 @@ func startCNIPlugin(ctx context.Context, cfg *config.Config, kubeconfigPath stri
 		}
 	}
 
-	ovnConfig, err := ovn.NewOVNKubernetesConfigFromFileOrDefault(filepath.Dir(config.ConfigFile), cfg.MultiNode.Enabled)
+	ovnConfig, err := ovn.NewOVNKubernetesConfigFromFileOrDefault(filepath.Dir(cfg.Paths.ConfigFile), cfg.MultiNode.Enabled)
 	if err != nil {
-		return fmt.Errorf("failed to create OVN-K configuration from %q: %w", config.ConfigFile, err)
+		return fmt.Errorf("failed to create OVN-K configuration from %q: %w", cfg.Paths.ConfigFile, err)
 	}
 
 	if err := ovnConfig.Validate(); err != nil {
@@ -90,7 +90,7 @@ func startCNIPlugin(ctx context.Context, cfg *config.Config, kubeconfigPath stri
 	extraParams := assets.RenderParams{
 		"OVNConfig":      ovnConfig,
 		"KubeconfigPath": kubeconfigPath,
-		"KubeconfigDir":  filepath.Join(config.DataDir, "/resources/kubeadmin"),
+		"KubeconfigDir":  filepath.Join(cfg.Paths.DataDir, "/resources/kubeadmin"),
 		"OVN_NB_DB_LIST": fmt.Sprintf("tcp:%s:%s", cfg.MultiNode.Controlplane, ovn.OVN_NB_PORT),
 		"OVN_SB_DB_LIST": fmt.Sprintf("tcp:%s:%s", cfg.MultiNode.Controlplane, ovn.OVN_SB_PORT),
 		"OVN_NB_PORT":    ovn.OVN_NB_PORT,
@@ -31,8 +31,8 @@ func startCSIPlugin(ctx context.Context, cfg *config.Config, kubeconfigPath stri
 		return nil
 	}
 
-	usrCfg := filepath.Join(filepath.Dir(config.ConfigFile), lvmd.LvmdConfigFileName)
-	runtimeCfg := lvmd.RuntimeLvmdConfigFile
+	usrCfg := filepath.Join(filepath.Dir(cfg.Paths.ConfigFile), lvmd.LvmdConfigFileName)
+	runtimeCfg := lvmd.RuntimeLvmdConfigFile(cfg.Paths.DataDir)
 
 	lvmdCfg, err := loadCSIPluginConfig(
 		ctx,
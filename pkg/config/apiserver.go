@@ -1,11 +1,11 @@
 package config
 
 import (
-	"fmt"
 	"slices"
 
 	configv1 "github.com/openshift/api/config/v1"
 	"github.com/openshift/library-go/pkg/crypto"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 )
 
 type ApiServer struct {
@@ -98,9 +98,10 @@ func (t *TLSConfig) UpdateValues() {
 	}
 }
 
-func (t *TLSConfig) Validate() error {
+func (t *TLSConfig) validate(path *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
 	if len(t.CipherSuites) == 0 {
-		return fmt.Errorf("unsupported empty cipher suites")
+		errs = append(errs, field.Required(path.Child("cipherSuites"), "unsupported empty cipher suites"))
 	}
 	var cipherSuites []string
 	switch t.MinVersion {
@@ -109,14 +110,15 @@ func (t *TLSConfig) Validate() error {
 	case string(configv1.VersionTLS13):
 		cipherSuites = getIANACipherSuites(configv1.TLSProfiles[configv1.TLSProfileModernType].Ciphers)
 	default:
-		return fmt.Errorf("unsupported value %s for tls.MinVersion", t.MinVersion)
+		return append(errs, field.NotSupported(path.Child("minVersion"), t.MinVersion,
+			[]string{string(configv1.VersionTLS12), string(configv1.VersionTLS13)}))
 	}
-	for _, suite := range t.CipherSuites {
+	for i, suite := range t.CipherSuites {
 		if !slices.Contains(cipherSuites, suite) {
-			return fmt.Errorf("unsupported cipher suite %s for TLS version %s", suite, t.MinVersion)
+			errs = append(errs, field.NotSupported(path.Child("cipherSuites").Index(i), suite, cipherSuites))
 		}
 	}
-	return nil
+	return errs
 }
 
 func getIANACipherSuites(suites []string) []string {
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"path/filepath"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+const (
+	defaultAutoRecoveryMaxFailedStarts = 3
+	defaultAutoRecoveryWindow          = 30 * time.Minute
+)
+
+type AutoRecovery struct {
+	// Directory holding backups created using `microshift backup --auto-recovery`.
+	// When set, MicroShift restores the most recent suitable backup by itself
+	// after failing to start maxFailedStarts times within the window.
+	// Empty value disables the automatic restore.
+	Storage string `json:"storage"`
+
+	// Number of failed starts within the window that triggers the restore.
+	// Defaults to 3.
+	// +kubebuilder:default=3
+	MaxFailedStarts int `json:"maxFailedStarts"`
+
+	// Period of time in which the failed starts are counted, e.g. "30m".
+	// Defaults to "30m".
+	// +kubebuilder:default="30m"
+	// +kubebuilder:validation:Type:=string
+	Window metav1.Duration `json:"window"`
+}
+
+// IsEnabled returns true if MicroShift should restore a backup when it keeps failing to start.
+func (a AutoRecovery) IsEnabled() bool {
+	return a.Storage != ""
+}
+
+func (a AutoRecovery) validate(path *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+	if a.Storage != "" && !filepath.IsAbs(a.Storage) {
+		errs = append(errs, field.Invalid(path.Child("storage"), a.Storage, "must be an absolute path"))
+	}
+	if a.MaxFailedStarts < 1 {
+		errs = append(errs, field.Invalid(path.Child("maxFailedStarts"), a.MaxFailedStarts, "must be at least 1"))
+	}
+	if a.Window.Duration <= 0 {
+		errs = append(errs, field.Invalid(path.Child("window"), a.Window.Duration.String(), "must be a positive duration"))
+	}
+	return errs
+}
@@ -4,7 +4,6 @@ package config
 
 import (
 	"bytes"
-	"errors"
 	"fmt"
 	"math"
 	"net"
@@ -19,6 +18,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/klog/v2"
 	netutils "k8s.io/utils/net"
 	"k8s.io/utils/ptr"
@@ -31,6 +31,11 @@ import (
 const (
 	// default DNS resolve file when systemd-resolved is used
 	DefaultSystemdResolvedFile = "/run/systemd/resolve/resolv.conf"
+
+	// Addresses from the documentation ranges used instead of
+	// the host's addresses when the config is offline.
+	offlineNodeIP   = "192.0.2.1"
+	offlineNodeIPv6 = "2001:db8::1"
 )
 
 var (
@@ -54,16 +59,27 @@ type Config struct {
 	Ingress   IngressConfig `json:"ingress"`
 	Storage   Storage       `json:"storage"`
 
-	// Settings specified in this section are transferred as-is into the Kubelet config.
+	// Settings of restoring backups when MicroShift keeps failing to start.
+	AutoRecovery AutoRecovery `json:"autoRecovery"`
+
+	// Settings specified in this section are transferred into the Kubelet config.
+	// They must be valid KubeletConfiguration fields, except those set by MicroShift
+	// like the TLS files, clusterDNS, clusterDomain, and volumePluginDir.
 	// +kubebuilder:validation:Schemaless
 	Kubelet map[string]any `json:"kubelet"`
 
 	// Internal-only fields
 	userSettings *Config `json:"-"` // the values read from the config file
 
+	Paths Paths `json:"-"` // the locations of the config files and data, set by flags or env
+
 	MultiNode MultiNodeConfig `json:"-"` // the value read from commond line
 
-	Warnings []string `json:"-"` // Warnings that should not prevent the service from starting.
+	Warnings []Warning `json:"-"` // Warnings that should not prevent the service from starting.
+
+	// offline makes the config skip probing the host for defaults and validation,
+	// so it can be validated on another machine (see ValidateFiles).
+	offline bool
 }
 
 // NewDefault creates a new Config struct populated with the
@@ -87,17 +103,20 @@ func NewDefault() *Config {
 // changed.
 func (c *Config) fillDefaults() error {
 	// Look up any values that may generate an error
-	subjectAltNames, err := getAllHostnames()
-	if err != nil {
-		return fmt.Errorf("failed to get all hostnames: %v", err)
-	}
 	hostname, err := os.Hostname()
 	if err != nil {
 		return fmt.Errorf("failed to get hostname %v", err)
 	}
-	nodeIP, err := util.GetHostIP("")
-	if err != nil {
-		return fmt.Errorf("failed to get host IP: %v", err)
+	subjectAltNames, nodeIP := []string{}, offlineNodeIP
+	if !c.offline {
+		subjectAltNames, err = getAllHostnames()
+		if err != nil {
+			return fmt.Errorf("failed to get all hostnames: %v", err)
+		}
+		nodeIP, err = util.GetHostIP("")
+		if err != nil {
+			return fmt.Errorf("failed to get host IP: %v", err)
+		}
 	}
 
 	c.Debugging = Debugging{
@@ -114,6 +133,10 @@ func (c *Config) fillDefaults() error {
 		MaxFileSize: 200,
 		Profile:     "Default",
 	}
+	c.AutoRecovery = AutoRecovery{
+		MaxFailedStarts: defaultAutoRecoveryMaxFailedStarts,
+		Window:          metav1.Duration{Duration: defaultAutoRecoveryWindow},
+	}
 	c.Node = Node{
 		HostnameOverride: hostname,
 		NodeIP:           nodeIP,
@@ -126,10 +149,10 @@ func (c *Config) fillDefaults() error {
 	}
 	c.Etcd = EtcdConfig{
 		MemoryLimitMB:           0,
-		QuotaBackendBytes:       8 * 1024 * 1024 * 1024,
-		MinDefragBytes:          100 * 1024 * 1024,
-		MaxFragmentedPercentage: 45,
-		DefragCheckFreq:         5 * time.Minute,
+		QuotaBackendBytes:       defaultEtcdQuotaBackendBytes,
+		MinDefragBytes:          defaultEtcdMinDefragBytes,
+		MaxFragmentedPercentage: defaultEtcdMaxFragmentedPercentage,
+		DefragCheckFreq:         metav1.Duration{Duration: defaultEtcdDefragCheckFreq},
 	}
 	c.Manifests = Manifests{
 		KustomizePaths: []string{
@@ -168,6 +191,7 @@ func (c *Config) fillDefaults() error {
 	}
 	c.MultiNode.Enabled = false
 	c.Kubelet = nil
+	c.Paths = DefaultPaths()
 
 	return nil
 }
@@ -211,6 +235,18 @@ func (c *Config) incorporateUserSettings(u *Config) {
 	if u.Etcd.MemoryLimitMB != 0 {
 		c.Etcd.MemoryLimitMB = u.Etcd.MemoryLimitMB
 	}
+	if u.Etcd.QuotaBackendBytes != 0 {
+		c.Etcd.QuotaBackendBytes = u.Etcd.QuotaBackendBytes
+	}
+	if u.Etcd.MinDefragBytes != 0 {
+		c.Etcd.MinDefragBytes = u.Etcd.MinDefragBytes
+	}
+	if u.Etcd.MaxFragmentedPercentage != 0 {
+		c.Etcd.MaxFragmentedPercentage = u.Etcd.MaxFragmentedPercentage
+	}
+	if u.Etcd.DefragCheckFreq.Duration != 0 {
+		c.Etcd.DefragCheckFreq = u.Etcd.DefragCheckFreq
+	}
 
 	if u.Node.HostnameOverride != "" {
 		c.Node.HostnameOverride = u.Node.HostnameOverride
@@ -253,6 +289,16 @@ func (c *Config) incorporateUserSettings(u *Config) {
 		c.Debugging.LogLevel = u.Debugging.LogLevel
 	}
 
+	if u.AutoRecovery.Storage != "" {
+		c.AutoRecovery.Storage = u.AutoRecovery.Storage
+	}
+	if u.AutoRecovery.MaxFailedStarts != 0 {
+		c.AutoRecovery.MaxFailedStarts = u.AutoRecovery.MaxFailedStarts
+	}
+	if u.AutoRecovery.Window.Duration != 0 {
+		c.AutoRecovery.Window = u.AutoRecovery.Window
+	}
+
 	// Check for nil instead of an empty list because if a user
 	// provides a list but it is empty we want to treat that as
 	// disabling the manifest loader.
@@ -374,9 +420,13 @@ func (c *Config) updateComputedValues() error {
 		// is not valid in this case, because it relies on net.ChooseHostInterface
 		// which gives preference to IPv4 addresses. Instead, a simple helper
 		// is used.
-		ip, err := util.GetHostIPv6("")
-		if err != nil {
-			return fmt.Errorf("unable to determine ipv6 host address: %v", err)
+		ip := offlineNodeIPv6
+		if !c.offline {
+			var err error
+			ip, err = util.GetHostIPv6("")
+			if err != nil {
+				return fmt.Errorf("unable to determine ipv6 host address: %v", err)
+			}
 		}
 		c.Node.NodeIPV6 = ip
 	}
@@ -429,138 +479,142 @@ func (c *Config) updateComputedValues() error {
 }
 
 func (c *Config) validate() error {
+	return c.validateFields().ToAggregate()
+}
+
+// validateFields returns all the problems of the configuration, each
+// with the path of the field causing it. Checks which need to probe
+// the host, like looking up its addresses and NICs, are skipped when
+// the config is offline.
+func (c *Config) validateFields() field.ErrorList {
+	errs := field.ErrorList{}
+
+	apiServerPath := field.NewPath("apiServer")
 	if !isValidIPAddress(c.ApiServer.AdvertiseAddress) {
-		return fmt.Errorf("error validating apiServer.advertiseAddress (%q)", c.ApiServer.AdvertiseAddress)
-	}
-	if c.ApiServer.SkipInterface {
-		err := checkAdvertiseAddressConfigured(c.ApiServer.AdvertiseAddress)
-		if err != nil {
-			return err
+		errs = append(errs, field.Invalid(apiServerPath.Child("advertiseAddress"), c.ApiServer.AdvertiseAddress,
+			"must be a valid IP address"))
+	} else if c.ApiServer.SkipInterface && !c.offline {
+		if err := checkAdvertiseAddressConfigured(c.ApiServer.AdvertiseAddress); err != nil {
+			errs = append(errs, field.Invalid(apiServerPath.Child("advertiseAddress"), c.ApiServer.AdvertiseAddress, err.Error()))
 		}
 	}
 
 	if !isValidIPAddress(c.Node.NodeIP) {
-		return fmt.Errorf("error validating node.nodeIP (%q)", c.Node.NodeIP)
+		errs = append(errs, field.Invalid(field.NewPath("node", "nodeIP"), c.Node.NodeIP, "must be a valid IP address"))
 	}
 
-	if err := validateNetworkStack(c); err != nil {
-		return fmt.Errorf("error validating networks: %w", err)
-	}
+	errs = append(errs, validateNetworkStack(c)...)
 
 	if !c.Network.validCNIPlugin() {
-		return fmt.Errorf("invalid cni plugin for network configuration  %q", c.Network.CNIPlugin)
+		errs = append(errs, field.NotSupported(field.NewPath("network", "cniPlugin"), c.Network.CNIPlugin, []string{string(CniPluginUnset), string(CniPluginNone), string(CniPluginOVNK)}))
 	}
 
-	//nolint:nestif // extracting the nested ifs will just increase the complexity of the if expressions as validation expands
 	if len(c.ApiServer.SubjectAltNames) > 0 {
-		// Any entry in SubjectAltNames will be included in the external access certificates.
-		// Any of the hostnames and IPs (except the node IP) listed below conflicts with
-		// other certificates, such as the service network and localhost access.
-		// The node IP is a bit special. Apiserver k8s service, which holds a service IP
-		// gets resolved to the node IP. If we include the node IP in the SAN then we have
-		// an ambiguity, the same IP matches two different certificates and there are errors
-		// when trying to reach apiserver from within the cluster using the service IP.
-		// Apiserver will decide which certificate to return to client hello based on SNI
-		// (which client-go does not use) or raw IP mappings. As soon as there is a match for
-		// the node IP it returns that certificate, which is the external access one. This
-		// breaks all pods trying to reach apiserver, as hostnames dont match and the certificate
-		// is invalid.
-		u, err := url.Parse(c.ApiServer.URL)
-		if err != nil {
-			return fmt.Errorf("failed to parse cluster URL: %v", err)
-		}
-		if u.Hostname() == "localhost" || u.Hostname() == "127.0.0.1" {
-			if stringSliceContains(c.ApiServer.SubjectAltNames, "localhost", "127.0.0.1") {
-				return fmt.Errorf("subjectAltNames must not contain localhost, 127.0.0.1")
-			}
-		} else {
-			if stringSliceContains(c.ApiServer.SubjectAltNames, c.Node.NodeIP) {
-				return fmt.Errorf("subjectAltNames must not contain node IP")
-			}
-			if !stringSliceContains(c.ApiServer.SubjectAltNames, u.Host) || u.Host != c.Node.HostnameOverride {
-				return fmt.Errorf("cluster URL host %q must be included in subjectAltNames or nodeName", u.String())
-			}
-		}
-		if stringSliceContains(
-			c.ApiServer.SubjectAltNames,
-			"kubernetes",
-			"kubernetes.default",
-			"kubernetes.default.svc",
-			"kubernetes.default.svc.cluster.local",
-			"openshift",
-			"openshift.default",
-			"openshift.default.svc",
-			"openshift.default.svc.cluster.local",
-		) {
-			return fmt.Errorf("subjectAltNames must not contain kubernetes service names")
-		}
-		if stringSliceContains(
-			c.ApiServer.SubjectAltNames,
-			c.ApiServer.AdvertiseAddresses...,
-		) {
-			return fmt.Errorf("subjectAltNames must not contain apiserver advertise address IPs")
-		}
+		errs = append(errs, c.validateSubjectAltNames(apiServerPath.Child("subjectAltNames"))...)
 	}
 
-	if c.Etcd.MemoryLimitMB > 0 && c.Etcd.MemoryLimitMB < EtcdMinimumMemoryLimit {
-		return fmt.Errorf("etcd.memoryLimitMB value %d is below the minimum allowed %d",
-			c.Etcd.MemoryLimitMB, EtcdMinimumMemoryLimit,
-		)
-	}
-
-	if c.ApiServer.SkipInterface {
-		err := checkAdvertiseAddressConfigured(c.ApiServer.AdvertiseAddresses[0])
-		if err != nil {
-			return err
-		}
-	}
+	errs = append(errs, c.Etcd.validate(field.NewPath("etcd"))...)
 
+	ingressPath := field.NewPath("ingress")
 	switch c.Ingress.Status {
 	case StatusManaged, StatusRemoved:
 	default:
-		return fmt.Errorf("unsupported ingress.status value %v", c.Ingress.Status)
+		errs = append(errs, field.NotSupported(ingressPath.Child("status"), c.Ingress.Status,
+			[]string{string(StatusManaged), string(StatusRemoved)}))
 	}
 
 	switch c.Ingress.AdmissionPolicy.NamespaceOwnership {
 	case NamespaceOwnershipAllowed, NamespaceOwnershipStrict:
 	default:
-		return fmt.Errorf("unsupported namespaceOwnership value %v", c.Ingress.AdmissionPolicy.NamespaceOwnership)
+		errs = append(errs, field.NotSupported(ingressPath.Child("routeAdmissionPolicy", "namespaceOwnership"),
+			c.Ingress.AdmissionPolicy.NamespaceOwnership,
+			[]string{string(NamespaceOwnershipAllowed), string(NamespaceOwnershipStrict)}))
 	}
 
 	if c.Ingress.Ports.Http != nil && (*c.Ingress.Ports.Http < 1 || *c.Ingress.Ports.Http > math.MaxUint16) {
-		return fmt.Errorf("unsupported value %v for ingress.ports.http", *c.Ingress.Ports.Http)
+		errs = append(errs, field.Invalid(ingressPath.Child("ports", "http"), *c.Ingress.Ports.Http, "must be a valid port number"))
 	}
 	if c.Ingress.Ports.Https != nil && (*c.Ingress.Ports.Https < 1 || *c.Ingress.Ports.Https > math.MaxUint16) {
-		return fmt.Errorf("unsupported value %v for ingress.ports.https", *c.Ingress.Ports.Https)
+		errs = append(errs, field.Invalid(ingressPath.Child("ports", "https"), *c.Ingress.Ports.Https, "must be a valid port number"))
 	}
 
 	if len(c.Ingress.ListenAddress) != 0 {
-		if err := validateRouterListenAddress(c.Ingress.ListenAddress, c.ApiServer.AdvertiseAddresses, c.ApiServer.SkipInterface, c.IsIPv4(), c.IsIPv6()); err != nil {
-			return fmt.Errorf("error validating ingress.listenAddress: %w", err)
-		}
-	}
-	if err := validateAuditLogConfig(c.ApiServer.AuditLog); err != nil {
-		return fmt.Errorf("error validating apiserver.auditLog:\n%w", err)
+		errs = append(errs, validateRouterListenAddress(ingressPath.Child("listenAddress"), c.Ingress.ListenAddress,
+			c.ApiServer.AdvertiseAddresses, c.ApiServer.SkipInterface, c.IsIPv4(), c.IsIPv6(), c.offline)...)
 	}
 
+	errs = append(errs, validateAuditLogConfig(apiServerPath.Child("auditLog"), c.ApiServer.AuditLog)...)
+	errs = append(errs, validateKubeletOwnedFields(field.NewPath("kubelet"), c.Kubelet)...)
+
 	if err := validateNodeIPv6Address(c.Node.NodeIPV6, c.IsIPv4() && c.IsIPv6()); err != nil {
-		return fmt.Errorf("error validating node.nodeIPv6: %w", err)
+		errs = append(errs, field.Invalid(field.NewPath("node", "nodeIPv6"), c.Node.NodeIPV6, err.Error()))
 	}
 
-	if errs := c.Storage.IsValid(); c.Storage.IsEnabled() && len(errs) > 0 {
-		return fmt.Errorf("error validating storage: %w", errors.Join(errs...))
+	if c.Storage.IsEnabled() {
+		errs = append(errs, c.Storage.validate(field.NewPath("storage"))...)
 	}
 
-	if err := c.ApiServer.TLS.Validate(); err != nil {
-		return fmt.Errorf("error validating apiServer.tls: %v", err)
-	}
+	errs = append(errs, c.ApiServer.TLS.validate(apiServerPath.Child("tls"))...)
 
-	return nil
+	errs = append(errs, c.AutoRecovery.validate(field.NewPath("autoRecovery"))...)
+
+	return errs
 }
 
-// AddWarning saves a warning message to be reported later.
-func (c *Config) AddWarning(message string) {
-	c.Warnings = append(c.Warnings, message)
+// validateSubjectAltNames checks that the names don't conflict with the names
+// and addresses used by other certificates.
+func (c *Config) validateSubjectAltNames(path *field.Path) field.ErrorList {
+	// Any entry in SubjectAltNames will be included in the external access certificates.
+	// Any of the hostnames and IPs (except the node IP) listed below conflicts with
+	// other certificates, such as the service network and localhost access.
+	// The node IP is a bit special. Apiserver k8s service, which holds a service IP
+	// gets resolved to the node IP. If we include the node IP in the SAN then we have
+	// an ambiguity, the same IP matches two different certificates and there are errors
+	// when trying to reach apiserver from within the cluster using the service IP.
+	// Apiserver will decide which certificate to return to client hello based on SNI
+	// (which client-go does not use) or raw IP mappings. As soon as there is a match for
+	// the node IP it returns that certificate, which is the external access one. This
+	// breaks all pods trying to reach apiserver, as hostnames dont match and the certificate
+	// is invalid.
+	errs := field.ErrorList{}
+	u, err := url.Parse(c.ApiServer.URL)
+	if err != nil {
+		return append(errs, field.Invalid(field.NewPath("apiServer", "url"), c.ApiServer.URL,
+			fmt.Sprintf("failed to parse cluster URL: %v", err)))
+	}
+	if u.Hostname() == "localhost" || u.Hostname() == "127.0.0.1" {
+		if stringSliceContains(c.ApiServer.SubjectAltNames, "localhost", "127.0.0.1") {
+			errs = append(errs, field.Invalid(path, c.ApiServer.SubjectAltNames, "must not contain localhost, 127.0.0.1"))
+		}
+	} else {
+		if stringSliceContains(c.ApiServer.SubjectAltNames, c.Node.NodeIP) {
+			errs = append(errs, field.Invalid(path, c.ApiServer.SubjectAltNames, "must not contain node IP"))
+		}
+		if !stringSliceContains(c.ApiServer.SubjectAltNames, u.Host) || u.Host != c.Node.HostnameOverride {
+			errs = append(errs, field.Invalid(path, c.ApiServer.SubjectAltNames,
+				fmt.Sprintf("cluster URL host %q must be included in subjectAltNames or nodeName", u.String())))
+		}
+	}
+	if stringSliceContains(
+		c.ApiServer.SubjectAltNames,
+		"kubernetes",
+		"kubernetes.default",
+		"kubernetes.default.svc",
+		"kubernetes.default.svc.cluster.local",
+		"openshift",
+		"openshift.default",
+		"openshift.default.svc",
+		"openshift.default.svc.cluster.local",
+	) {
+		errs = append(errs, field.Invalid(path, c.ApiServer.SubjectAltNames, "must not contain kubernetes service names"))
+	}
+	if stringSliceContains(
+		c.ApiServer.SubjectAltNames,
+		c.ApiServer.AdvertiseAddresses...,
+	) {
+		errs = append(errs, field.Invalid(path, c.ApiServer.SubjectAltNames, "must not contain apiserver advertise address IPs"))
+	}
+	return errs
 }
 
 // UserNodeIP return the user configured NodeIP, or "" if it's unset.
@@ -639,34 +693,39 @@ func checkAdvertiseAddressConfigured(advertiseAddress string) error {
 	return fmt.Errorf("Advertise address: %s not present in any interface", advertiseAddress)
 }
 
-func validateRouterListenAddress(ingressListenAddresses []string, advertiseAddresses []string, skipInterface bool, ipv4, ipv6 bool) error {
-	addresses, err := AllowedListeningIPAddresses(ipv4, ipv6)
-	if err != nil {
-		return err
-	}
-	nicNames, err := AllowedNICNames()
-	if err != nil {
-		return err
+func validateRouterListenAddress(path *field.Path, ingressListenAddresses []string, advertiseAddresses []string, skipInterface bool, ipv4, ipv6 bool, offline bool) field.ErrorList {
+	errs := field.ErrorList{}
+	var addresses, nicNames []string
+	if !offline {
+		var err error
+		if addresses, err = AllowedListeningIPAddresses(ipv4, ipv6); err != nil {
+			return append(errs, field.InternalError(path, err))
+		}
+		if nicNames, err = AllowedNICNames(); err != nil {
+			return append(errs, field.InternalError(path, err))
+		}
 	}
-	for _, entry := range ingressListenAddresses {
+	for i, entry := range ingressListenAddresses {
 		if slices.Contains(advertiseAddresses, entry) && !skipInterface {
 			continue
 		}
 		ip := net.ParseIP(entry)
 		if ip == nil {
-			if slices.Contains(nicNames, entry) {
+			if offline || slices.Contains(nicNames, entry) {
 				continue
 			}
-			return fmt.Errorf("interface %v not present in the host", entry)
+			errs = append(errs, field.Invalid(path.Index(i), entry, "interface not present in the host"))
+			continue
 		}
 		if (ip.To4() != nil && !ipv4) || (ip.To4() == nil && !ipv6) {
-			return fmt.Errorf("IP %v does not match family of service/cluster network", entry)
+			errs = append(errs, field.Invalid(path.Index(i), entry, "IP does not match family of service/cluster network"))
+			continue
 		}
-		if !slices.Contains(addresses, entry) {
-			return fmt.Errorf("IP %v not present in any of the host's interfaces", entry)
+		if !offline && !slices.Contains(addresses, entry) {
+			errs = append(errs, field.Invalid(path.Index(i), entry, "IP not present in any of the host's interfaces"))
 		}
 	}
-	return nil
+	return errs
 }
 
 func getForbiddenIPs() ([]*net.IPNet, error) {
@@ -755,46 +814,55 @@ func AllowedNICNames() ([]string, error) {
 	return names, nil
 }
 
-func validateAuditLogConfig(cfg AuditLog) error {
-	// compose a list of errors so that multiple executions are not required to detect each invalid value individually
-	errs := make([]error, 0)
+func validateAuditLogConfig(path *field.Path, cfg AuditLog) field.ErrorList {
+	errs := field.ErrorList{}
 	if cfg.Profile != "" {
 		if _, err := apiserver.GetPolicy(cfg.Profile); err != nil {
-			errs = append(errs, fmt.Errorf("invalid value for apiserver.auditlog.profile: %v", err))
+			errs = append(errs, field.Invalid(path.Child("profile"), cfg.Profile, err.Error()))
 		}
 	}
 	if cfg.MaxFiles < 0 {
-		errs = append(errs, fmt.Errorf("invalid value for apiserver.auditlog.maxFiles, expected value >=0"))
+		errs = append(errs, field.Invalid(path.Child("maxFiles"), cfg.MaxFiles, "must be greater than or equal to 0"))
 	}
 	if cfg.MaxFileAge < 0 {
-		errs = append(errs, fmt.Errorf("invalid value for apiserver.auditlog.maxFileAge, expected value >=0"))
+		errs = append(errs, field.Invalid(path.Child("maxFileAge"), cfg.MaxFileAge, "must be greater than or equal to 0"))
 	}
 	if cfg.MaxFileSize < 0 {
-		errs = append(errs, fmt.Errorf("invalid value for apiserver.auditlog.maxFileSize, expected value >=0"))
+		errs = append(errs, field.Invalid(path.Child("maxFileSize"), cfg.MaxFileSize, "must be greater than or equal to 0"))
 	}
-	return errors.Join(errs...) // Join returns nil if len(errs) == 0
+	return errs
 }
 
-func validateNetworkStack(cfg *Config) error {
+func validateNetworkStack(cfg *Config) field.ErrorList {
+	errs := field.ErrorList{}
+	clusterNetworkPath := field.NewPath("network", "clusterNetwork")
+	serviceNetworkPath := field.NewPath("network", "serviceNetwork")
 	if len(cfg.Network.ClusterNetwork) != len(cfg.Network.ServiceNetwork) {
-		return fmt.Errorf("network.serviceNetwork and network.clusterNetwork have different cardinality")
+		return append(errs, field.Invalid(serviceNetworkPath, cfg.Network.ServiceNetwork,
+			"must have the same number of entries as network.clusterNetwork"))
 	}
 	if len(cfg.Network.ServiceNetwork) > 2 {
-		return fmt.Errorf("network.serviceNetwork can not have more than 2 entries")
+		return append(errs, field.TooMany(serviceNetworkPath, len(cfg.Network.ServiceNetwork), 2))
 	}
 	ipv4Entries := 0
 	ipv6Entries := 0
 	for i := 0; i < len(cfg.Network.ClusterNetwork); i++ {
-		_, _, err := net.ParseCIDR(cfg.Network.ServiceNetwork[i])
-		if err != nil {
-			return fmt.Errorf("invalid format in network.ServiceNetwork[%d]: %w", i, err)
+		valid := true
+		if _, _, err := net.ParseCIDR(cfg.Network.ServiceNetwork[i]); err != nil {
+			errs = append(errs, field.Invalid(serviceNetworkPath.Index(i), cfg.Network.ServiceNetwork[i], err.Error()))
+			valid = false
 		}
-		_, _, err = net.ParseCIDR(cfg.Network.ClusterNetwork[i])
-		if err != nil {
-			return fmt.Errorf("invalid format in network.ClusterNetwork[%d]: %w", i, err)
+		if _, _, err := net.ParseCIDR(cfg.Network.ClusterNetwork[i]); err != nil {
+			errs = append(errs, field.Invalid(clusterNetworkPath.Index(i), cfg.Network.ClusterNetwork[i], err.Error()))
+			valid = false
+		}
+		if !valid {
+			continue
 		}
 		if netutils.IPFamilyOfCIDRString(cfg.Network.ServiceNetwork[i]) != netutils.IPFamilyOfCIDRString(cfg.Network.ClusterNetwork[i]) {
-			return fmt.Errorf("mismatched IP families in network.ServiceNetwork[%d] and network.ClusterNetwork[%d]", i, i)
+			errs = append(errs, field.Invalid(serviceNetworkPath.Index(i), cfg.Network.ServiceNetwork[i],
+				fmt.Sprintf("IP family does not match network.clusterNetwork[%d]", i)))
+			continue
 		}
 		if netutils.IPFamilyOfCIDRString(cfg.Network.ServiceNetwork[i]) == netutils.IPv4 {
 			ipv4Entries++
@@ -803,12 +871,14 @@ func validateNetworkStack(cfg *Config) error {
 		}
 	}
 	if ipv4Entries > 1 || ipv6Entries > 1 {
-		return fmt.Errorf("invalid number of entries of the same IP family in network.serviceNetwork and network.clusterNetwork")
+		errs = append(errs, field.Invalid(serviceNetworkPath, cfg.Network.ServiceNetwork,
+			"must not have multiple entries of the same IP family"))
 	}
-	if netutils.IPFamilyOfString(cfg.ApiServer.AdvertiseAddress) != netutils.IPFamilyOfCIDRString(cfg.Network.ServiceNetwork[0]) {
-		return fmt.Errorf("invalid IP family in apiServer.AdvertiseAddress: does not match first network.ServiceNetwork IP family")
+	if len(errs) == 0 && netutils.IPFamilyOfString(cfg.ApiServer.AdvertiseAddress) != netutils.IPFamilyOfCIDRString(cfg.Network.ServiceNetwork[0]) {
+		errs = append(errs, field.Invalid(field.NewPath("apiServer", "advertiseAddress"), cfg.ApiServer.AdvertiseAddress,
+			"IP family does not match the first entry of network.serviceNetwork"))
 	}
-	return nil
+	return errs
 }
 
 func firstIPFromNextSubnet(subnet string) (string, error) {
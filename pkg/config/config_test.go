@@ -2,12 +2,15 @@ package config
 
 import (
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/utils/ptr"
 )
 
@@ -197,7 +200,7 @@ func TestGetActiveConfigFromYAML(t *testing.T) {
 				c.computeLoggingSetting()
 				// We expect a warning, but do not want to check for
 				// the message string.
-				c.Warnings = []string{}
+				c.Warnings = []Warning{}
 				return c
 			}(),
 		},
@@ -214,6 +217,25 @@ func TestGetActiveConfigFromYAML(t *testing.T) {
 				return c
 			}(),
 		},
+		{
+			name: "etcd-tuning",
+			config: dedent(`
+            etcd:
+              quotaBackendBytes: 536870912
+              minDefragBytes: 10485760
+              maxFragmentedPercentage: 30
+              defragCheckFreq: 1m
+            `),
+			expected: func() *Config {
+				c := mkDefaultConfig()
+				c.Etcd.QuotaBackendBytes = 512 * 1024 * 1024
+				c.Etcd.MinDefragBytes = 10 * 1024 * 1024
+				c.Etcd.MaxFragmentedPercentage = 30
+				c.Etcd.DefragCheckFreq = metav1.Duration{Duration: time.Minute}
+				assert.NoError(t, c.updateComputedValues())
+				return c
+			}(),
+		},
 		{
 			name: "manifests-default",
 			config: dedent(`
@@ -386,19 +408,35 @@ func TestGetActiveConfigFromYAML(t *testing.T) {
 				}
 				return c
 			}(),
+		}, {
+			name: "auto-recovery",
+			config: dedent(`
+			autoRecovery:
+			  storage: /var/lib/microshift-auto-recovery
+			  window: 1h
+			`),
+			expected: func() *Config {
+				c := mkDefaultConfig()
+				c.AutoRecovery = AutoRecovery{
+					Storage:         "/var/lib/microshift-auto-recovery",
+					MaxFailedStarts: 3,
+					Window:          metav1.Duration{Duration: time.Hour},
+				}
+				return c
+			}(),
 		},
 	}
 
 	for _, tt := range ttests {
 		t.Run(tt.name, func(t *testing.T) {
-			config, err := getActiveConfigFromYAMLDropins([][]byte{[]byte(tt.config)})
+			config, err := getActiveConfigFromYAMLDropins([][]byte{[]byte(tt.config)}, DefaultPaths())
 			// If we have any warnings, drop them. Use an empty array
 			// instead of nil so that we can differentiate between
 			// unexpected warnings (where we get an array instead of
 			// nil) and missing expected warnings (where we get nil
 			// but expect an array).
 			if config.Warnings != nil {
-				config.Warnings = []string{}
+				config.Warnings = []Warning{}
 			}
 
 			if tt.expectErr && err == nil {
@@ -483,7 +521,7 @@ func TestGetActiveConfigFromYAML(t *testing.T) {
 			},
 		}
 
-		config, err := getActiveConfigFromYAMLDropins(dropins)
+		config, err := getActiveConfigFromYAMLDropins(dropins, DefaultPaths())
 		assert.NoError(t, err)
 
 		config.userSettings = nil
@@ -492,6 +530,36 @@ func TestGetActiveConfigFromYAML(t *testing.T) {
 }
 
 // Test the validation logic
+func TestEtcdQuotaExceedsDisk(t *testing.T) {
+	// The free space changes over time, so a quota accepted before only warns on start.
+	cfg, err := getActiveConfigFromYAMLDropins([][]byte{[]byte("etcd:\n  quotaBackendBytes: 9223372036854775807\n")}, DefaultPaths())
+	assert.NoError(t, err)
+	if assert.Len(t, cfg.Warnings, 1) {
+		assert.Equal(t, WarningEtcdQuotaExceedsDisk, cfg.Warnings[0].Code)
+		assert.Equal(t, "etcd.quotaBackendBytes", cfg.Warnings[0].Path)
+	}
+
+	// 'config validate' reports it as an error.
+	dir := t.TempDir()
+	main := writeConfigFile(t, filepath.Join(dir, "config.yaml"), "etcd:\n  quotaBackendBytes: 9223372036854775807\n")
+	report, err := ValidateFiles(ValidateOptions{Files: []string{main}, DataDir: filepath.Join(dir, "data")})
+	assert.NoError(t, err)
+	found := false
+	for _, issue := range report.Issues {
+		if issue.Path == "etcd.quotaBackendBytes" {
+			found = true
+			assert.Equal(t, SeverityError, issue.Severity)
+			assert.Contains(t, issue.Message, "must not exceed the disk space")
+		}
+	}
+	assert.True(t, found, "quota exceeding the disk must be an error: %v", report.Issues)
+
+	// The default quota is not checked, only the one set by the user.
+	cfg = NewDefault()
+	cfg.Etcd.QuotaBackendBytes = math.MaxInt64
+	assert.Nil(t, cfg.checkEtcdQuotaFitsOnDisk())
+}
+
 func TestValidate(t *testing.T) {
 	mkDefaultConfig := func() *Config {
 		c := NewDefault()
@@ -554,6 +622,42 @@ func TestValidate(t *testing.T) {
 			}(),
 			expectErr: false,
 		},
+		{
+			name: "etcd-quota-low",
+			config: func() *Config {
+				c := mkDefaultConfig()
+				c.Etcd.QuotaBackendBytes = 100 * 1024 * 1024
+				return c
+			}(),
+			expectErr: true,
+		},
+		{
+			name: "etcd-min-defrag-above-quota",
+			config: func() *Config {
+				c := mkDefaultConfig()
+				c.Etcd.MinDefragBytes = c.Etcd.QuotaBackendBytes
+				return c
+			}(),
+			expectErr: true,
+		},
+		{
+			name: "etcd-fragmented-percentage-above-100",
+			config: func() *Config {
+				c := mkDefaultConfig()
+				c.Etcd.MaxFragmentedPercentage = 101
+				return c
+			}(),
+			expectErr: true,
+		},
+		{
+			name: "etcd-defrag-check-freq-negative",
+			config: func() *Config {
+				c := mkDefaultConfig()
+				c.Etcd.DefragCheckFreq = metav1.Duration{Duration: -time.Minute}
+				return c
+			}(),
+			expectErr: true,
+		},
 		{
 			name: "advertise-address-not-present",
 			config: func() *Config {
@@ -800,6 +904,24 @@ func TestValidate(t *testing.T) {
 			}(),
 			expectErr: true,
 		},
+		{
+			name: "auto-recovery-relative-storage",
+			config: func() *Config {
+				c := mkDefaultConfig()
+				c.AutoRecovery.Storage = "microshift-auto-recovery"
+				return c
+			}(),
+			expectErr: true,
+		},
+		{
+			name: "auto-recovery-max-failed-starts-invalid",
+			config: func() *Config {
+				c := mkDefaultConfig()
+				c.AutoRecovery.MaxFailedStarts = -1
+				return c
+			}(),
+			expectErr: true,
+		},
 	}
 	for _, tt := range ttests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -911,6 +1033,9 @@ func TestMicroshiftConfigNodeNameValidationFromDefault(t *testing.T) {
 	if err := c.validateNodeName(IS_DEFAULT_NODENAME, dataDir); err != nil {
 		t.Errorf("validation should have failed in this case, it must be a warning in logs: %v", err)
 	}
+	if len(c.Warnings) != 1 || c.Warnings[0].Code != WarningNodeNameChangedByHostname {
+		t.Errorf("expected a %s warning, got %v", WarningNodeNameChangedByHostname, c.Warnings)
+	}
 }
 
 func TestMicroshiftConfigNodeNameValidationBadName(t *testing.T) {
@@ -3,6 +3,8 @@ package config
 import (
 	"fmt"
 	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
 )
 
 // Use the upper-case version of the word to match the kubebuilder
@@ -29,7 +31,7 @@ func (c *Config) computeLoggingSetting() {
 	_, ok := logLevelNames[strings.ToLower(c.Debugging.LogLevel)]
 	if !ok {
 		if c.Debugging.LogLevel != "" {
-			c.AddWarning(fmt.Sprintf("Unrecognized log level %q, defaulting to %q",
+			c.AddWarning(WarningUnrecognizedLogLevel, field.NewPath("debugging", "logLevel"), fmt.Sprintf("Unrecognized log level %q, defaulting to %q",
 				c.Debugging.LogLevel, defaultLogLevel))
 		}
 		// Reset the value so that `show-config` reports the value
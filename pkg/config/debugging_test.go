@@ -77,6 +77,11 @@ func TestGetVerbosity(t *testing.T) {
 			verbosity := config.GetVerbosity()
 			assert.Equal(t, tt.level, verbosity)
 			assert.Equal(t, tt.warnings, len(config.Warnings))
+			for _, w := range config.Warnings {
+				assert.Equal(t, WarningUnrecognizedLogLevel, w.Code)
+				assert.Equal(t, SeverityWarning, w.Severity)
+				assert.Equal(t, "debugging.logLevel", w.Path)
+			}
 		})
 	}
 }
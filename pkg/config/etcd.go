@@ -1,11 +1,30 @@
 package config
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
 
 const (
 	// Etcd performance degrades significantly if the memory available
 	// is less than 128MB, enforce this minimum.
 	EtcdMinimumMemoryLimit = 128
+
+	// MicroShift's own resources need about 100MB in etcd's database,
+	// enforce a minimum quota leaving room for the workloads.
+	EtcdMinimumQuotaBackendBytes = 256 * 1024 * 1024
+
+	defaultEtcdQuotaBackendBytes       = 8 * 1024 * 1024 * 1024
+	defaultEtcdMinDefragBytes          = 100 * 1024 * 1024
+	defaultEtcdMaxFragmentedPercentage = 45
+	defaultEtcdDefragCheckFreq         = 5 * time.Minute
 )
 
 type EtcdConfig struct {
@@ -14,16 +33,95 @@ type EtcdConfig struct {
 	MemoryLimitMB uint64 `json:"memoryLimitMB"`
 
 	// The limit on the size of the etcd database; etcd will start
-	// failing writes if its size on disk reaches this value
-	QuotaBackendBytes int64 `json:"-"`
+	// failing writes if its size on disk reaches this value.
+	// Must be at least 268435456 (256MB) and fit on the disk with MicroShift's data.
+	// Defaults to 8589934592 (8GB).
+	// +kubebuilder:default=8589934592
+	QuotaBackendBytes int64 `json:"quotaBackendBytes"`
 
 	// If the backend is fragmented more than
 	// `maxFragmentedPercentage` and the database size is greater than
 	// `minDefragBytes`, do a defrag.
-	MinDefragBytes          int64   `json:"-"`
-	MaxFragmentedPercentage float64 `json:"-"`
+	// Defaults to 104857600 (100MB).
+	// +kubebuilder:default=104857600
+	MinDefragBytes int64 `json:"minDefragBytes"`
+
+	// Percentage of the database's size which is fragmented above which
+	// a defrag is done, see `minDefragBytes`. Must be between 1 and 100.
+	// Defaults to 45.
+	// +kubebuilder:default=45
+	MaxFragmentedPercentage int `json:"maxFragmentedPercentage"`
+
+	// How often to check the conditions for defragging, e.g. "1m".
+	// Defaults to "5m".
+	// +kubebuilder:default="5m"
+	// +kubebuilder:validation:Type:=string
+	DefragCheckFreq metav1.Duration `json:"defragCheckFreq"`
+}
+
+func (e EtcdConfig) validate(path *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+	if e.MemoryLimitMB > 0 && e.MemoryLimitMB < EtcdMinimumMemoryLimit {
+		errs = append(errs, field.Invalid(path.Child("memoryLimitMB"), e.MemoryLimitMB,
+			fmt.Sprintf("must not be below the minimum allowed %d", EtcdMinimumMemoryLimit)))
+	}
+	if e.QuotaBackendBytes < EtcdMinimumQuotaBackendBytes {
+		errs = append(errs, field.Invalid(path.Child("quotaBackendBytes"), e.QuotaBackendBytes,
+			fmt.Sprintf("must not be below the minimum allowed %d", EtcdMinimumQuotaBackendBytes)))
+	}
+	if e.MinDefragBytes < 0 {
+		errs = append(errs, field.Invalid(path.Child("minDefragBytes"), e.MinDefragBytes, "must not be negative"))
+	} else if e.MinDefragBytes >= e.QuotaBackendBytes {
+		errs = append(errs, field.Invalid(path.Child("minDefragBytes"), e.MinDefragBytes,
+			"must be lower than quotaBackendBytes"))
+	}
+	if e.MaxFragmentedPercentage <= 0 || e.MaxFragmentedPercentage > 100 {
+		errs = append(errs, field.Invalid(path.Child("maxFragmentedPercentage"), e.MaxFragmentedPercentage,
+			"must be between 1 and 100"))
+	}
+	if e.DefragCheckFreq.Duration <= 0 {
+		errs = append(errs, field.Invalid(path.Child("defragCheckFreq"), e.DefragCheckFreq.Duration.String(),
+			"must be a positive duration"))
+	}
+	return errs
+}
+
+// checkEtcdQuotaFitsOnDisk checks the quota set by the user against the free disk space.
+// The default quota is not checked, to not prevent existing installations on small disks
+// from starting. It isn't part of validateFields, because the free space changes over time:
+// 'config validate' reports it as an error, the other commands as a warning.
+func (c *Config) checkEtcdQuotaFitsOnDisk() *field.Error {
+	if c.userSettings == nil || c.userSettings.Etcd.QuotaBackendBytes == 0 || c.offline {
+		return nil
+	}
+	return validateEtcdQuotaFitsOnDisk(field.NewPath("etcd", "quotaBackendBytes"), c.Etcd.QuotaBackendBytes, c.Paths.DataDir)
+}
+
+// validateEtcdQuotaFitsOnDisk checks that etcd's database can grow up to the quota
+// on the file system holding the data directory, taking into account the space
+// the existing database already occupies.
+func validateEtcdQuotaFitsOnDisk(path *field.Path, quota int64, dataDir string) *field.Error {
+	// The data directory doesn't exist before the first start.
+	dir := dataDir
+	for {
+		if _, err := os.Stat(dir); err == nil || !errors.Is(err, os.ErrNotExist) || dir == filepath.Dir(dir) {
+			break
+		}
+		dir = filepath.Dir(dir)
+	}
+
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return field.InternalError(path, fmt.Errorf("failed to get free space of %q: %w", dir, err))
+	}
+	available := int64(stat.Bavail) * int64(stat.Bsize)
+	if info, err := os.Stat(filepath.Join(dataDir, "etcd", "member", "snap", "db")); err == nil {
+		available += info.Size()
+	}
 
-	// How often to check the conditions for defragging (0 means no
-	// defrags, except for a single on startup).
-	DefragCheckFreq time.Duration `json:"-"`
+	if quota > available {
+		return field.Invalid(path, quota,
+			fmt.Sprintf("must not exceed the disk space available for etcd's database in %q (%d)", dataDir, available))
+	}
+	return nil
 }
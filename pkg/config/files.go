@@ -6,32 +6,78 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 
 	jsonpatch "github.com/evanphx/json-patch"
 	"github.com/openshift/microshift/pkg/util"
+	yamlv3 "gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	"sigs.k8s.io/yaml"
 )
 
-const (
-	ConfigFile      = "/etc/microshift/config.yaml"
-	DataDir         = "/var/lib/microshift"
-	BackupsDir      = "/var/lib/microshift-backups"
-	ConfigDropInDir = "/etc/microshift/config.d"
-)
+func getActiveConfigFromYAMLDropins(yamlDropins [][]byte, paths Paths) (*Config, error) {
+	mergedUserConfigPatch, warnings, err := mergeYAMLDropins(yamlDropins)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &Config{}
+	if err := cfg.fillDefaults(); err != nil {
+		return nil, fmt.Errorf("failed to fill config's defaults: %w", err)
+	}
+	cfg.Paths = paths
+	cfg.Warnings = append(cfg.Warnings, warnings...)
+
+	if len(mergedUserConfigPatch) != 0 {
+		userSettings := &Config{}
+		if err := json.Unmarshal(mergedUserConfigPatch, userSettings); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal user cfg json to config: %w", err)
+		}
+		cfg.incorporateUserSettings(userSettings)
+	}
+
+	if err := cfg.updateComputedValues(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	if e := cfg.checkEtcdQuotaFitsOnDisk(); e != nil {
+		cfg.AddWarning(WarningEtcdQuotaExceedsDisk, field.NewPath("etcd", "quotaBackendBytes"), e.Detail)
+	}
+
+	return cfg, nil
+}
 
-func getActiveConfigFromYAMLDropins(yamlDropins [][]byte) (*Config, error) {
+// mergeYAMLDropins converts YAMLs to JSONs and merges them together
+// to get a single configuration patch from the user. Deprecated keys are
+// migrated, and warnings are returned for them and for the unknown keys.
+func mergeYAMLDropins(yamlDropins [][]byte) ([]byte, []Warning, error) {
 	var mergedUserConfigPatch []byte
+	warnings := []Warning{}
+	reported := map[Warning]bool{}
 
-	// Convert YAMLs to JSONs and merge them together to get a single configuration from the user.
 	for _, dropin := range yamlDropins {
 		if strings.TrimSpace(string(dropin)) == "" {
 			continue
 		}
 
-		jsonDropin, err := yaml.YAMLToJSON(dropin)
+		migrated, dropinWarnings, err := migrateDropin(dropin)
 		if err != nil {
-			return nil, fmt.Errorf("failed to convert config yaml (%q) to json: %w", string(dropin), err)
+			return nil, nil, fmt.Errorf("failed to parse config yaml (%q): %w", string(dropin), err)
+		}
+		for _, w := range dropinWarnings {
+			if !reported[w] {
+				reported[w] = true
+				warnings = append(warnings, w)
+			}
+		}
+
+		jsonDropin, err := yaml.YAMLToJSON(migrated)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to convert config yaml (%q) to json: %w", string(migrated), err)
 		}
 
 		if mergedUserConfigPatch == nil {
@@ -41,87 +87,109 @@ func getActiveConfigFromYAMLDropins(yamlDropins [][]byte) (*Config, error) {
 
 		patched, err := jsonpatch.MergePatch(mergedUserConfigPatch, jsonDropin)
 		if err != nil {
-			return nil, fmt.Errorf("failed to merge dropin (%q) into the config patch (%q): %w", string(jsonDropin), string(mergedUserConfigPatch), err)
+			return nil, nil, fmt.Errorf("failed to merge dropin (%q) into the config patch (%q): %w", string(jsonDropin), string(mergedUserConfigPatch), err)
 		}
 		mergedUserConfigPatch = patched
 	}
+	return mergedUserConfigPatch, warnings, nil
+}
 
-	cfg := &Config{}
-	if err := cfg.fillDefaults(); err != nil {
-		return nil, fmt.Errorf("failed to fill config's defaults: %w", err)
+// migrateDropin migrates the deprecated keys of the dropin, and returns
+// warnings about them and about the keys which are not fields of the Config.
+func migrateDropin(dropin []byte) ([]byte, []Warning, error) {
+	migrated, migrations, err := migrateYAML(dropin)
+	if err != nil {
+		return nil, nil, err
+	}
+	warnings := []Warning{}
+	for _, m := range migrations {
+		warnings = append(warnings, m.warning())
 	}
 
-	if len(mergedUserConfigPatch) != 0 {
-		userSettings := &Config{}
-		if err := json.Unmarshal(mergedUserConfigPatch, userSettings); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal user cfg json to config: %w", err)
+	doc := &yamlv3.Node{}
+	if err := yamlv3.Unmarshal(migrated, doc); err != nil {
+		return nil, nil, err
+	}
+	if len(doc.Content) != 0 {
+		src := &configSource{lines: map[string]int{}}
+		for _, issue := range src.walk(doc.Content[0], nil, reflect.TypeOf(Config{})) {
+			warnings = append(warnings, Warning{Code: issue.Code, Severity: issue.Severity, Path: issue.Path, Message: issue.Message})
 		}
-		cfg.incorporateUserSettings(userSettings)
 	}
+	return migrated, warnings, nil
+}
 
-	if err := cfg.updateComputedValues(); err != nil {
-		return nil, fmt.Errorf("invalid configuration: %w", err)
+// collectUserProvidedConfigs loads all the user provided yaml config files:
+// - main MicroShift config (/etc/microshift/config.yaml by default), and
+// - YAML files from config drop-in directory (/etc/microshift/config.d by default)
+func collectUserProvidedConfigs(p Paths) ([][]byte, error) {
+	paths, err := userProvidedConfigPaths(p)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := cfg.validate(); err != nil {
-		return nil, fmt.Errorf("invalid configuration: %w", err)
+	dropins := make([][]byte, 0, len(paths))
+	for _, path := range paths {
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading config file %q: %v", path, err)
+		}
+		dropins = append(dropins, contents)
 	}
-
-	return cfg, nil
+	return dropins, nil
 }
 
-// collectUserProvidedConfigs loads all the user provided yaml config files:
-// - main MicroShift config (/etc/microshift/config.yaml), and
-// - YAML files from config drop-in directory (/etc/microshift/config.d)
-func collectUserProvidedConfigs() ([][]byte, error) {
-	dropins := [][]byte{}
+// userProvidedConfigPaths returns paths of the existing user provided
+// config files in the order they are merged.
+func userProvidedConfigPaths(p Paths) ([]string, error) {
+	paths := []string{}
 
-	if exists, err := util.PathExists(ConfigFile); err != nil {
+	if exists, err := util.PathExists(p.ConfigFile); err != nil {
 		return nil, err
 	} else if exists {
-		contents, err := os.ReadFile(ConfigFile)
-		if err != nil {
-			return nil, fmt.Errorf("error reading config file %q: %v", ConfigFile, err)
-		}
-		dropins = append(dropins, contents)
+		paths = append(paths, p.ConfigFile)
 	}
 
-	dropInDirExists, err := util.PathExistsAndIsNotEmpty(ConfigDropInDir)
+	dropInDirExists, err := util.PathExistsAndIsNotEmpty(p.ConfigDropInDir)
 	if err != nil {
 		return nil, err
 	}
-
-	if !dropInDirExists {
-		return dropins, nil
+	if dropInDirExists {
+		dropins, err := dropInFilePaths(p.ConfigDropInDir)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, dropins...)
 	}
+	return paths, nil
+}
 
-	err = filepath.WalkDir(ConfigDropInDir, func(path string, info fs.DirEntry, err error) error {
+// dropInFilePaths returns paths of the YAML files of the drop-in directory
+// in the order they are merged.
+func dropInFilePaths(dir string) ([]string, error) {
+	paths := []string{}
+	err := filepath.WalkDir(dir, func(path string, info fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
 		if !info.IsDir() && filepath.Ext(info.Name()) == ".yaml" {
-			contents, err := os.ReadFile(path)
-			if err != nil {
-				return fmt.Errorf("error reading config file %q: %v", path, err)
-			}
-			dropins = append(dropins, contents)
+			paths = append(paths, path)
 		}
 		return nil
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to walk the config drop-in dir %q: %w", ConfigDropInDir, err)
+		return nil, fmt.Errorf("failed to walk the config drop-in dir %q: %w", dir, err)
 	}
-
-	return dropins, nil
+	return paths, nil
 }
 
 // ActiveConfig returns the active configuration which is default config with overrides
-// from user provided config files.
-func ActiveConfig() (*Config, error) {
-	dropins, err := collectUserProvidedConfigs()
+// from user provided config files found in the paths. The paths are kept in the config.
+func ActiveConfig(paths Paths) (*Config, error) {
+	dropins, err := collectUserProvidedConfigs(paths)
 	if err != nil {
 		return nil, err
 	}
 
-	return getActiveConfigFromYAMLDropins(dropins)
+	return getActiveConfigFromYAMLDropins(dropins, paths)
 }
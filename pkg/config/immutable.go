@@ -0,0 +1,139 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+)
+
+// ImmutableFieldsFileName is the file in the data directory recording
+// the values of the immutable fields established on the first start.
+const ImmutableFieldsFileName = ".immutable-config.yaml"
+
+// immutableFields are the fields which cannot be changed after MicroShift's
+// first start, because the state of the cluster depends on them: IPs of
+// the pods and services, and DNS names of the API server and the routes.
+// The keys are the paths of the fields in the config.
+type immutableFields struct {
+	ClusterNetwork []string `json:"network.clusterNetwork"`
+	ServiceNetwork []string `json:"network.serviceNetwork"`
+	BaseDomain     string   `json:"dns.baseDomain"`
+}
+
+func (c *Config) immutableFields() immutableFields {
+	return immutableFields{
+		ClusterNetwork: c.Network.ClusterNetwork,
+		ServiceNetwork: c.Network.ServiceNetwork,
+		BaseDomain:     c.DNS.BaseDomain,
+	}
+}
+
+func immutableFieldsPath(dataDir string) string {
+	return filepath.Join(dataDir, ImmutableFieldsFileName)
+}
+
+// ImmutableFieldChange is a change of an immutable field since the values were established.
+type ImmutableFieldChange struct {
+	Field    string
+	Previous string
+	Current  string
+}
+
+func (c ImmutableFieldChange) String() string {
+	return fmt.Sprintf("%s: %s -> %s", c.Field, c.Previous, c.Current)
+}
+
+// ImmutableFieldChanges compares the immutable fields with the values established
+// on the first start, persisted in the data directory. If the values were not
+// established yet, the current values are persisted and no changes are returned.
+func (c *Config) ImmutableFieldChanges() ([]ImmutableFieldChange, error) {
+	path := immutableFieldsPath(c.Paths.DataDir)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		klog.InfoS("Establishing values of immutable config fields", "path", path)
+		return nil, c.PersistImmutableFields()
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read immutable config fields: %w", err)
+	}
+
+	previous := map[string]any{}
+	if err := yaml.Unmarshal(data, &previous); err != nil {
+		return nil, fmt.Errorf("failed to parse immutable config fields %q: %w", path, err)
+	}
+	current, err := toGeneric(c.immutableFields())
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []ImmutableFieldChange{}
+	established := true
+	for field, value := range current.(map[string]any) {
+		prev, ok := previous[field]
+		if !ok {
+			established = false
+			continue
+		}
+		if !reflect.DeepEqual(prev, value) {
+			changes = append(changes, ImmutableFieldChange{Field: field, Previous: toJSONString(prev), Current: toJSONString(value)})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+
+	// Fields which became immutable since the values were established are established now.
+	if !established && len(changes) == 0 {
+		return changes, c.PersistImmutableFields()
+	}
+	return changes, nil
+}
+
+// PersistImmutableFields establishes the current values of the immutable fields,
+// accepting any changes since they were established.
+func (c *Config) PersistImmutableFields() error {
+	data, err := yaml.Marshal(c.immutableFields())
+	if err != nil {
+		return fmt.Errorf("failed to marshal immutable config fields: %w", err)
+	}
+	if err := os.MkdirAll(c.Paths.DataDir, 0700); err != nil {
+		return fmt.Errorf("failed to create data dir: %w", err)
+	}
+	path := immutableFieldsPath(c.Paths.DataDir)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write immutable config fields %q: %w", path, err)
+	}
+	return nil
+}
+
+// EnsureImmutableFieldsHaveNotChanged fails if any of the immutable fields changed since
+// MicroShift's first start. Changing them would break the existing workloads and the cluster.
+func (c *Config) EnsureImmutableFieldsHaveNotChanged() error {
+	changes, err := c.ImmutableFieldChanges()
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	lines := make([]string, 0, len(changes))
+	for _, change := range changes {
+		lines = append(lines, "  "+change.String())
+	}
+	return fmt.Errorf("configuration fields which cannot be changed after the first start of MicroShift were changed:\n%s\n"+
+		"Revert the changes, or run 'microshift config accept-immutable-changes' to migrate the cluster deliberately",
+		strings.Join(lines, "\n"))
+}
+
+func toJSONString(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(data)
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnsureImmutableFieldsHaveNotChanged(t *testing.T) {
+	cfg := NewDefault()
+	cfg.Paths.DataDir = t.TempDir()
+
+	// First start establishes the values.
+	require.NoError(t, cfg.EnsureImmutableFieldsHaveNotChanged())
+	assert.FileExists(t, immutableFieldsPath(cfg.Paths.DataDir))
+	require.NoError(t, cfg.EnsureImmutableFieldsHaveNotChanged())
+
+	changed := NewDefault()
+	changed.Paths = cfg.Paths
+	changed.Network.ServiceNetwork = []string{"10.44.0.0/16"}
+	changed.DNS.BaseDomain = "example.org"
+	changes, err := changed.ImmutableFieldChanges()
+	require.NoError(t, err)
+	assert.Equal(t, []ImmutableFieldChange{
+		{Field: "dns.baseDomain", Previous: `"example.com"`, Current: `"example.org"`},
+		{Field: "network.serviceNetwork", Previous: `["10.43.0.0/16"]`, Current: `["10.44.0.0/16"]`},
+	}, changes)
+	err = changed.EnsureImmutableFieldsHaveNotChanged()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `network.serviceNetwork: ["10.43.0.0/16"] -> ["10.44.0.0/16"]`)
+
+	// Accepting the changes establishes the new values.
+	require.NoError(t, changed.PersistImmutableFields())
+	require.NoError(t, changed.EnsureImmutableFieldsHaveNotChanged())
+	require.Error(t, cfg.EnsureImmutableFieldsHaveNotChanged())
+}
+
+func TestImmutableFieldChanges_newField(t *testing.T) {
+	cfg := NewDefault()
+	cfg.Paths.DataDir = t.TempDir()
+	// Values established by a version of MicroShift without the base domain.
+	require.NoError(t, os.WriteFile(immutableFieldsPath(cfg.Paths.DataDir),
+		[]byte("network.clusterNetwork: [10.42.0.0/16]\nnetwork.serviceNetwork: [10.43.0.0/16]\n"), 0600))
+
+	changes, err := cfg.ImmutableFieldChanges()
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+
+	cfg.DNS.BaseDomain = "example.org"
+	changes, err = cfg.ImmutableFieldChanges()
+	require.NoError(t, err)
+	assert.Len(t, changes, 1)
+}
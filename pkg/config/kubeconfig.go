@@ -2,7 +2,7 @@ package config
 
 import "path/filepath"
 
-// KubeConfigID identifies the different kubeconfigs managed in the DataDir
+// KubeConfigID identifies the different kubeconfigs managed in the Paths.DataDir
 type KubeConfigID string
 
 const (
@@ -16,7 +16,7 @@ const (
 
 // KubeConfigPath returns the path to the specified kubeconfig file.
 func (cfg *Config) KubeConfigPath(id KubeConfigID) string {
-	return filepath.Join(DataDir, "resources", string(id), "kubeconfig")
+	return filepath.Join(cfg.Paths.DataDir, "resources", string(id), "kubeconfig")
 }
 
 func (cfg *Config) KubeConfigAdminPath(id string) string {
@@ -24,5 +24,5 @@ func (cfg *Config) KubeConfigAdminPath(id string) string {
 }
 
 func (cfg *Config) KubeConfigRootAdminPath() string {
-	return filepath.Join(DataDir, "resources", string(KubeAdmin))
+	return filepath.Join(cfg.Paths.DataDir, "resources", string(KubeAdmin))
 }
@@ -0,0 +1,35 @@
+package config
+
+import (
+	"sort"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// kubeletOwnedFields are the top-level fields of the kubelet's configuration
+// which MicroShift sets, with the reason. Fields of the kubelet section replace
+// the top-level fields as a whole, so e.g. setting any field of authentication
+// would drop the client CA set by MicroShift.
+var kubeletOwnedFields = map[string]string{
+	"authentication":           "MicroShift sets the client CA and disables anonymous authentication",
+	"clusterDNS":               "MicroShift sets it to the DNS service's IP, see network.serviceNetwork",
+	"clusterDomain":            "MicroShift's DNS serves the cluster.local domain",
+	"containerRuntimeEndpoint": "MicroShift uses CRI-O's socket",
+	"tlsCertFile":              "MicroShift sets the kubelet's serving certificate",
+	"tlsPrivateKeyFile":        "MicroShift sets the kubelet's serving certificate",
+	"volumePluginDir":          "MicroShift sets it to a directory in its data directory",
+}
+
+// validateKubeletOwnedFields rejects the kubelet settings overriding fields MicroShift owns.
+// The kubelet section is validated against the KubeletConfiguration by the node package,
+// which the config package can't depend on.
+func validateKubeletOwnedFields(path *field.Path, kubelet map[string]any) field.ErrorList {
+	errs := field.ErrorList{}
+	for key := range kubelet {
+		if reason, ok := kubeletOwnedFields[key]; ok {
+			errs = append(errs, field.Forbidden(path.Child(key), "must not be overridden: "+reason))
+		}
+	}
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Field < errs[j].Field })
+	return errs
+}
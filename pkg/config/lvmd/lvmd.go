@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 
 	"k8s.io/klog/v2"
@@ -11,7 +12,6 @@ import (
 )
 
 const (
-	RuntimeLvmdConfigFile       = "/var/lib/microshift/lvms/lvmd.yaml"
 	LvmdConfigFileName          = "lvmd.yaml"
 	defaultSockName             = "/run/lvmd/lvmd.socket"
 	defaultRHEL4EdgeVolumeGroup = "microshift"
@@ -22,6 +22,11 @@ const (
 	statusMessageDefaultAvailable    = "Defaulting to the only available volume group"
 )
 
+// RuntimeLvmdConfigFile returns path of the lvmd configuration generated by MicroShift in its data directory.
+func RuntimeLvmdConfigFile(dataDir string) string {
+	return filepath.Join(dataDir, "lvms", LvmdConfigFileName)
+}
+
 // Lvmd stores the read-in or defaulted values of the lvmd configuration and provides the topolvm-node process information
 // about its host's storage environment.
 type Lvmd struct {
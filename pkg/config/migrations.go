@@ -0,0 +1,300 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// keyMigration describes a deprecated key of the configuration file and how
+// to migrate its value. Deprecated keys are migrated when the files are loaded,
+// and by 'microshift config migrate' which rewrites the files.
+type keyMigration struct {
+	// From is the path of the deprecated key, e.g. "cluster.serviceCIDR".
+	From string
+	// To is the path of the key replacing it. Empty if the key was removed.
+	// Equal to From if only the format of the value changed.
+	To string
+	// Convert converts the value to the format of To. It returns false if the
+	// value doesn't need to be migrated. Nil keeps the value as it is.
+	Convert func(value *yaml.Node) (*yaml.Node, bool)
+	// Reason explains why a removed key isn't needed anymore.
+	Reason string
+}
+
+// keyMigrations are applied to every file in order.
+var keyMigrations = []keyMigration{
+	{From: "cluster.clusterCIDR", To: "network.clusterNetwork", Convert: scalarToList},
+	{From: "cluster.serviceCIDR", To: "network.serviceNetwork", Convert: scalarToList},
+	{From: "cluster.serviceNodePortRange", To: "network.serviceNodePortRange"},
+	{From: "cluster.dns", Reason: "the DNS service's IP is computed from network.serviceNetwork"},
+	{From: "cluster.url", Reason: "the API server's URL is computed from the node's IP"},
+	{From: "nodeIP", To: "node.nodeIP"},
+	{From: "nodeName", To: "node.hostnameOverride"},
+	{From: "network.clusterNetwork", To: "network.clusterNetwork", Convert: cidrObjectsToList},
+}
+
+// KeyMigration is a deprecated key found in a configuration file.
+type KeyMigration struct {
+	// From is the path of the deprecated key, To is the path of the key
+	// replacing it, or empty if the key was removed.
+	From string `json:"from"`
+	To   string `json:"to,omitempty"`
+	// Line of the deprecated key in the file.
+	Line int `json:"line,omitempty"`
+	// Conflict is set when the file sets both keys, and the deprecated one is dropped.
+	Conflict bool   `json:"conflict,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+func (m KeyMigration) String() string {
+	switch {
+	case m.To == "":
+		return fmt.Sprintf("%s was removed and is ignored: %s", m.From, m.Reason)
+	case m.To == m.From:
+		return fmt.Sprintf("%s uses a deprecated format", m.From)
+	case m.Conflict:
+		return fmt.Sprintf("%s is deprecated and ignored, because %s is set as well", m.From, m.To)
+	default:
+		return fmt.Sprintf("%s is deprecated, use %s instead", m.From, m.To)
+	}
+}
+
+func (m KeyMigration) warning() Warning {
+	return Warning{
+		Code:     WarningDeprecatedField,
+		Severity: SeverityWarning,
+		Path:     m.From,
+		Message:  m.String() + "; run 'microshift config migrate' to update the configuration files",
+	}
+}
+
+// migrateYAML migrates the deprecated keys of a configuration file. If there
+// are none, the data is returned unchanged, otherwise it is re-encoded with
+// the comments preserved.
+func migrateYAML(data []byte) ([]byte, []KeyMigration, error) {
+	doc := &yaml.Node{}
+	if err := yaml.Unmarshal(data, doc); err != nil {
+		return nil, nil, err
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return data, nil, nil
+	}
+	migrations, comments := migrateNode(doc.Content[0])
+	if len(migrations) == 0 {
+		return data, nil, nil
+	}
+	// Comments of the removed parents, like "cluster", are kept at the head of the file.
+	doc.HeadComment = strings.Join(append([]string{doc.HeadComment}, comments...), "\n")
+	doc.HeadComment = strings.TrimPrefix(doc.HeadComment, "\n")
+
+	b := &bytes.Buffer{}
+	enc := yaml.NewEncoder(b)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return nil, nil, fmt.Errorf("failed to encode migrated config: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, nil, fmt.Errorf("failed to encode migrated config: %w", err)
+	}
+	return b.Bytes(), migrations, nil
+}
+
+// migrateNode applies keyMigrations to the root mapping of a configuration file.
+// It returns the comments of the keys removed without being migrated: the deprecated
+// keys which were dropped, and their parents which became empty.
+func migrateNode(root *yaml.Node) ([]KeyMigration, []string) {
+	migrations := []KeyMigration{}
+	comments := []string{}
+	for _, km := range keyMigrations {
+		from := strings.Split(km.From, ".")
+		parent, i := findKey(root, from)
+		if parent == nil {
+			continue
+		}
+		key, value := parent.Content[i], parent.Content[i+1]
+		if km.Convert != nil {
+			converted, ok := km.Convert(value)
+			if !ok {
+				continue
+			}
+			value = converted
+		}
+		m := KeyMigration{From: km.From, To: km.To, Line: key.Line, Reason: km.Reason}
+
+		if km.To == km.From {
+			parent.Content[i+1] = value
+			migrations = append(migrations, m)
+			continue
+		}
+		comments = append(comments, removeKey(root, from)...)
+		moved := false
+		if km.To != "" {
+			to := strings.Split(km.To, ".")
+			if p, _ := findKey(root, to); p != nil {
+				m.Conflict = true
+			} else {
+				setKey(root, to, key, value)
+				moved = true
+			}
+		}
+		// The comments of a migrated key move with it, those of a dropped key are kept at the head of the file.
+		if !moved && key.HeadComment != "" {
+			comments = append(comments, key.HeadComment)
+		}
+		migrations = append(migrations, m)
+	}
+	return migrations, comments
+}
+
+// findKey returns the mapping holding the key at the path, and the index of the key in its content.
+// Keys are matched case-insensitively, like the fields are when the configuration is decoded.
+func findKey(node *yaml.Node, path []string) (*yaml.Node, int) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if !strings.EqualFold(node.Content[i].Value, path[0]) {
+			continue
+		}
+		if len(path) == 1 {
+			return node, i
+		}
+		if value := node.Content[i+1]; value.Kind == yaml.MappingNode {
+			return findKey(value, path[1:])
+		}
+		return nil, 0
+	}
+	return nil, 0
+}
+
+// removeKey removes the key at the path, and its parents which become empty.
+// It returns the comments of the removed parents.
+func removeKey(node *yaml.Node, path []string) []string {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if !strings.EqualFold(node.Content[i].Value, path[0]) {
+			continue
+		}
+		comments := []string{}
+		if len(path) > 1 {
+			child := node.Content[i+1]
+			comments = removeKey(child, path[1:])
+			if len(child.Content) != 0 {
+				return comments
+			}
+			if node.Content[i].HeadComment != "" {
+				comments = append([]string{node.Content[i].HeadComment}, comments...)
+			}
+		}
+		node.Content = append(node.Content[:i], node.Content[i+2:]...)
+		return comments
+	}
+	return nil
+}
+
+// setKey sets the value at the path, creating the missing parents. The key
+// node of the deprecated key is reused to keep its comments.
+func setKey(node *yaml.Node, path []string, key, value *yaml.Node) {
+	if len(path) == 1 {
+		key.Value = path[0]
+		node.Content = append(node.Content, key, value)
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if strings.EqualFold(node.Content[i].Value, path[0]) && node.Content[i+1].Kind == yaml.MappingNode {
+			setKey(node.Content[i+1], path[1:], key, value)
+			return
+		}
+	}
+	child := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: path[0]}, child)
+	setKey(child, path[1:], key, value)
+}
+
+// scalarToList converts a single value to a list with the value.
+func scalarToList(value *yaml.Node) (*yaml.Node, bool) {
+	if value.Kind != yaml.ScalarNode {
+		return value, true
+	}
+	return &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Content: []*yaml.Node{value}}, true
+}
+
+// cidrObjectsToList converts a list of objects with a cidr, the format of
+// network.clusterNetwork before it became a list of CIDRs.
+func cidrObjectsToList(value *yaml.Node) (*yaml.Node, bool) {
+	if value.Kind != yaml.SequenceNode {
+		return nil, false
+	}
+	converted := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Style: value.Style}
+	for _, item := range value.Content {
+		if item.Kind != yaml.MappingNode {
+			return nil, false
+		}
+		parent, i := findKey(item, []string{"cidr"})
+		if parent == nil {
+			return nil, false
+		}
+		converted.Content = append(converted.Content, parent.Content[i+1])
+	}
+	return converted, true
+}
+
+// FileMigration lists the deprecated keys migrated in a configuration file.
+type FileMigration struct {
+	File       string         `json:"file"`
+	Migrations []KeyMigration `json:"migrations"`
+	// Backup is the copy of the file before the migration. Empty if the file was not rewritten.
+	Backup string `json:"backup,omitempty"`
+}
+
+// MigrateFiles migrates the deprecated keys of the configuration files found
+// in the paths. Unless dryRun is set, the files are rewritten in place, each
+// after being copied to a backup next to it. Files without deprecated keys are
+// left untouched and are not returned.
+func MigrateFiles(paths Paths, dryRun bool) ([]FileMigration, error) {
+	files, err := userProvidedConfigPaths(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []FileMigration{}
+	for _, file := range files {
+		data, err := readFile(file)
+		if err != nil {
+			return nil, err
+		}
+		migrated, migrations, err := migrateYAML(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse config file %q: %w", file, err)
+		}
+		if len(migrations) == 0 {
+			continue
+		}
+		fm := FileMigration{File: file, Migrations: migrations}
+		if !dryRun {
+			if fm.Backup, err = rewriteWithBackup(file, data, migrated); err != nil {
+				return nil, err
+			}
+		}
+		result = append(result, fm)
+	}
+	return result, nil
+}
+
+// rewriteWithBackup copies the original data to a backup next to the file, and writes the new data.
+// The backup doesn't end with .yaml, so it isn't read as a drop-in.
+func rewriteWithBackup(file string, original, data []byte) (string, error) {
+	info, err := os.Stat(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to stat config file %q: %w", file, err)
+	}
+	backup := fmt.Sprintf("%s.%s.bak", file, time.Now().Format("20060102-150405"))
+	if err := os.WriteFile(backup, original, info.Mode().Perm()); err != nil {
+		return "", fmt.Errorf("failed to back up config file %q: %w", file, err)
+	}
+	if err := os.WriteFile(file, data, info.Mode().Perm()); err != nil {
+		return "", fmt.Errorf("failed to write migrated config file %q: %w", file, err)
+	}
+	return backup, nil
+}
@@ -0,0 +1,178 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateYAML(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     string
+		expected   string
+		migrations []KeyMigration
+	}{
+		{
+			name: "renamed keys",
+			config: `# Cluster settings
+cluster:
+  clusterCIDR: 10.42.0.0/16
+  serviceNodePortRange: 30000-32000
+  url: https://127.0.0.1:6443
+nodeName: node1
+`,
+			expected: `# Cluster settings
+
+network:
+  clusterNetwork:
+    - 10.42.0.0/16
+  serviceNodePortRange: 30000-32000
+node:
+  hostnameOverride: node1
+`,
+			migrations: []KeyMigration{
+				{From: "cluster.clusterCIDR", To: "network.clusterNetwork", Line: 3},
+				{From: "cluster.serviceNodePortRange", To: "network.serviceNodePortRange", Line: 4},
+				{From: "cluster.url", Line: 5, Reason: "the API server's URL is computed from the node's IP"},
+				{From: "nodeName", To: "node.hostnameOverride", Line: 6},
+			},
+		},
+		{
+			name: "both keys set",
+			config: `nodeIP: 10.0.0.1
+node:
+  nodeIP: 10.0.0.2
+`,
+			expected: `node:
+  nodeIP: 10.0.0.2
+`,
+			migrations: []KeyMigration{{From: "nodeIP", To: "node.nodeIP", Line: 1, Conflict: true}},
+		},
+		{
+			name: "both keys set with comments",
+			config: `# Node settings
+node:
+  # The new node IP
+  nodeIP: 10.0.0.2
+# The old node IP
+nodeIP: 10.0.0.1
+`,
+			expected: `# The old node IP
+
+# Node settings
+node:
+  # The new node IP
+  nodeIP: 10.0.0.2
+`,
+			migrations: []KeyMigration{{From: "nodeIP", To: "node.nodeIP", Line: 6, Conflict: true}},
+		},
+		{
+			name: "keys differing in case",
+			config: `Cluster:
+  ServiceCIDR: 10.43.0.0/16
+Node:
+  NodeIP: 10.0.0.2
+nodeip: 10.0.0.1
+`,
+			expected: `Node:
+  NodeIP: 10.0.0.2
+network:
+  serviceNetwork:
+    - 10.43.0.0/16
+`,
+			migrations: []KeyMigration{
+				{From: "cluster.serviceCIDR", To: "network.serviceNetwork", Line: 2},
+				{From: "nodeIP", To: "node.nodeIP", Line: 5, Conflict: true},
+			},
+		},
+		{
+			name: "deprecated format",
+			config: `network:
+  clusterNetwork:
+  - cidr: 10.42.0.0/16
+  - cidr: fd01::/48
+`,
+			expected: `network:
+  clusterNetwork:
+    - 10.42.0.0/16
+    - fd01::/48
+`,
+			migrations: []KeyMigration{{From: "network.clusterNetwork", To: "network.clusterNetwork", Line: 2}},
+		},
+		{
+			name: "nothing to migrate",
+			config: `network:
+  clusterNetwork:
+  - 10.42.0.0/16
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrated, migrations, err := migrateYAML([]byte(tt.config))
+			require.NoError(t, err)
+			if tt.expected == "" {
+				assert.Equal(t, tt.config, string(migrated))
+				assert.Empty(t, migrations)
+				return
+			}
+			assert.Equal(t, tt.expected, string(migrated))
+			assert.Equal(t, tt.migrations, migrations)
+		})
+	}
+}
+
+func TestGetActiveConfigFromYAML_deprecatedKeys(t *testing.T) {
+	cfg, err := getActiveConfigFromYAMLDropins([][]byte{
+		[]byte("cluster:\n  serviceCIDR: 10.66.0.0/16\n"),
+		[]byte("debugging:\n  logLevl: Debug\n"),
+	}, DefaultPaths())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.66.0.0/16"}, cfg.Network.ServiceNetwork)
+
+	codes := map[string]WarningCode{}
+	for _, w := range cfg.Warnings {
+		codes[w.Path] = w.Code
+	}
+	assert.Equal(t, map[string]WarningCode{
+		"cluster.serviceCIDR": WarningDeprecatedField,
+		"debugging.logLevl":   WarningUnknownField,
+	}, codes)
+}
+
+func TestMigrateFiles(t *testing.T) {
+	dir := t.TempDir()
+	paths := Paths{
+		ConfigFile:      writeConfigFile(t, filepath.Join(dir, "config.yaml"), "nodeIP: 10.0.0.1\n"),
+		ConfigDropInDir: filepath.Join(dir, "config.d"),
+	}
+	current := writeConfigFile(t, filepath.Join(paths.ConfigDropInDir, "10-node.yaml"), "node:\n  nodeIP: 10.0.0.1\n")
+
+	migrated, err := MigrateFiles(paths, true)
+	require.NoError(t, err)
+	require.Len(t, migrated, 1)
+	assert.Equal(t, paths.ConfigFile, migrated[0].File)
+	assert.Empty(t, migrated[0].Backup)
+	data, err := os.ReadFile(paths.ConfigFile)
+	require.NoError(t, err)
+	assert.Equal(t, "nodeIP: 10.0.0.1\n", string(data))
+
+	migrated, err = MigrateFiles(paths, false)
+	require.NoError(t, err)
+	require.Len(t, migrated, 1)
+	data, err = os.ReadFile(paths.ConfigFile)
+	require.NoError(t, err)
+	assert.Equal(t, "node:\n  nodeIP: 10.0.0.1\n", string(data))
+	backup, err := os.ReadFile(migrated[0].Backup)
+	require.NoError(t, err)
+	assert.Equal(t, "nodeIP: 10.0.0.1\n", string(backup))
+
+	// The backups are not read as drop-ins.
+	files, err := userProvidedConfigPaths(paths)
+	require.NoError(t, err)
+	assert.Equal(t, []string{paths.ConfigFile, current}, files)
+}
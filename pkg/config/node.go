@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/klog/v2"
 )
 
@@ -77,8 +78,9 @@ func (c *Config) validateNodeName(isDefaultNodeName bool, dataDir string) error
 				currentNodeName, establishedNodeName)
 		} else {
 			c.Node.HostnameOverride = establishedNodeName
-			klog.Warningf("NodeName has changed due to a host name change, using previously established NodeName %q."+
-				"Please consider using a static NodeName in configuration", establishedNodeName)
+			c.AddWarning(WarningNodeNameChangedByHostname, field.NewPath("node", "hostnameOverride"),
+				fmt.Sprintf("NodeName has changed due to a host name change, using previously established NodeName %q. "+
+					"Please consider using a static NodeName in configuration", establishedNodeName))
 		}
 	}
 
@@ -89,5 +91,5 @@ func (c *Config) EnsureNodeNameHasNotChanged() error {
 	// Validate NodeName in config file, node-name should not be changed for an already
 	// initialized MicroShift instance. This can lead to Pods being re-scheduled, storage
 	// being orphaned or lost, and other side effects.
-	return c.validateNodeName(c.isDefaultNodeName(), DataDir)
+	return c.validateNodeName(c.isDefaultNodeName(), c.Paths.DataDir)
 }
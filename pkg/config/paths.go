@@ -0,0 +1,115 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/pflag"
+)
+
+const (
+	DefaultConfigFile      = "/etc/microshift/config.yaml"
+	DefaultConfigDropInDir = "/etc/microshift/config.d"
+	DefaultDataDir         = "/var/lib/microshift"
+	DefaultBackupsDir      = "/var/lib/microshift-backups"
+
+	// Environment variables overriding the default paths.
+	EnvConfigFile      = "MICROSHIFT_CONFIG_FILE"
+	EnvConfigDropInDir = "MICROSHIFT_CONFIG_DROPIN_DIR"
+	EnvDataDir         = "MICROSHIFT_DATA_DIR"
+	EnvBackupsDir      = "MICROSHIFT_BACKUPS_DIR"
+
+	// Flags overriding the default paths and the environment variables.
+	FlagConfigFile      = "config-file"
+	FlagConfigDropInDir = "config-dropin-dir"
+	FlagDataDir         = "data-dir"
+	FlagBackupsDir      = "backups-dir"
+)
+
+// Paths are the locations of MicroShift's configuration, data, and backups.
+// They are not part of the configuration file, because they are needed to
+// find it. Overriding them allows keeping the data on a dedicated partition,
+// running tests in a temporary directory, or running several instances on a host.
+type Paths struct {
+	// ConfigFile is the main configuration file, merged before the drop-ins.
+	ConfigFile string
+	// ConfigDropInDir contains the YAML drop-ins merged in the lexical order.
+	ConfigDropInDir string
+	// DataDir contains the certificates, etcd's database, and other state.
+	DataDir string
+	// BackupsDir contains the backups and auto-recovery's state.
+	BackupsDir string
+}
+
+// DefaultPaths returns the paths used by the MicroShift's packages.
+func DefaultPaths() Paths {
+	return Paths{
+		ConfigFile:      DefaultConfigFile,
+		ConfigDropInDir: DefaultConfigDropInDir,
+		DataDir:         DefaultDataDir,
+		BackupsDir:      DefaultBackupsDir,
+	}
+}
+
+// PathsFromEnv returns the default paths overridden with the environment variables.
+func PathsFromEnv() Paths {
+	p := DefaultPaths()
+	for env, path := range map[string]*string{
+		EnvConfigFile:      &p.ConfigFile,
+		EnvConfigDropInDir: &p.ConfigDropInDir,
+		EnvDataDir:         &p.DataDir,
+		EnvBackupsDir:      &p.BackupsDir,
+	} {
+		if v := os.Getenv(env); v != "" {
+			*path = v
+		}
+	}
+	return p
+}
+
+// AddPathFlags registers flags overriding the paths. The environment
+// variables, or the default paths, are the flags' defaults.
+func AddPathFlags(flags *pflag.FlagSet) {
+	p := PathsFromEnv()
+	flags.String(FlagConfigFile, p.ConfigFile,
+		"Path of MicroShift's main configuration file. Overrides $"+EnvConfigFile+".")
+	flags.String(FlagConfigDropInDir, p.ConfigDropInDir,
+		"Directory with MicroShift's configuration drop-ins. Overrides $"+EnvConfigDropInDir+".")
+	flags.String(FlagDataDir, p.DataDir,
+		"Directory with MicroShift's data. Overrides $"+EnvDataDir+".")
+	flags.String(FlagBackupsDir, p.BackupsDir,
+		"Directory with MicroShift's backups. Overrides $"+EnvBackupsDir+".")
+}
+
+// PathsFromFlags returns the paths set by the flags registered with AddPathFlags.
+// Paths without the flags come from the environment variables, or are the defaults.
+func PathsFromFlags(flags *pflag.FlagSet) Paths {
+	p := PathsFromEnv()
+	for name, path := range map[string]*string{
+		FlagConfigFile:      &p.ConfigFile,
+		FlagConfigDropInDir: &p.ConfigDropInDir,
+		FlagDataDir:         &p.DataDir,
+		FlagBackupsDir:      &p.BackupsDir,
+	} {
+		if f := flags.Lookup(name); f != nil {
+			*path = f.Value.String()
+		}
+	}
+	return p
+}
+
+// BackupHooksDir returns the directory containing the backup and restore hooks
+// of all the stages, next to the configuration file.
+func (p Paths) BackupHooksDir() string {
+	return filepath.Join(filepath.Dir(p.ConfigFile), "backup-hooks.d")
+}
+
+// Args returns the flags passing the paths to another MicroShift's process, like microshift-etcd.
+func (p Paths) Args() []string {
+	return []string{
+		"--" + FlagConfigFile, p.ConfigFile,
+		"--" + FlagConfigDropInDir, p.ConfigDropInDir,
+		"--" + FlagDataDir, p.DataDir,
+		"--" + FlagBackupsDir, p.BackupsDir,
+	}
+}
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPathsFromFlags(t *testing.T) {
+	t.Setenv(EnvDataDir, "/env/data")
+	t.Setenv(EnvBackupsDir, "/env/backups")
+
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	AddPathFlags(flags)
+	require.NoError(t, flags.Parse([]string{"--" + FlagBackupsDir, "/flag/backups"}))
+
+	p := PathsFromFlags(flags)
+	assert.Equal(t, Paths{
+		ConfigFile:      DefaultConfigFile,
+		ConfigDropInDir: DefaultConfigDropInDir,
+		DataDir:         "/env/data",
+		BackupsDir:      "/flag/backups",
+	}, p)
+
+	// Paths are passed to microshift-etcd as flags.
+	other := pflag.NewFlagSet("etcd", pflag.ContinueOnError)
+	AddPathFlags(other)
+	require.NoError(t, other.Parse(p.Args()))
+	assert.Equal(t, p, PathsFromFlags(other))
+}
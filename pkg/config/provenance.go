@@ -0,0 +1,281 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	k8syaml "sigs.k8s.io/yaml"
+)
+
+const (
+	// SourceDefault marks values which are MicroShift's defaults,
+	// including those derived from the host, like its hostname and IP.
+	SourceDefault = "default"
+	// SourceComputed marks values which MicroShift computes from other values,
+	// like the DNS service IP computed from the service network.
+	SourceComputed = "computed"
+)
+
+// Provenance tells where the values of the active configuration come from.
+type Provenance struct {
+	// Files are the user provided config files in the order they are merged.
+	Files    []string
+	sources  []*configSource
+	defaults any
+}
+
+// ActiveConfigWithProvenance returns the active configuration together with
+// the provenance of its values.
+func ActiveConfigWithProvenance(paths Paths) (*Config, *Provenance, error) {
+	files, err := userProvidedConfigPaths(paths)
+	if err != nil {
+		return nil, nil, err
+	}
+	return activeConfigWithProvenance(files, paths)
+}
+
+func activeConfigWithProvenance(files []string, paths Paths) (*Config, *Provenance, error) {
+	p := &Provenance{Files: files}
+	contents := make([][]byte, 0, len(files))
+	for _, path := range files {
+		data, err := readFile(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		contents = append(contents, data)
+		// Files which can't be parsed make getActiveConfigFromYAMLDropins fail below.
+		if src, _ := parseConfigSource(path, data); src != nil {
+			p.sources = append(p.sources, src)
+		}
+	}
+
+	cfg, err := getActiveConfigFromYAMLDropins(contents, paths)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defaults := &Config{}
+	if err := defaults.fillDefaults(); err != nil {
+		return nil, nil, fmt.Errorf("failed to fill config's defaults: %w", err)
+	}
+	if p.defaults, err = toGeneric(defaults); err != nil {
+		return nil, nil, err
+	}
+	return cfg, p, nil
+}
+
+// Source returns the source of the field's effective value: file and line
+// of the last user provided file setting the field, SourceDefault, or SourceComputed.
+// Lists are replaced as a whole when merging the files, so their items come
+// from the file setting the list.
+func (p *Provenance) Source(path string, value any) string {
+	for prefix := path; prefix != ""; prefix = parentPath(prefix) {
+		// Files setting a parent object don't set its other fields.
+		if prefix != path && path[len(prefix)] != '[' {
+			continue
+		}
+		for i := len(p.sources) - 1; i >= 0; i-- {
+			if line, ok := p.sources[i].lines[prefix]; ok {
+				return fmt.Sprintf("%s:%d", p.sources[i].file, line)
+			}
+		}
+	}
+	if def, ok := lookupGeneric(p.defaults, path); ok && reflect.DeepEqual(def, value) {
+		return SourceDefault
+	}
+	return SourceComputed
+}
+
+// AnnotatedYAML marshals the config to YAML with each value
+// annotated with its source in a line comment.
+func (p *Provenance) AnnotatedYAML(cfg *Config) ([]byte, error) {
+	marshalled, err := k8syaml.Marshal(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	effective, err := toGeneric(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &yaml.Node{}
+	if err := yaml.Unmarshal(marshalled, doc); err != nil {
+		return nil, fmt.Errorf("failed to parse marshalled config: %w", err)
+	}
+	if len(doc.Content) != 0 {
+		p.annotate(doc.Content[0], nil, effective)
+	}
+
+	buf := &bytes.Buffer{}
+	enc := yaml.NewEncoder(buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return nil, fmt.Errorf("failed to marshal annotated config: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("failed to marshal annotated config: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// annotate sets line comments of the node's values which are scalars or empty collections.
+// The value is the generic form of the node used to compare it with the default.
+func (p *Provenance) annotate(node *yaml.Node, path *field.Path, value any) {
+	child := func(n *yaml.Node, path *field.Path, value any) {
+		if len(n.Content) == 0 {
+			n.LineComment = p.Source(path.String(), value)
+			return
+		}
+		p.annotate(n, path, value)
+	}
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		m, _ := value.(map[string]any)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			keyPath := field.NewPath(key)
+			if path != nil {
+				keyPath = path.Child(key)
+			}
+			child(node.Content[i+1], keyPath, m[key])
+		}
+	case yaml.SequenceNode:
+		l, _ := value.([]any)
+		for i, item := range node.Content {
+			var v any
+			if i < len(l) {
+				v = l[i]
+			}
+			child(item, path.Index(i), v)
+		}
+	}
+}
+
+// UserSettings returns the fields set by the user provided config files,
+// in the form they were read. Fields with zero values are omitted,
+// because MicroShift treats them as not set.
+func (c *Config) UserSettings() (map[string]any, error) {
+	if c.userSettings == nil {
+		return map[string]any{}, nil
+	}
+	generic, err := toGeneric(c.userSettings)
+	if err != nil {
+		return nil, err
+	}
+	zero, err := toGeneric(&Config{})
+	if err != nil {
+		return nil, err
+	}
+	m, _ := pruneZeroValues(generic, zero).(map[string]any)
+	if m == nil {
+		m = map[string]any{}
+	}
+	return m, nil
+}
+
+// toGeneric converts the value to its JSON form made of maps, lists, and scalars.
+func toGeneric(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	var generic any
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+	return generic, nil
+}
+
+// lookupGeneric finds the value of the field path, e.g. "a.b[1]", in the generic form of the config.
+func lookupGeneric(v any, path string) (any, bool) {
+	p, err := parseFieldPath(path)
+	if err != nil {
+		return nil, false
+	}
+	for _, elem := range p {
+		switch typed := v.(type) {
+		case map[string]any:
+			if elem.key == nil {
+				return nil, false
+			}
+			var ok bool
+			if v, ok = typed[*elem.key]; !ok {
+				return nil, false
+			}
+		case []any:
+			if elem.key != nil || elem.index >= len(typed) {
+				return nil, false
+			}
+			v = typed[elem.index]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+type pathElem struct {
+	key   *string
+	index int
+}
+
+// parseFieldPath splits paths created by field.Path, e.g. "a.b[1]".
+func parseFieldPath(path string) ([]pathElem, error) {
+	elems := []pathElem{}
+	for path != "" {
+		i := len(path) - 1
+		for i >= 0 && path[i] != '.' && path[i] != '[' {
+			i--
+		}
+		last := path[i+1:]
+		if i >= 0 && path[i] == '[' {
+			var index int
+			if _, err := fmt.Sscanf(last, "%d]", &index); err != nil {
+				return nil, fmt.Errorf("invalid index in path %q: %w", path, err)
+			}
+			elems = append([]pathElem{{index: index}}, elems...)
+		} else {
+			key := last
+			elems = append([]pathElem{{key: &key}}, elems...)
+		}
+		if i < 0 {
+			i = 0
+		}
+		path = path[:i]
+	}
+	return elems, nil
+}
+
+// pruneZeroValues removes the values which are equal to those of the zero
+// value, given in the same generic form, and the collections left empty.
+func pruneZeroValues(v, zero any) any {
+	switch typed := v.(type) {
+	case map[string]any:
+		zeroMap, _ := zero.(map[string]any)
+		for k, item := range typed {
+			if pruned := pruneZeroValues(item, zeroMap[k]); pruned == nil {
+				delete(typed, k)
+			} else {
+				typed[k] = pruned
+			}
+		}
+		if len(typed) == 0 {
+			return nil
+		}
+		return typed
+	case []any:
+		if len(typed) == 0 {
+			return nil
+		}
+		return typed
+	}
+	if reflect.DeepEqual(v, zero) {
+		return nil
+	}
+	return v
+}
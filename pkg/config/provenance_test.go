@@ -0,0 +1,70 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestActiveConfigWithProvenance(t *testing.T) {
+	dir := t.TempDir()
+	main := writeConfigFile(t, filepath.Join(dir, "config.yaml"), `dns:
+  baseDomain: example.org
+ingress:
+  ports:
+    http: 8080
+network:
+  serviceNetwork:
+  - 10.44.0.0/16
+`)
+	dropin := writeConfigFile(t, filepath.Join(dir, "config.d", "10-ingress.yaml"), `ingress:
+  ports:
+    http: 9080
+`)
+
+	cfg, p, err := activeConfigWithProvenance([]string{main, dropin}, DefaultPaths())
+	require.NoError(t, err)
+	assert.Equal(t, []string{main, dropin}, p.Files)
+
+	assert.Equal(t, main+":2", p.Source("dns.baseDomain", "example.org"))
+	assert.Equal(t, dropin+":3", p.Source("ingress.ports.http", float64(9080)))
+	assert.Equal(t, main+":8", p.Source("network.serviceNetwork[0]", "10.44.0.0/16"))
+	assert.Equal(t, SourceDefault, p.Source("ingress.ports.https", float64(443)))
+	assert.Equal(t, SourceComputed, p.Source("network.clusterNetwork[0]", "10.42.0.0/16"))
+	assert.Equal(t, SourceDefault, p.Source("network.serviceNodePortRange", "30000-32767"))
+	assert.Equal(t, SourceComputed, p.Source("apiServer.advertiseAddress", cfg.ApiServer.AdvertiseAddress))
+
+	annotated, err := p.AnnotatedYAML(cfg)
+	require.NoError(t, err)
+	assert.Contains(t, string(annotated), "baseDomain: example.org # "+main+":2\n")
+	assert.Contains(t, string(annotated), "- 10.44.0.0/16 # "+main+":8\n")
+	assert.Contains(t, string(annotated), "https: 443 # default\n")
+	assert.Contains(t, string(annotated), "- 10.42.0.0/16 # computed\n")
+	for _, line := range strings.Split(strings.TrimSpace(string(annotated)), "\n") {
+		if !strings.HasSuffix(line, ":") {
+			assert.Contains(t, line, " # ", "value is not annotated: %q", line)
+		}
+	}
+
+	user, err := cfg.UserSettings()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"dns":     map[string]any{"baseDomain": "example.org"},
+		"ingress": map[string]any{"ports": map[string]any{"http": float64(9080)}},
+		"network": map[string]any{"serviceNetwork": []any{"10.44.0.0/16"}},
+	}, user)
+}
+
+func Test_lookupGeneric(t *testing.T) {
+	v := map[string]any{"a": map[string]any{"b": []any{"x", "y"}}}
+	found, ok := lookupGeneric(v, "a.b[1]")
+	assert.True(t, ok)
+	assert.Equal(t, "y", found)
+	_, ok = lookupGeneric(v, "a.b[2]")
+	assert.False(t, ok)
+	_, ok = lookupGeneric(v, "a.c")
+	assert.False(t, ok)
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// reloadableFields are the fields, including their children, which are applied
+// to the running MicroShift when it receives SIGHUP. Changes of other fields
+// take effect only after MicroShift is restarted.
+var reloadableFields = []string{
+	"debugging.logLevel",
+	"ingress.tuningOptions",
+	"manifests",
+}
+
+// IsReloadable tells if the change of the field, given as a path like
+// "ingress.tuningOptions.clientTimeout", can be applied without a restart.
+func IsReloadable(path string) bool {
+	for _, f := range reloadableFields {
+		if path == f || strings.HasPrefix(path, f+".") || strings.HasPrefix(path, f+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+// ChangedFields returns the sorted paths of the fields whose values differ between the configs.
+// Lists are compared as a whole, so a change of a list's item is reported as the change of the list.
+func ChangedFields(old, new *Config) ([]string, error) {
+	oldGeneric, err := toGeneric(old)
+	if err != nil {
+		return nil, err
+	}
+	newGeneric, err := toGeneric(new)
+	if err != nil {
+		return nil, err
+	}
+	changed := diffGeneric(nil, oldGeneric, newGeneric)
+	sort.Strings(changed)
+	return changed, nil
+}
+
+func diffGeneric(path *field.Path, old, new any) []string {
+	oldMap, oldIsMap := old.(map[string]any)
+	newMap, newIsMap := new.(map[string]any)
+	if !oldIsMap || !newIsMap {
+		if reflect.DeepEqual(old, new) {
+			return nil
+		}
+		return []string{path.String()}
+	}
+
+	changed := []string{}
+	keys := map[string]struct{}{}
+	for k := range oldMap {
+		keys[k] = struct{}{}
+	}
+	for k := range newMap {
+		keys[k] = struct{}{}
+	}
+	for k := range keys {
+		keyPath := field.NewPath(k)
+		if path != nil {
+			keyPath = path.Child(k)
+		}
+		changed = append(changed, diffGeneric(keyPath, oldMap[k], newMap[k])...)
+	}
+	return changed
+}
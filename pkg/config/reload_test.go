@@ -0,0 +1,37 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestChangedFields(t *testing.T) {
+	old := NewDefault()
+	new := NewDefault()
+	new.Debugging.LogLevel = "Debug"
+	new.Ingress.TuningOptions.ClientTimeout = &metav1.Duration{Duration: time.Minute}
+	new.Network.ServiceNetwork = []string{"10.44.0.0/16"}
+
+	changed, err := ChangedFields(old, new)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"debugging.logLevel",
+		"ingress.tuningOptions.clientTimeout",
+		"network.serviceNetwork",
+	}, changed)
+
+	assert.True(t, IsReloadable("debugging.logLevel"))
+	assert.True(t, IsReloadable("ingress.tuningOptions.clientTimeout"))
+	assert.True(t, IsReloadable("manifests.kustomizePaths"))
+	assert.False(t, IsReloadable("apiServer.auditLog.profile"))
+	assert.False(t, IsReloadable("network.serviceNetwork"))
+	assert.False(t, IsReloadable("ingress.tuningOptionsX"))
+
+	changed, err = ChangedFields(old, NewDefault())
+	require.NoError(t, err)
+	assert.Empty(t, changed)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 )
 
 // CSIStorageDriver is an enum value that determines whether MicroShift deploys LVMS.
@@ -112,6 +113,23 @@ func (s Storage) IsValid() []error {
 	return errs.UnsortedList()
 }
 
+// validate reports the same problems as IsValid with the paths of the invalid fields.
+func (s Storage) validate(path *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+	if !s.driverIsValid() {
+		errs = append(errs, field.NotSupported(path.Child("driver"), s.Driver,
+			[]string{string(CsiDriverUnset), string(CsiDriverNone), string(CsiDriverLVMS)}))
+	}
+	unsupported := sets.New[string](s.csiComponentsAreValid()...)
+	for i, c := range s.OptionalCSIComponents {
+		if unsupported.Has(string(c)) {
+			errs = append(errs, field.NotSupported(path.Child("optionalCsiComponents").Index(i), c,
+				[]string{string(CsiComponentNone), string(CsiComponentSnapshot)}))
+		}
+	}
+	return errs
+}
+
 // IsEnabled returns false only when .storage.driver: "none". An empty value is considered "enabled"
 // for backwards compatibility. Otherwise, the meaning of the config would silently change after an
 // upgrade from enabled-by-default to disabled-by-default.
@@ -0,0 +1,384 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	k8syaml "sigs.k8s.io/yaml"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+var (
+	yamlErrorLineRegexp = regexp.MustCompile(`^yaml: line (\d+): `)
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+)
+
+// Issue is a problem of the configuration found by ValidateFiles.
+type Issue struct {
+	Severity Severity `json:"severity"`
+	// Code of the warning, see WarningCode. Empty for errors.
+	Code WarningCode `json:"code,omitempty"`
+	// Path of the field causing the issue, e.g. ingress.listenAddress[1].
+	// Empty if the issue is not caused by a single field.
+	Path    string `json:"path,omitempty"`
+	Message string `json:"message"`
+	// File and Line where the field is set. Empty if the field is not set
+	// by any of the files, e.g. because the issue is caused by a default value.
+	File string `json:"file,omitempty"`
+	Line int    `json:"line,omitempty"`
+}
+
+func (i Issue) String() string {
+	b := &strings.Builder{}
+	if i.File != "" {
+		b.WriteString(i.File)
+		if i.Line != 0 {
+			fmt.Fprintf(b, ":%d", i.Line)
+		}
+		b.WriteString(": ")
+	}
+	fmt.Fprintf(b, "%s: ", i.Severity)
+	if i.Path != "" {
+		fmt.Fprintf(b, "%s: ", i.Path)
+	}
+	b.WriteString(i.Message)
+	if i.Code != "" {
+		fmt.Fprintf(b, " (%s)", i.Code)
+	}
+	return b.String()
+}
+
+// ValidationReport lists all the issues found in the configuration files.
+type ValidationReport struct {
+	// Files are the validated files in the order they are merged.
+	Files  []string `json:"files"`
+	Issues []Issue  `json:"issues"`
+}
+
+// HasErrors returns true if the configuration would prevent MicroShift from starting.
+func (r *ValidationReport) HasErrors() bool {
+	for _, i := range r.Issues {
+		if i.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateOptions specify the configuration files to validate.
+type ValidateOptions struct {
+	// Files are merged in the order they are given, followed by the DropInDir's YAML files.
+	Files     []string
+	DropInDir string
+	// Offline skips the checks which need to probe the host, like looking up
+	// its hostnames, IP addresses, and NICs. The host's addresses are
+	// replaced by addresses from the documentation ranges.
+	Offline bool
+	// DataDir is used to check that etcd's database quota fits on the disk.
+	// Defaults to DefaultDataDir.
+	DataDir string
+	// Validators are run in addition to the config's own validation, e.g. by
+	// packages which the config package can't depend on.
+	Validators []func(*Config) field.ErrorList
+}
+
+// ValidateFiles reads, merges, and validates the configuration files the same way as
+// MicroShift does when it starts, but instead of stopping at the first problem,
+// it reports all of them together with the file and line setting the offending field.
+// Errors are only returned if the files cannot be read.
+func ValidateFiles(opts ValidateOptions) (*ValidationReport, error) {
+	paths := append([]string{}, opts.Files...)
+	if opts.DropInDir != "" {
+		dropins, err := dropInFilePaths(opts.DropInDir)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, dropins...)
+	}
+
+	report := &ValidationReport{Files: paths, Issues: []Issue{}}
+	sources := make([]*configSource, 0, len(paths))
+	contents := make([][]byte, 0, len(paths))
+	for _, path := range paths {
+		data, err := readFile(path)
+		if err != nil {
+			return nil, err
+		}
+		src, issues := parseConfigSource(path, data)
+		report.Issues = append(report.Issues, issues...)
+		if src != nil {
+			sources = append(sources, src)
+			contents = append(contents, data)
+		}
+	}
+	if report.HasErrors() {
+		// Files which are not valid YAML or have values of wrong types can't be merged.
+		return report, nil
+	}
+
+	patch, warnings, err := mergeYAMLDropins(contents)
+	if err != nil {
+		report.Issues = append(report.Issues, Issue{Severity: SeverityError, Message: err.Error()})
+		return report, nil
+	}
+
+	cfg := &Config{offline: opts.Offline}
+	if err := cfg.fillDefaults(); err != nil {
+		return nil, fmt.Errorf("failed to fill config's defaults: %w", err)
+	}
+	cfg.Warnings = append(cfg.Warnings, warnings...)
+	if opts.DataDir != "" {
+		cfg.Paths.DataDir = opts.DataDir
+	}
+	if len(patch) != 0 {
+		userSettings := &Config{}
+		if err := json.Unmarshal(patch, userSettings); err != nil {
+			report.Issues = append(report.Issues, Issue{Severity: SeverityError, Message: err.Error()})
+			return report, nil
+		}
+		cfg.incorporateUserSettings(userSettings)
+	}
+	if err := cfg.updateComputedValues(); err != nil {
+		report.Issues = append(report.Issues, Issue{Severity: SeverityError, Message: err.Error()})
+		return report, nil
+	}
+
+	errs := cfg.validateFields()
+	if e := cfg.checkEtcdQuotaFitsOnDisk(); e != nil {
+		errs = append(errs, e)
+	}
+	for _, validate := range opts.Validators {
+		errs = append(errs, validate(cfg)...)
+	}
+	for _, e := range errs {
+		report.Issues = append(report.Issues, locate(sources, Issue{
+			Severity: SeverityError,
+			Path:     e.Field,
+			Message:  e.ErrorBody(),
+		}))
+	}
+	for _, w := range cfg.Warnings {
+		report.Issues = append(report.Issues, locate(sources, Issue{
+			Severity: w.Severity,
+			Code:     w.Code,
+			Path:     w.Path,
+			Message:  w.Message,
+		}))
+	}
+	return report, nil
+}
+
+func readFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config file %q: %w", path, err)
+	}
+	return data, nil
+}
+
+// configSource is a parsed configuration file.
+type configSource struct {
+	file string
+	// lines maps the paths of the fields set by the file to their line numbers.
+	lines map[string]int
+}
+
+// parseConfigSource parses the file to find out where its fields are set and
+// to check the types of their values. Unknown and deprecated fields are reported
+// when the files are merged. If the file can't be merged with other files,
+// nil source is returned.
+func parseConfigSource(file string, data []byte) (*configSource, []Issue) {
+	src := &configSource{file: file, lines: map[string]int{}}
+	if strings.TrimSpace(string(data)) == "" {
+		return src, nil
+	}
+
+	doc := &yaml.Node{}
+	if err := yaml.Unmarshal(data, doc); err != nil {
+		issue := Issue{Severity: SeverityError, File: file, Message: err.Error()}
+		if m := yamlErrorLineRegexp.FindStringSubmatch(issue.Message); m != nil {
+			issue.Line, _ = strconv.Atoi(m[1])
+			issue.Message = strings.TrimPrefix(issue.Message, m[0])
+		}
+		return nil, []Issue{issue}
+	}
+	root := doc
+	if root.Kind == yaml.DocumentNode && len(root.Content) != 0 {
+		root = root.Content[0]
+	}
+	if root.Kind != yaml.MappingNode {
+		return nil, []Issue{{Severity: SeverityError, File: file, Line: root.Line, Message: "config must be a YAML mapping"}}
+	}
+	src.walk(root, nil, reflect.TypeOf(Config{}))
+	issues := []Issue{}
+
+	// Migrated fields are located at the deprecated fields.
+	migrated, migrations, err := migrateYAML(data)
+	if err != nil {
+		return nil, []Issue{{Severity: SeverityError, File: file, Message: err.Error()}}
+	}
+	for _, m := range migrations {
+		if _, ok := src.lines[m.To]; m.To != "" && !ok {
+			src.lines[m.To] = m.Line
+		}
+	}
+
+	// Decoding the file alone tells which file has a value of a wrong type.
+	jsonData, err := k8syaml.YAMLToJSON(migrated)
+	if err == nil {
+		err = json.Unmarshal(jsonData, &Config{})
+	}
+	if err != nil {
+		issue := Issue{Severity: SeverityError, File: file, Message: err.Error()}
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			issue.Path = typeErr.Field
+			issue.Message = fmt.Sprintf("Invalid value: expected %s, got %s", typeErr.Type, typeErr.Value)
+		}
+		issues = append(issues, locate([]*configSource{src}, issue))
+		return nil, issues
+	}
+	return src, issues
+}
+
+// walk records lines of the node's fields and warns about those which are
+// not fields of the type t. Nil type means that any field is allowed.
+func (s *configSource) walk(node *yaml.Node, path *field.Path, t reflect.Type) []Issue {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t != nil && (t.Implements(jsonUnmarshalerType) || reflect.PointerTo(t).Implements(jsonUnmarshalerType)) {
+		// Types with custom decoding, like durations, are values, not structs.
+		t = nil
+	}
+
+	issues := []Issue{}
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			child := func(name string) *field.Path {
+				if path == nil {
+					return field.NewPath(name)
+				}
+				return path.Child(name)
+			}
+
+			var valueType reflect.Type
+			keyPath := child(key.Value)
+			if t != nil {
+				switch t.Kind() {
+				case reflect.Struct:
+					f, name, ok := jsonField(t, key.Value)
+					if !ok {
+						s.lines[keyPath.String()] = key.Line
+						issues = append(issues, Issue{
+							Severity: SeverityWarning,
+							Code:     WarningUnknownField,
+							Path:     keyPath.String(),
+							Message:  "unknown field is ignored",
+							File:     s.file,
+							Line:     key.Line,
+						})
+						// Lines of the unknown field's children locate deprecated fields.
+						s.walk(value, keyPath, nil)
+						continue
+					}
+					// Keys differing in case are decoded into the same field,
+					// so they are located under the field's name.
+					keyPath = child(name)
+					valueType = f.Type
+				case reflect.Map:
+					valueType = t.Elem()
+				}
+			}
+			s.lines[keyPath.String()] = key.Line
+			issues = append(issues, s.walk(value, keyPath, valueType)...)
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			itemPath := path.Index(i)
+			s.lines[itemPath.String()] = item.Line
+			var itemType reflect.Type
+			if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+				itemType = t.Elem()
+			}
+			issues = append(issues, s.walk(item, itemPath, itemType)...)
+		}
+	}
+	return issues
+}
+
+// jsonField finds the struct's field decoded from the JSON key
+// following the rules of encoding/json, including case insensitivity.
+// It also returns the field's JSON name.
+func jsonField(t reflect.Type, key string) (reflect.StructField, string, bool) {
+	var folded *reflect.StructField
+	foldedName := ""
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if name == key {
+			return f, name, true
+		}
+		if folded == nil && strings.EqualFold(name, key) {
+			folded, foldedName = &f, name
+		}
+	}
+	if folded != nil {
+		return *folded, foldedName, true
+	}
+	return reflect.StructField{}, "", false
+}
+
+// locate sets the file and line of the issue to the last file setting the field,
+// or its closest parent if the field itself is not set by any file.
+func locate(sources []*configSource, issue Issue) Issue {
+	if issue.Path == "" {
+		return issue
+	}
+	for p := issue.Path; p != ""; p = parentPath(p) {
+		for i := len(sources) - 1; i >= 0; i-- {
+			if line, ok := sources[i].lines[p]; ok {
+				issue.File, issue.Line = sources[i].file, line
+				return issue
+			}
+		}
+	}
+	return issue
+}
+
+// parentPath strips the last element of the path, e.g. "a.b[1]" becomes "a.b".
+func parentPath(path string) string {
+	i := strings.LastIndexAny(path, ".[")
+	if i < 0 {
+		return ""
+	}
+	return path[:i]
+}
@@ -0,0 +1,175 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func writeConfigFile(t *testing.T, path, contents string) string {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+	require.NoError(t, os.WriteFile(path, []byte(contents), 0600))
+	return path
+}
+
+func TestValidateFiles(t *testing.T) {
+	dir := t.TempDir()
+	main := writeConfigFile(t, filepath.Join(dir, "config.yaml"), `dns:
+  baseDomain: example.org
+ingress:
+  listenAddress:
+  - 10.44.0.1
+  - fd03::1
+  status: Bogus
+  ports:
+    http: 70000
+debugging:
+  logLevel: Loud
+node:
+  nodeIp: 10.44.0.1
+  unknown: x
+`)
+	dropin := writeConfigFile(t, filepath.Join(dir, "config.d", "10-auto-recovery.yaml"), `autoRecovery:
+  storage: relative/path
+ingress:
+  ports:
+    https: 0
+`)
+
+	report, err := ValidateFiles(ValidateOptions{
+		Files:     []string{main},
+		DropInDir: filepath.Join(dir, "config.d"),
+		Offline:   true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{main, dropin}, report.Files)
+	assert.True(t, report.HasErrors())
+
+	type location struct {
+		severity Severity
+		path     string
+		file     string
+		line     int
+	}
+	locations := []location{}
+	for _, i := range report.Issues {
+		locations = append(locations, location{i.Severity, i.Path, i.File, i.Line})
+	}
+	assert.ElementsMatch(t, []location{
+		{SeverityWarning, "node.unknown", main, 14},
+		{SeverityError, "ingress.status", main, 7},
+		{SeverityError, "ingress.ports.http", main, 9},
+		{SeverityError, "ingress.ports.https", dropin, 5},
+		// Addresses of the host are not checked offline, but the IP family is.
+		{SeverityError, "ingress.listenAddress[1]", main, 6},
+		{SeverityError, "autoRecovery.storage", dropin, 2},
+		{SeverityWarning, "debugging.logLevel", main, 11},
+	}, locations)
+
+	codes := map[string]WarningCode{}
+	for _, i := range report.Issues {
+		if i.Severity == SeverityWarning {
+			codes[i.Path] = i.Code
+		}
+	}
+	assert.Equal(t, map[string]WarningCode{
+		"node.unknown":       WarningUnknownField,
+		"debugging.logLevel": WarningUnrecognizedLogLevel,
+	}, codes)
+}
+
+func TestValidateFiles_KeysDifferingInCase(t *testing.T) {
+	dir := t.TempDir()
+	main := writeConfigFile(t, filepath.Join(dir, "config.yaml"), `dns:
+  baseDomain: example.org
+Ingress:
+  Ports:
+    HTTP: 70000
+`)
+
+	report, err := ValidateFiles(ValidateOptions{Files: []string{main}, Offline: true})
+	require.NoError(t, err)
+	require.Len(t, report.Issues, 1)
+	assert.Equal(t, "ingress.ports.http", report.Issues[0].Path)
+	assert.Equal(t, main, report.Issues[0].File)
+	assert.Equal(t, 5, report.Issues[0].Line)
+}
+
+func TestValidateFiles_Unmergeable(t *testing.T) {
+	dir := t.TempDir()
+	wrongType := writeConfigFile(t, filepath.Join(dir, "type.yaml"), `ingress:
+  ports:
+    http: eighty
+`)
+	notMapping := writeConfigFile(t, filepath.Join(dir, "list.yaml"), `- a
+`)
+	empty := writeConfigFile(t, filepath.Join(dir, "empty.yaml"), "")
+
+	report, err := ValidateFiles(ValidateOptions{Files: []string{wrongType, notMapping, empty}, Offline: true})
+	require.NoError(t, err)
+	require.Len(t, report.Issues, 2)
+	assert.Equal(t, Issue{
+		Severity: SeverityError,
+		Path:     "ingress.ports.http",
+		Message:  "Invalid value: expected int, got string",
+		File:     wrongType,
+		Line:     3,
+	}, report.Issues[0])
+	assert.Equal(t, notMapping, report.Issues[1].File)
+
+	_, err = ValidateFiles(ValidateOptions{Files: []string{filepath.Join(dir, "missing.yaml")}, Offline: true})
+	assert.Error(t, err)
+}
+
+func TestValidateFiles_Valid(t *testing.T) {
+	dir := t.TempDir()
+	main := writeConfigFile(t, filepath.Join(dir, "config.yaml"), `dns:
+  baseDomain: example.org
+apiServer:
+  auditLog:
+    profile: WriteRequestBodies
+kubelet:
+  anything: goes
+`)
+
+	report, err := ValidateFiles(ValidateOptions{Files: []string{main}, Offline: true})
+	require.NoError(t, err)
+	assert.False(t, report.HasErrors())
+	assert.Empty(t, report.Issues)
+}
+
+func TestValidateFiles_Kubelet(t *testing.T) {
+	dir := t.TempDir()
+	main := writeConfigFile(t, filepath.Join(dir, "config.yaml"), `kubelet:
+  maxPods: 100
+  clusterDNS:
+  - 10.43.0.10
+  maxPodz: 100
+`)
+
+	report, err := ValidateFiles(ValidateOptions{
+		Files:   []string{main},
+		Offline: true,
+		Validators: []func(*Config) field.ErrorList{func(c *Config) field.ErrorList {
+			return field.ErrorList{field.Invalid(field.NewPath("kubelet", "maxPodz"), field.OmitValueType{}, "unknown field")}
+		}},
+	})
+	require.NoError(t, err)
+	require.Len(t, report.Issues, 2)
+	assert.Equal(t, "kubelet.clusterDNS", report.Issues[0].Path)
+	assert.Contains(t, report.Issues[0].Message, "Forbidden: must not be overridden")
+	assert.Equal(t, 3, report.Issues[0].Line)
+	assert.Equal(t, "kubelet.maxPodz", report.Issues[1].Path)
+	assert.Equal(t, 5, report.Issues[1].Line)
+}
+
+func Test_parentPath(t *testing.T) {
+	assert.Equal(t, "ingress.listenAddress", parentPath("ingress.listenAddress[1]"))
+	assert.Equal(t, "ingress", parentPath("ingress.listenAddress"))
+	assert.Equal(t, "", parentPath("ingress"))
+}
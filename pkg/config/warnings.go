@@ -0,0 +1,56 @@
+package config
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// WarningCode identifies the condition causing a warning. The codes are stable,
+// so tools can act on them, and documented in docs/user/howto_config.md.
+type WarningCode string
+
+const (
+	// WarningUnrecognizedLogLevel: debugging.logLevel is not one of the known
+	// levels, the default level is used instead.
+	WarningUnrecognizedLogLevel WarningCode = "UnrecognizedLogLevel"
+	// WarningNodeNameChangedByHostname: the host name changed since the first start,
+	// and the node name isn't set, so the previously established node name is used.
+	WarningNodeNameChangedByHostname WarningCode = "NodeNameChangedByHostname"
+	// WarningUnknownField: the configuration file sets a field which doesn't exist
+	// and is ignored.
+	WarningUnknownField WarningCode = "UnknownField"
+	// WarningDeprecatedField: the configuration file sets a deprecated field,
+	// which is migrated to the field replacing it, or ignored if it was removed.
+	WarningDeprecatedField WarningCode = "DeprecatedField"
+	// WarningEtcdQuotaExceedsDisk: etcd.quotaBackendBytes is bigger than the disk space
+	// available for etcd's database, so the disk may run out of space before etcd's quota.
+	WarningEtcdQuotaExceedsDisk WarningCode = "EtcdQuotaExceedsDisk"
+)
+
+// Warning is a problem of the configuration which does not prevent
+// the service from starting.
+type Warning struct {
+	Code     WarningCode `json:"code"`
+	Severity Severity    `json:"severity"`
+	// Path of the field causing the warning, e.g. debugging.logLevel.
+	Path    string `json:"path,omitempty"`
+	Message string `json:"message"`
+}
+
+func (w Warning) String() string {
+	if w.Path == "" {
+		return fmt.Sprintf("%s (%s)", w.Message, w.Code)
+	}
+	return fmt.Sprintf("%s: %s (%s)", w.Path, w.Message, w.Code)
+}
+
+// AddWarning saves a warning about the field to be reported later.
+func (c *Config) AddWarning(code WarningCode, path *field.Path, message string) {
+	c.Warnings = append(c.Warnings, Warning{
+		Code:     code,
+		Severity: SeverityWarning,
+		Path:     path.String(),
+		Message:  message,
+	})
+}
@@ -62,9 +62,9 @@ func (s *ClusterID) Run(ctx context.Context, ready chan<- struct{}, stopped chan
 
 	// Use the 'kube-system' namespace metadata UID as the MicroShift Cluster ID
 	clusterID := string(namespace.ObjectMeta.UID)
-	// Write <config.DataDir>/cluster-id file if it does not already exist
+	// Write <data dir>/cluster-id file if it does not already exist
 	// or has inconsistent contents
-	err = initClusterIDFile(clusterID)
+	err = initClusterIDFile(s.cfg.Paths.DataDir, clusterID)
 	if err != nil {
 		return fmt.Errorf("failed to initialize cluster ID file: %v", err)
 	}
@@ -74,9 +74,9 @@ func (s *ClusterID) Run(ctx context.Context, ready chan<- struct{}, stopped chan
 	return ctx.Err()
 }
 
-func initClusterIDFile(clusterID string) error {
+func initClusterIDFile(dataDir, clusterID string) error {
 	// The location of the cluster ID file
-	fileName := filepath.Join(config.DataDir, "cluster-id")
+	fileName := filepath.Join(dataDir, "cluster-id")
 
 	// Read and verify the cluster ID file if it already exists,
 	// logging a warning if the cluster ID is inconsistent
@@ -25,11 +25,8 @@ import (
 	"time"
 
 	"github.com/openshift/microshift/pkg/config"
-	"github.com/openshift/microshift/pkg/util/cryptomaterial"
+	"github.com/openshift/microshift/pkg/util"
 	klog "k8s.io/klog/v2"
-
-	"go.etcd.io/etcd/client/pkg/v3/transport"
-	clientv3 "go.etcd.io/etcd/client/v3"
 )
 
 var (
@@ -39,11 +36,13 @@ var (
 
 type EtcdService struct {
 	memoryLimit uint64
+	paths       config.Paths
 }
 
 func NewEtcd(cfg *config.Config) *EtcdService {
 	return &EtcdService{
 		memoryLimit: cfg.Etcd.MemoryLimitMB,
+		paths:       cfg.Paths,
 	}
 }
 
@@ -95,6 +94,8 @@ func (s *EtcdService) Run(ctx context.Context, ready chan<- struct{}, stopped ch
 		exe = etcdPath
 	}
 	args = append(args, "run")
+	// microshift-etcd reads the same config and data as MicroShift.
+	args = append(args, s.paths.Args()...)
 	// Not using context as canceling ctx sends SIGKILL to process
 	klog.Infof("starting etcd via %s with args %v", exe, args)
 	cmd := exec.Command(exe, args...)
@@ -137,7 +138,7 @@ func (s *EtcdService) Run(ctx context.Context, ready chan<- struct{}, stopped ch
 		}
 	}()
 
-	if err := checkIfEtcdIsReady(ctx); err != nil {
+	if err := checkIfEtcdIsReady(ctx, s.paths.DataDir); err != nil {
 		return err
 	}
 	klog.Info("etcd is ready!")
@@ -168,8 +169,8 @@ func stopMicroshiftEtcdScopeIfExists() error {
 	return nil
 }
 
-func checkIfEtcdIsReady(ctx context.Context) error {
-	client, err := getEtcdClient(ctx)
+func checkIfEtcdIsReady(ctx context.Context, dataDir string) error {
+	client, err := util.GetEtcdClient(ctx, dataDir)
 	if err != nil {
 		return fmt.Errorf("failed to obtain etcd client: %v", err)
 	}
@@ -188,29 +189,3 @@ func checkIfEtcdIsReady(ctx context.Context) error {
 	}
 	return fmt.Errorf("etcd still not healthy after checking %d times", HealthCheckRetries)
 }
-
-func getEtcdClient(ctx context.Context) (*clientv3.Client, error) {
-	certsDir := cryptomaterial.CertsDirectory(config.DataDir)
-	etcdAPIServerClientCertDir := cryptomaterial.EtcdAPIServerClientCertDir(certsDir)
-
-	tlsInfo := transport.TLSInfo{
-		CertFile:      cryptomaterial.ClientCertPath(etcdAPIServerClientCertDir),
-		KeyFile:       cryptomaterial.ClientKeyPath(etcdAPIServerClientCertDir),
-		TrustedCAFile: cryptomaterial.CACertPath(cryptomaterial.EtcdSignerDir(certsDir)),
-	}
-	tlsConfig, err := tlsInfo.ClientConfig()
-	if err != nil {
-		return nil, err
-	}
-
-	cli, err := clientv3.New(clientv3.Config{
-		Endpoints:   []string{"https://localhost:2379"},
-		DialTimeout: 5 * time.Second,
-		TLS:         tlsConfig,
-		Context:     ctx,
-	})
-	if err != nil {
-		return nil, err
-	}
-	return cli, nil
-}
@@ -96,7 +96,7 @@ func (s *KubeAPIServer) Dependencies() []string { return []string{"etcd", "netwo
 func (s *KubeAPIServer) configure(cfg *config.Config) error {
 	s.verbosity = cfg.GetVerbosity()
 
-	certsDir := cryptomaterial.CertsDirectory(config.DataDir)
+	certsDir := cryptomaterial.CertsDirectory(cfg.Paths.DataDir)
 	kubeCSRSignerDir := cryptomaterial.CSRSignerCertDir(certsDir)
 	kubeletClientDir := cryptomaterial.KubeAPIServerToKubeletClientCertDir(certsDir)
 	clientCABundlePath := cryptomaterial.TotalClientCABundlePath(certsDir)
@@ -166,7 +166,7 @@ func (s *KubeAPIServer) configure(cfg *config.Config) error {
 	overrides := &kubecontrolplanev1.KubeAPIServerConfig{
 		APIServerArguments: map[string]kubecontrolplanev1.Arguments{
 			"advertise-address":   {s.advertiseAddress},
-			"audit-policy-file":   {filepath.Join(config.DataDir, "/resources/kube-apiserver-audit-policies/default.yaml")},
+			"audit-policy-file":   {filepath.Join(cfg.Paths.DataDir, "/resources/kube-apiserver-audit-policies/default.yaml")},
 			"audit-log-maxage":    {strconv.Itoa(cfg.ApiServer.AuditLog.MaxFileAge)},
 			"audit-log-maxbackup": {strconv.Itoa(cfg.ApiServer.AuditLog.MaxFiles)},
 			"audit-log-maxsize":   {strconv.Itoa(cfg.ApiServer.AuditLog.MaxFileSize)},
@@ -193,7 +193,7 @@ func (s *KubeAPIServer) configure(cfg *config.Config) error {
 			"proxy-client-cert-file":           {cryptomaterial.ClientCertPath(aggregatorClientCertDir)},
 			"proxy-client-key-file":            {cryptomaterial.ClientKeyPath(aggregatorClientCertDir)},
 			"requestheader-client-ca-file":     {aggregatorCAPath},
-			"service-account-signing-key-file": {filepath.Join(config.DataDir, "/resources/kube-apiserver/secrets/service-account-key/service-account.key")},
+			"service-account-signing-key-file": {filepath.Join(cfg.Paths.DataDir, "/resources/kube-apiserver/secrets/service-account-key/service-account.key")},
 			"service-node-port-range":          {cfg.Network.ServiceNodePortRange},
 			"tls-cert-file":                    {servingCert},
 			"tls-private-key-file":             {servingKey},
@@ -249,7 +249,7 @@ func (s *KubeAPIServer) configure(cfg *config.Config) error {
 			},
 		},
 		ServiceAccountPublicKeyFiles: []string{
-			filepath.Join(config.DataDir, "/resources/kube-apiserver/secrets/service-account-key/service-account.pub"),
+			filepath.Join(cfg.Paths.DataDir, "/resources/kube-apiserver/secrets/service-account-key/service-account.pub"),
 		},
 		ServicesNodePortRange: cfg.Network.ServiceNodePortRange,
 	}
@@ -296,7 +296,7 @@ func (s *KubeAPIServer) configureAuditPolicy(cfg *config.Config) error {
 	if err != nil {
 		return err
 	}
-	path := filepath.Join(config.DataDir, "resources", "kube-apiserver-audit-policies", "default.yaml")
+	path := filepath.Join(cfg.Paths.DataDir, "resources", "kube-apiserver-audit-policies", "default.yaml")
 	if err := os.MkdirAll(filepath.Dir(path), os.FileMode(0700)); err != nil {
 		return err
 	}
@@ -61,35 +61,35 @@ func NewKubeControllerManager(ctx context.Context, cfg *config.Config) *KubeCont
 func (s *KubeControllerManager) Name() string           { return "kube-controller-manager" }
 func (s *KubeControllerManager) Dependencies() []string { return []string{"kube-apiserver"} }
 
-func kcmRootCAFile() string {
-	certsDir := cryptomaterial.CertsDirectory(config.DataDir)
+func kcmRootCAFile(dataDir string) string {
+	certsDir := cryptomaterial.CertsDirectory(dataDir)
 	return cryptomaterial.ServiceAccountTokenCABundlePath(certsDir)
 }
 
-func kcmClusterSigningCertKeyAndFile() (string, string) {
-	certsDir := cryptomaterial.CertsDirectory(config.DataDir)
+func kcmClusterSigningCertKeyAndFile(dataDir string) (string, string) {
+	certsDir := cryptomaterial.CertsDirectory(dataDir)
 	csrSignerDir := cryptomaterial.CSRSignerCertDir(certsDir)
 	return cryptomaterial.CAKeyPath(csrSignerDir), cryptomaterial.CACertPath(csrSignerDir)
 }
 
-func kcmServiceAccountPrivateKeyFile() string {
-	return filepath.Join(config.DataDir, "/resources/kube-apiserver/secrets/service-account-key/service-account.key")
+func kcmServiceAccountPrivateKeyFile(dataDir string) string {
+	return filepath.Join(dataDir, "/resources/kube-apiserver/secrets/service-account-key/service-account.key")
 }
 
 func configure(ctx context.Context, cfg *config.Config) (args []string, applyFn func() error, err error) {
 	kubeConfig := cfg.KubeConfigPath(config.KubeControllerManager)
-	clusterSigningKey, clusterSigningCert := kcmClusterSigningCertKeyAndFile()
+	clusterSigningKey, clusterSigningCert := kcmClusterSigningCertKeyAndFile(cfg.Paths.DataDir)
 
 	overrides := &kubecontrolplanev1.KubeControllerManagerConfig{
 		ExtendedArguments: map[string]kubecontrolplanev1.Arguments{
 			"kubeconfig":                       {kubeConfig},
 			"authentication-kubeconfig":        {kubeConfig},
 			"authorization-kubeconfig":         {kubeConfig},
-			"service-account-private-key-file": {kcmServiceAccountPrivateKeyFile()},
+			"service-account-private-key-file": {kcmServiceAccountPrivateKeyFile(cfg.Paths.DataDir)},
 			"allocate-node-cidrs":              {"true"},
 			"cluster-cidr":                     {strings.Join(cfg.Network.ClusterNetwork, ",")},
 			"service-cluster-ip-range":         {strings.Join(cfg.Network.ServiceNetwork, ",")},
-			"root-ca-file":                     {kcmRootCAFile()},
+			"root-ca-file":                     {kcmRootCAFile(cfg.Paths.DataDir)},
 			"secure-port":                      {"10257"},
 			"leader-elect":                     {"false"},
 			"use-service-account-credentials":  {"true"},
@@ -44,7 +44,7 @@ func TestConfigure(t *testing.T) {
 	cfg := config.NewDefault()
 	kcm := NewKubeControllerManager(context.TODO(), cfg)
 
-	clusterSigningKey, clusterSigningCert := kcmClusterSigningCertKeyAndFile()
+	clusterSigningKey, clusterSigningCert := kcmClusterSigningCertKeyAndFile(cfg.Paths.DataDir)
 	argsWant := []string{
 		"--allocate-node-cidrs=true",
 		fmt.Sprintf("--authentication-kubeconfig=%s", cfg.KubeConfigPath(config.KubeControllerManager)),
@@ -67,9 +67,9 @@ func TestConfigure(t *testing.T) {
 		"--leader-elect-resource-lock=leases",
 		"--leader-elect-retry-period=3s",
 		"--leader-elect=false",
-		fmt.Sprintf("--root-ca-file=%s", kcmRootCAFile()),
+		fmt.Sprintf("--root-ca-file=%s", kcmRootCAFile(cfg.Paths.DataDir)),
 		"--secure-port=10257",
-		fmt.Sprintf("--service-account-private-key-file=%s", kcmServiceAccountPrivateKeyFile()),
+		fmt.Sprintf("--service-account-private-key-file=%s", kcmServiceAccountPrivateKeyFile(cfg.Paths.DataDir)),
 		fmt.Sprintf("--service-cluster-ip-range=%s", cfg.Network.ServiceNetwork[0]),
 		fmt.Sprintf("--tls-cipher-suites=%s", strings.Join(crypto.OpenSSLToIANACipherSuites(fixedTLSProfile.Ciphers), ",")),
 		fmt.Sprintf("--tls-min-version=%s", string(fixedTLSProfile.MinTLSVersion)),
@@ -50,7 +50,7 @@ func (s *KubeScheduler) configure(cfg *config.Config) {
 	}
 
 	s.options = schedulerOptions.NewOptions()
-	s.options.ConfigFile = filepath.Join(config.DataDir, "/resources/kube-scheduler/config/config.yaml")
+	s.options.ConfigFile = filepath.Join(cfg.Paths.DataDir, "/resources/kube-scheduler/config/config.yaml")
 	s.options.Authentication.RemoteKubeConfigFile = cfg.KubeConfigPath(config.KubeScheduler)
 	s.options.Authorization.RemoteKubeConfigFile = cfg.KubeConfigPath(config.KubeScheduler)
 	s.options.SecureServing.MinTLSVersion = cfg.ApiServer.TLS.MinVersion
@@ -66,7 +66,7 @@ clientConnection:
 leaderElection:
   leaderElect: false`)
 
-	path := filepath.Join(config.DataDir, "resources", "kube-scheduler", "config", "config.yaml")
+	path := filepath.Join(cfg.Paths.DataDir, "resources", "kube-scheduler", "config", "config.yaml")
 	if err := os.MkdirAll(filepath.Dir(path), os.FileMode(0700)); err != nil {
 		return fmt.Errorf("creating directory path %s: %w", path, err)
 	}
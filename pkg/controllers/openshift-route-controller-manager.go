@@ -65,7 +65,7 @@ func (s *OCPRouteControllerManager) configure(cfg *config.Config) error {
 	s.kubeconfig = cfg.KubeConfigPath(config.RouteControllerManager)
 	s.kubeadmconfig = cfg.KubeConfigPath(config.KubeAdmin)
 
-	servingCertDir := cryptomaterial.RouteControllerManagerServingCertDir(cryptomaterial.CertsDirectory(config.DataDir))
+	servingCertDir := cryptomaterial.RouteControllerManagerServingCertDir(cryptomaterial.CertsDirectory(cfg.Paths.DataDir))
 	rcmConfig := &openshiftcontrolplanev1.OpenShiftControllerManagerConfig{
 		ServingInfo: &configv1.HTTPServingInfo{
 			ServingInfo: configv1.ServingInfo{
@@ -75,7 +75,7 @@ func (s *OCPRouteControllerManager) configure(cfg *config.Config) error {
 					CertFile: cryptomaterial.ServingCertPath(servingCertDir),
 					KeyFile:  cryptomaterial.ServingKeyPath(servingCertDir),
 				},
-				ClientCA:      cryptomaterial.TotalClientCABundlePath(cryptomaterial.CertsDirectory(config.DataDir)),
+				ClientCA:      cryptomaterial.TotalClientCABundlePath(cryptomaterial.CertsDirectory(cfg.Paths.DataDir)),
 				MinTLSVersion: cfg.ApiServer.TLS.MinVersion,
 				CipherSuites:  cfg.ApiServer.TLS.CipherSuites,
 			},
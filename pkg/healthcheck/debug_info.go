@@ -5,7 +5,6 @@ import (
 	"path/filepath"
 	"strings"
 
-	"github.com/openshift/microshift/pkg/config"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/client-go/util/homedir"
 	"k8s.io/klog/v2"
@@ -14,9 +13,9 @@ import (
 	"k8s.io/utils/ptr"
 )
 
-func logPodsAndEvents() {
+func logPodsAndEvents(dataDir string) {
 	cliOptions := genericclioptions.NewConfigFlags(true).WithDeprecatedPasswordFlag()
-	cliOptions.KubeConfig = ptr.To(filepath.Join(config.DataDir, "resources", string(config.KubeAdmin), "kubeconfig"))
+	cliOptions.KubeConfig = ptr.To(kubeAdminKubeconfigPath(dataDir))
 	if homedir.HomeDir() == "" {
 		// By default client writes cache to $HOME/.kube/cache.
 		// However, when healthcheck is executed by greenboot, the $HOME is empty,
@@ -5,23 +5,24 @@ import (
 	"encoding/json"
 	"time"
 
+	"github.com/openshift/microshift/pkg/config"
 	"k8s.io/klog/v2"
 )
 
-func MicroShiftHealthcheck(ctx context.Context, timeout time.Duration) error {
+func MicroShiftHealthcheck(ctx context.Context, paths config.Paths, timeout time.Duration) error {
 	if enabled, err := microshiftServiceShouldBeOk(ctx, timeout); err != nil {
-		printPrerunLog()
+		printPrerunLog(paths.BackupsDir)
 		return err
 	} else if !enabled {
 		return nil
 	}
 
-	workloads, err := getCoreMicroShiftWorkloads()
+	workloads, err := getCoreMicroShiftWorkloads(paths)
 	if err != nil {
 		return err
 	}
 
-	if err := waitForWorkloads(ctx, timeout, workloads); err != nil {
+	if err := waitForWorkloads(ctx, paths.DataDir, timeout, workloads); err != nil {
 		return err
 	}
 
@@ -30,7 +31,7 @@ func MicroShiftHealthcheck(ctx context.Context, timeout time.Duration) error {
 	return nil
 }
 
-func CustomWorkloadHealthcheck(ctx context.Context, timeout time.Duration, definition string) error {
+func CustomWorkloadHealthcheck(ctx context.Context, paths config.Paths, timeout time.Duration, definition string) error {
 	workloads := map[string]NamespaceWorkloads{}
 
 	err := json.Unmarshal([]byte(definition), &workloads)
@@ -39,14 +40,14 @@ func CustomWorkloadHealthcheck(ctx context.Context, timeout time.Duration, defin
 	}
 	klog.V(2).Infof("Deserialized '%s' into %+v", definition, workloads)
 
-	if err := waitForWorkloads(ctx, timeout, workloads); err != nil {
+	if err := waitForWorkloads(ctx, paths.DataDir, timeout, workloads); err != nil {
 		return err
 	}
 	klog.Info("Workloads are ready")
 	return nil
 }
 
-func EasyCustomWorkloadHealthcheck(ctx context.Context, timeout time.Duration, namespace string, deployments, daemonsets, statefulsets []string) error {
+func EasyCustomWorkloadHealthcheck(ctx context.Context, paths config.Paths, timeout time.Duration, namespace string, deployments, daemonsets, statefulsets []string) error {
 	workloads := map[string]NamespaceWorkloads{
 		namespace: {
 			Deployments:  deployments,
@@ -55,7 +56,7 @@ func EasyCustomWorkloadHealthcheck(ctx context.Context, timeout time.Duration, n
 		},
 	}
 
-	if err := waitForWorkloads(ctx, timeout, workloads); err != nil {
+	if err := waitForWorkloads(ctx, paths.DataDir, timeout, workloads); err != nil {
 		return err
 	}
 	klog.Info("Workloads are ready")
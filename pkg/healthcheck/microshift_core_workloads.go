@@ -4,16 +4,18 @@ import (
 	"bytes"
 	"encoding/json"
 	"os/exec"
+	"path/filepath"
 
 	"github.com/openshift/microshift/pkg/config"
+	"github.com/openshift/microshift/pkg/config/lvmd"
 	"github.com/openshift/microshift/pkg/util"
 	"k8s.io/klog/v2"
 )
 
 // getCoreMicroShiftWorkloads assembles a structure with the core MicroShift
 // workloads that the healthcheck should verify.
-func getCoreMicroShiftWorkloads() (map[string]NamespaceWorkloads, error) {
-	cfg, err := config.ActiveConfig()
+func getCoreMicroShiftWorkloads(paths config.Paths) (map[string]NamespaceWorkloads, error) {
+	cfg, err := config.ActiveConfig(paths)
 	if err != nil {
 		return nil, err
 	}
@@ -45,7 +47,7 @@ func getCoreMicroShiftWorkloads() (map[string]NamespaceWorkloads, error) {
 }
 
 func lvmsIsExpected(cfg *config.Config) (bool, error) {
-	cfgFile := "/etc/microshift/lvmd.yaml"
+	cfgFile := filepath.Join(filepath.Dir(cfg.Paths.ConfigFile), lvmd.LvmdConfigFileName)
 	if exists, err := util.PathExists(cfgFile); err != nil {
 		return false, err
 	} else if exists {
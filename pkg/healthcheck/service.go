@@ -8,7 +8,6 @@ import (
 	"time"
 
 	"github.com/coreos/go-systemd/v22/dbus"
-	"github.com/openshift/microshift/pkg/config"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/klog/v2"
 )
@@ -99,8 +98,8 @@ func (s *Systemd) IsServiceActiveAndNotFailed(ctx context.Context, service strin
 	return activeState == "active" || activeState == "reloading", nil
 }
 
-func printPrerunLog() {
-	contents, err := os.ReadFile(filepath.Join(config.BackupsDir, "prerun_failed.log"))
+func printPrerunLog(backupsDir string) {
+	contents, err := os.ReadFile(filepath.Join(backupsDir, "prerun_failed.log"))
 	if err != nil && !os.IsNotExist(err) {
 		klog.Errorf("Failed to read prerun_failed.log: %v", err)
 	}
@@ -24,8 +24,13 @@ type NamespaceWorkloads struct {
 	StatefulSets []string `json:"statefulsets"`
 }
 
-func waitForWorkloads(ctx context.Context, timeout time.Duration, workloads map[string]NamespaceWorkloads) error {
-	restConfig, err := clientcmd.BuildConfigFromFlags("", filepath.Join(config.DataDir, "resources", string(config.KubeAdmin), "kubeconfig"))
+// kubeAdminKubeconfigPath returns path of the admin's kubeconfig in the data dir.
+func kubeAdminKubeconfigPath(dataDir string) string {
+	return filepath.Join(dataDir, "resources", string(config.KubeAdmin), "kubeconfig")
+}
+
+func waitForWorkloads(ctx context.Context, dataDir string, timeout time.Duration, workloads map[string]NamespaceWorkloads) error {
+	restConfig, err := clientcmd.BuildConfigFromFlags("", kubeAdminKubeconfigPath(dataDir))
 	if err != nil {
 		return fmt.Errorf("failed to create restConfig: %v", err)
 	}
@@ -48,7 +53,7 @@ func waitForWorkloads(ctx context.Context, timeout time.Duration, workloads map[
 	}
 	errs := aeg.Wait()
 	if errs != nil {
-		logPodsAndEvents()
+		logPodsAndEvents(dataDir)
 		return errs
 	}
 	return nil
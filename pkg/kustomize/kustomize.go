@@ -43,11 +43,24 @@ func (s *Kustomizer) Run(ctx context.Context, ready chan<- struct{}, stopped cha
 	defer close(stopped)
 	defer close(ready)
 
-	kustomizationPaths, err := s.cfg.Manifests.GetKustomizationPaths()
+	if err := s.apply(ctx, s.cfg.Manifests); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// Reapply applies, and deletes, the manifests again, e.g. after the reload of
+// the configuration. The manifests might have changed since MicroShift started.
+func (s *Kustomizer) Reapply(ctx context.Context, manifests config.Manifests) error {
+	return s.apply(ctx, manifests)
+}
+
+func (s *Kustomizer) apply(ctx context.Context, manifests config.Manifests) error {
+	kustomizationPaths, err := manifests.GetKustomizationPaths()
 	if err != nil {
 		return fmt.Errorf("failed to find any kustomization paths: %w", err)
 	}
-	deletePaths, err := s.cfg.Manifests.GetKustomizationDeletePaths()
+	deletePaths, err := manifests.GetKustomizationDeletePaths()
 	if err != nil {
 		return fmt.Errorf("failed to find any delete kustomization paths: %w", err)
 	}
@@ -59,8 +72,7 @@ func (s *Kustomizer) Run(ctx context.Context, ready chan<- struct{}, stopped cha
 	for _, path := range kustomizationPaths {
 		s.handleKustomizationPath(ctx, path, "Applying", applyKustomization)
 	}
-
-	return ctx.Err()
+	return nil
 }
 
 func (s *Kustomizer) handleKustomizationPath(ctx context.Context, path string, verb string, actionFunc func(string, string) error) {
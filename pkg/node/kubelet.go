@@ -86,7 +86,7 @@ func (s *KubeletServer) configure(cfg *config.Config) {
 	kubeletFlags.NodeLabels["node-role.kubernetes.io/worker"] = ""
 	kubeletFlags.NodeLabels["node.openshift.io/os_id"] = osID
 
-	kubeletConfig, err := loadConfigFile(filepath.Join(config.DataDir, "/resources/kubelet/config/config.yaml"))
+	kubeletConfig, err := loadConfigFile(filepath.Join(cfg.Paths.DataDir, "/resources/kubelet/config/config.yaml"))
 
 	if err != nil {
 		klog.Fatalf("Failed to load Kubelet Configuration %v", err)
@@ -102,7 +102,7 @@ func (s *KubeletServer) writeConfig(cfg *config.Config) error {
 		return err
 	}
 
-	path := filepath.Join(config.DataDir, "resources", "kubelet", "config", "config.yaml")
+	path := filepath.Join(cfg.Paths.DataDir, "resources", "kubelet", "config", "config.yaml")
 	if err := os.MkdirAll(filepath.Dir(path), os.FileMode(0700)); err != nil {
 		return fmt.Errorf("failed to create dir %q: %w", path, err)
 	}
@@ -111,7 +111,7 @@ func (s *KubeletServer) writeConfig(cfg *config.Config) error {
 }
 
 func (s *KubeletServer) generateConfig(cfg *config.Config) ([]byte, error) {
-	certsDir := cryptomaterial.CertsDirectory(config.DataDir)
+	certsDir := cryptomaterial.CertsDirectory(cfg.Paths.DataDir)
 	servingCertDir := cryptomaterial.KubeletServingCertDir(certsDir)
 
 	tplData, err := embedded.Asset("core/kubelet.yaml")
@@ -141,10 +141,10 @@ func (s *KubeletServer) generateConfig(cfg *config.Config) ([]byte, error) {
 	}
 
 	tplParams := map[string]string{
-		"clientCAFile":       cryptomaterial.KubeletClientCAPath(cryptomaterial.CertsDirectory(config.DataDir)),
+		"clientCAFile":       cryptomaterial.KubeletClientCAPath(cryptomaterial.CertsDirectory(cfg.Paths.DataDir)),
 		"tlsCertFile":        cryptomaterial.ServingCertPath(servingCertDir),
 		"tlsPrivateKeyFile":  cryptomaterial.ServingKeyPath(servingCertDir),
-		"volumePluginDir":    config.DataDir + "/kubelet-plugins/volume/exec",
+		"volumePluginDir":    cfg.Paths.DataDir + "/kubelet-plugins/volume/exec",
 		"clusterDNSIP":       cfg.Network.DNS,
 		"resolvConf":         resolvConf,
 		"tlsCipherSuites":    strings.Join(cfg.ApiServer.TLS.CipherSuites, ","),
@@ -48,3 +48,51 @@ reservedMemory:
 	assert.NoError(t, err)
 	assert.Contains(t, string(data), expectedConfigPart)
 }
+
+func TestValidateKubeletConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		kubelet  map[string]any
+		expected []string
+	}{
+		{
+			name: "valid",
+			kubelet: map[string]any{
+				"maxPods":      float64(100),
+				"evictionHard": map[string]any{"memory.available": "100Mi"},
+			},
+		},
+		{
+			name: "unknown and mistyped fields",
+			kubelet: map[string]any{
+				"maxPodz":          float64(100),
+				"maxPods":          "many",
+				"logging":          map[string]any{"formatt": "json"},
+				"cpuManagerPolicy": "static",
+			},
+			expected: []string{
+				"kubelet.logging.formatt: Invalid value: unknown field of KubeletConfiguration",
+				"kubelet.maxPods: Invalid value: must be of type int32",
+				"kubelet.maxPodz: Invalid value: unknown field of KubeletConfiguration",
+			},
+		},
+		{
+			name:    "rejected by kubelet's validation",
+			kubelet: map[string]any{"eventBurst": float64(-1)},
+			expected: []string{
+				"kubelet.eventBurst: Invalid value: eventBurst (--event-burst) -1 must not be a negative number",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.NewDefault()
+			cfg.Kubelet = tt.kubelet
+			errs := []string{}
+			for _, e := range ValidateKubeletConfig(cfg) {
+				errs = append(errs, e.Error())
+			}
+			assert.ElementsMatch(t, tt.expected, errs)
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package node
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/openshift/microshift/pkg/config"
+	"gopkg.in/yaml.v2"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	utilfeature "k8s.io/apiserver/pkg/util/feature"
+	kubeletconfig "k8s.io/kubernetes/pkg/kubelet/apis/config"
+	kubeletscheme "k8s.io/kubernetes/pkg/kubelet/apis/config/scheme"
+	kubeletvalidation "k8s.io/kubernetes/pkg/kubelet/apis/config/validation"
+)
+
+const kubeletConfigHeader = "kind: KubeletConfiguration\napiVersion: kubelet.config.k8s.io/v1beta1\n"
+
+var (
+	// Messages of the strict decoding errors, e.g. `unknown field "evictionHard.foo"`.
+	strictFieldErrorRegexp = regexp.MustCompile(`^(unknown|duplicate) field "([^"]+)"$`)
+	// Messages of the type errors, e.g. `json: cannot unmarshal string into Go struct field
+	// KubeletConfiguration.maxPods of type int32`.
+	typeErrorRegexp = regexp.MustCompile(`Go struct field KubeletConfiguration\.(\S+) of type (\S+)`)
+	// Messages of the kubelet's validation, e.g. `invalid configuration: eventBurst (--event-burst) ...`.
+	validationErrorRegexp = regexp.MustCompile(`^invalid configuration: ([A-Za-z]+)`)
+)
+
+// ValidateKubeletConfig validates the kubelet section of the config against the
+// KubeletConfiguration: the fields must exist and have the right types, and the
+// configuration MicroShift runs the kubelet with must pass the kubelet's validation.
+func ValidateKubeletConfig(cfg *config.Config) field.ErrorList {
+	path := field.NewPath("kubelet")
+	if len(cfg.Kubelet) == 0 {
+		return nil
+	}
+
+	_, codecs, err := kubeletscheme.NewSchemeAndCodecs(serializer.EnableStrict)
+	if err != nil {
+		return field.ErrorList{field.InternalError(path, fmt.Errorf("failed to create kubelet config codecs: %w", err))}
+	}
+
+	// Fields are decoded one by one, because decoding stops at the first mistyped field.
+	keys := make([]string, 0, len(cfg.Kubelet))
+	for k := range cfg.Kubelet {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	errs := field.ErrorList{}
+	for _, k := range keys {
+		data, err := yaml.Marshal(map[string]any{k: cfg.Kubelet[k]})
+		if err != nil {
+			errs = append(errs, field.InternalError(path.Child(k), fmt.Errorf("failed to marshal kubelet config: %w", err)))
+			continue
+		}
+		errs = append(errs, decodeStrict(path, codecs, data)...)
+	}
+	if len(errs) != 0 {
+		return errs
+	}
+
+	// User provided fields replace the fields of MicroShift's kubelet config as a whole.
+	kc, err := mergedKubeletConfig(cfg, codecs)
+	if err != nil {
+		return field.ErrorList{field.InternalError(path, err)}
+	}
+	err = kubeletvalidation.ValidateKubeletConfiguration(kc, utilfeature.DefaultFeatureGate)
+	if err == nil {
+		return nil
+	}
+	for _, e := range flattenErrors(err) {
+		fieldPath := path
+		if m := validationErrorRegexp.FindStringSubmatch(e.Error()); m != nil {
+			if _, ok := cfg.Kubelet[m[1]]; ok {
+				fieldPath = path.Child(m[1])
+			}
+		}
+		errs = append(errs, field.Invalid(fieldPath, field.OmitValueType{}, strings.TrimPrefix(e.Error(), "invalid configuration: ")))
+	}
+	return errs
+}
+
+// decodeStrict decodes the user provided kubelet config and reports the unknown,
+// duplicate, and mistyped fields.
+func decodeStrict(path *field.Path, codecs *serializer.CodecFactory, data []byte) field.ErrorList {
+	_, _, err := codecs.UniversalDecoder().Decode(append([]byte(kubeletConfigHeader), data...), nil, nil)
+	if err == nil {
+		return nil
+	}
+
+	errs := field.ErrorList{}
+	if strictErr, ok := runtime.AsStrictDecodingError(err); ok {
+		for _, e := range strictErr.Errors() {
+			m := strictFieldErrorRegexp.FindStringSubmatch(e.Error())
+			switch {
+			case m == nil:
+				errs = append(errs, field.Invalid(path, field.OmitValueType{}, e.Error()))
+			case m[1] == "duplicate":
+				errs = append(errs, field.Duplicate(childPath(path, m[2]), field.OmitValueType{}))
+			default:
+				errs = append(errs, field.Invalid(childPath(path, m[2]), field.OmitValueType{}, "unknown field of KubeletConfiguration"))
+			}
+		}
+	} else if m := typeErrorRegexp.FindStringSubmatch(err.Error()); m != nil {
+		errs = append(errs, field.Invalid(childPath(path, m[1]), field.OmitValueType{}, "must be of type "+m[2]))
+	} else {
+		errs = append(errs, field.Invalid(path, field.OmitValueType{}, err.Error()))
+	}
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Field < errs[j].Field })
+	return errs
+}
+
+// mergedKubeletConfig returns the configuration MicroShift runs the kubelet with.
+func mergedKubeletConfig(cfg *config.Config, codecs *serializer.CodecFactory) (*kubeletconfig.KubeletConfiguration, error) {
+	base := *cfg
+	base.Kubelet = nil
+	data, err := (&KubeletServer{}).generateConfig(&base)
+	if err != nil {
+		return nil, err
+	}
+	merged := map[string]any{}
+	if err := yaml.Unmarshal(data, &merged); err != nil {
+		return nil, fmt.Errorf("failed to parse kubelet config: %w", err)
+	}
+	for k, v := range cfg.Kubelet {
+		merged[k] = v
+	}
+	if data, err = yaml.Marshal(merged); err != nil {
+		return nil, fmt.Errorf("failed to marshal kubelet config: %w", err)
+	}
+
+	obj, _, err := codecs.UniversalDecoder().Decode(data, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode kubelet config: %w", err)
+	}
+	kc, ok := obj.(*kubeletconfig.KubeletConfiguration)
+	if !ok {
+		return nil, fmt.Errorf("failed to decode kubelet config: unexpected type %T", obj)
+	}
+	return kc, nil
+}
+
+func flattenErrors(err error) []error {
+	if agg, ok := err.(utilerrors.Aggregate); ok {
+		return utilerrors.Flatten(agg).Errors()
+	}
+	return []error{err}
+}
+
+// childPath returns the path of a field given with dots, e.g. "evictionHard.foo".
+func childPath(path *field.Path, name string) *field.Path {
+	for _, part := range strings.Split(name, ".") {
+		path = path.Child(part)
+	}
+	return path
+}
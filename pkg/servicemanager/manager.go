@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
 	"syscall"
 	"time"
 
@@ -19,6 +20,10 @@ type ServiceManager struct {
 	services   []Service
 	serviceMap map[string]Service
 	startRec   *startuprecorder.StartupRecorder
+
+	failureLock   sync.Mutex
+	failedService string
+	failure       error
 }
 
 func NewServiceManager(startRec *startuprecorder.StartupRecorder) *ServiceManager {
@@ -34,6 +39,22 @@ func NewServiceManager(startRec *startuprecorder.StartupRecorder) *ServiceManage
 func (s *ServiceManager) Name() string           { return s.name }
 func (s *ServiceManager) Dependencies() []string { return s.deps }
 
+// FailedService returns the name of the first service that failed or panicked
+// together with the failure, or an empty string if no service failed.
+func (m *ServiceManager) FailedService() (string, error) {
+	m.failureLock.Lock()
+	defer m.failureLock.Unlock()
+	return m.failedService, m.failure
+}
+
+func (m *ServiceManager) recordFailure(service string, err error) {
+	m.failureLock.Lock()
+	defer m.failureLock.Unlock()
+	if m.failedService == "" {
+		m.failedService, m.failure = service, err
+	}
+}
+
 func (m *ServiceManager) AddService(s Service) error {
 	if s == nil {
 		return fmt.Errorf("service must not be <nil>")
@@ -113,6 +134,7 @@ func (m *ServiceManager) asyncRun(ctx context.Context, service Service) (<-chan
 			defer func() {
 				if r := recover(); r != nil {
 					klog.Errorf("%s panicked: %s", service.Name(), r)
+					m.recordFailure(service.Name(), fmt.Errorf("panic: %v", r))
 					klog.Error("Stopping MicroShift")
 					if err := syscall.Kill(syscall.Getpid(), syscall.SIGTERM); err != nil {
 						klog.Warningf("error killing process: %v", err)
@@ -136,6 +158,7 @@ func (m *ServiceManager) asyncRun(ctx context.Context, service Service) (<-chan
 
 			if err := service.Run(ctx, ready, stopped); err != nil && !errors.Is(err, context.Canceled) {
 				klog.ErrorS(err, "SERVICE FAILED - stopping MicroShift", "service", service.Name(), "since-start", time.Since(svcStart))
+				m.recordFailure(service.Name(), err)
 				if err := syscall.Kill(syscall.Getpid(), syscall.SIGTERM); err != nil {
 					klog.Warningf("error killing process: %v", err)
 				}
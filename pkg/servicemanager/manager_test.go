@@ -170,6 +170,10 @@ func TestRunToServiceCrash(t *testing.T) {
 		t.Errorf("an error from bar-crash was expected %s: %v", m.Name(), err)
 	}
 
+	failed, failure := m.FailedService()
+	assert.Equal(t, "bar-crash", failed)
+	assert.Error(t, failure)
+
 	if !sigchannel.IsClosed(ready) {
 		t.Errorf("ready channel not closed after completing service manager")
 	}
@@ -226,6 +230,10 @@ func TestRunToServicePanic(t *testing.T) {
 		t.Errorf("an error from bar-panic was expected %s: %v", m.Name(), err)
 	}
 
+	failed, failure := m.FailedService()
+	assert.Equal(t, "bar-panic", failed)
+	assert.Error(t, failure)
+
 	if !sigchannel.IsClosed(ready) {
 		t.Errorf("ready channel not closed after completing service manager")
 	}